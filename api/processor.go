@@ -24,3 +24,18 @@ package api
 type Processor interface {
 	Process(batch Batch, batchMaker BatchMaker) error
 }
+
+// RecordProcessor is a Processor that processes every record of a batch independently of the other records.
+// When the stage is configured with a record parallelism greater than 1, the Data Collector Edge calls ProcessRecord
+// for the records of a batch on a bounded pool of workers instead of calling Process, and adds the output records
+// to the batch in the order of the input records and with their output lanes.
+//
+// ProcessRecord method processes a single record and adds the records it creates to the given BatchMaker.
+// An error returned by this method sends the record to error.
+//
+// IsRecordParallel method returns whether ProcessRecord can be called for several records at the same time.
+type RecordProcessor interface {
+	Processor
+	ProcessRecord(record Record, batchMaker BatchMaker) error
+	IsRecordParallel() bool
+}
@@ -36,4 +36,7 @@ type StageContext interface {
 	GetPipelineParameters() map[string]interface{}
 	SetStop()
 	IsStopped() bool
+	// Done returns a channel that is closed once the stage is stopped, so that stages blocked waiting for data
+	// can select on it.
+	Done() <-chan struct{}
 }
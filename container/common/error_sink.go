@@ -14,6 +14,7 @@ package common
 
 import (
 	"github.com/streamsets/datacollector-edge/api"
+	"sync"
 )
 
 type ErrorSink struct {
@@ -21,6 +22,7 @@ type ErrorSink struct {
 	stageErrorRecords  map[string][]api.Record
 	totalErrorRecords  int64
 	totalErrorMessages int64
+	mutex              sync.Mutex
 }
 
 func NewErrorSink() *ErrorSink {
@@ -31,7 +33,7 @@ func NewErrorSink() *ErrorSink {
 	return errorSink
 }
 
-//After each batch call this function to clear current batch error messages/records
+// After each batch call this function to clear current batch error messages/records
 func (e *ErrorSink) ClearErrorRecordsAndMessages() {
 	e.stageErrorMessages = make(map[string][]api.ErrorMessage)
 	e.stageErrorRecords = make(map[string][]api.Record)
@@ -64,6 +66,8 @@ func (e *ErrorSink) GetErrorMessages() map[string][]api.ErrorMessage {
 }
 
 func (e *ErrorSink) ReportError(stageIns string, errorMessage api.ErrorMessage) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
 	var errorMessages []api.ErrorMessage
 	var keyExists bool
 	errorMessages, keyExists = e.stageErrorMessages[stageIns]
@@ -78,6 +82,8 @@ func (e *ErrorSink) ReportError(stageIns string, errorMessage api.ErrorMessage)
 }
 
 func (e *ErrorSink) ToError(stageIns string, record api.Record) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
 	var errorRecords []api.Record
 	var keyExists bool
 	errorRecords, keyExists = e.stageErrorRecords[stageIns]
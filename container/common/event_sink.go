@@ -14,10 +14,12 @@ package common
 
 import (
 	"github.com/streamsets/datacollector-edge/api"
+	"sync"
 )
 
 type EventSink struct {
 	eventRecords map[string][]api.Record
+	mutex        sync.Mutex
 }
 
 func NewEventSink() *EventSink {
@@ -34,7 +36,13 @@ func (e *EventSink) GetStageEvents(stageIns string) []api.Record {
 	return e.eventRecords[stageIns]
 }
 
+func (e *EventSink) GetEventRecords() map[string][]api.Record {
+	return e.eventRecords
+}
+
 func (e *EventSink) AddEvent(stageIns string, record api.Record) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
 	var eventRecords []api.Record
 	var keyExists bool
 	eventRecords, keyExists = e.eventRecords[stageIns]
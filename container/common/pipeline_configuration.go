@@ -12,6 +12,13 @@
 // limitations under the License.
 package common
 
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
 const (
 	PipelineConfigSchemaVersion = 6
 	PipelineConfigVersion       = 10
@@ -22,6 +29,8 @@ const (
 	FragmentTargetStageName     = "com_streamsets_pipeline_stage_destination_fragment_FragmentTarget"
 	ConfFragmentId              = "conf.fragmentId"
 	ConfFragmentInstanceId      = "conf.fragmentInstanceId"
+	ConfConstants               = "constants"
+	FragmentStageConfiguration  = "fragmentStageConfiguration"
 )
 
 type PipelineConfiguration struct {
@@ -119,7 +128,7 @@ func (s StageConfiguration) GetConfigurationMap() map[string]Config {
 }
 
 func (s StageConfiguration) GetOutputAndEventLanes() []string {
-	outputAndEventLanes := make([]string, len(s.OutputLanes)+len(s.EventLanes))
+	outputAndEventLanes := make([]string, 0, len(s.OutputLanes)+len(s.EventLanes))
 	outputAndEventLanes = append(outputAndEventLanes, s.OutputLanes...)
 	outputAndEventLanes = append(outputAndEventLanes, s.EventLanes...)
 	return outputAndEventLanes
@@ -127,78 +136,350 @@ func (s StageConfiguration) GetOutputAndEventLanes() []string {
 
 type PipelineEnvelope struct {
 	PipelineConfig     PipelineConfiguration  `json:"pipelineConfig"`
-	PipelineRules      map[string]interface{} `json:"pipelineRules"`
+	PipelineRules      RuleDefinitions        `json:"pipelineRules"`
 	LibraryDefinitions map[string]interface{} `json:"libraryDefinitions"`
 }
 
-func (p *PipelineConfiguration) ProcessFragmentStages() {
-	if p.Fragments != nil && len(p.Fragments) > 0 {
-		resolvedStages := make([]*StageConfiguration, 0)
-		for _, stageInstance := range p.Stages {
-			if isFragmentGroupStage(stageInstance) {
-				stageConfig := stageInstance.GetConfigurationMap()
-				fragmentIdConfig := stageConfig[ConfFragmentId]
-				fragmentInstanceIdConfig := stageConfig[ConfFragmentInstanceId]
-
-				if fragmentIdConfig.Value != nil && fragmentInstanceIdConfig.Value != nil {
-					fragmentId := fragmentIdConfig.Value.(string)
-					fragmentInstanceId := fragmentInstanceIdConfig.Value.(string)
-					for _, fragment := range p.Fragments {
-						if fragment.PipelineId == fragmentId && fragment.FragmentInstanceId == fragmentInstanceId {
-							resolvedStages = append(resolvedStages, fragment.Stages...)
-						}
-					}
-				}
+// ProcessFragmentStages replaces the fragment stages of the pipeline with the stages of their fragments. The stage
+// instances and lanes of a fragment are prefixed with the fragment instance id, its input and output lanes are
+// connected to the lanes of the fragment stage, and its parameters are added to the pipeline constants with the
+// fragment instance id as prefix. Fragments within fragments are expanded as well. Fragments are expanded once, when
+// the pipeline configuration is loaded from the pipeline store.
+func (p *PipelineConfiguration) ProcessFragmentStages() error {
+	if !hasFragmentGroupStage(p.Stages) {
+		return nil
+	}
+	resolvedStages, fragmentConstants, err := expandFragmentStages(p.Stages, p.Fragments, []string{})
+	if err != nil {
+		return err
+	}
+	// graph issues are reported when the pipeline is created
+	p.Stages, _ = SortStages(resolvedStages)
+	p.Configuration = addConstants(p.Configuration, fragmentConstants)
+	return nil
+}
+
+func hasFragmentGroupStage(stageInstances []*StageConfiguration) bool {
+	for _, stageInstance := range stageInstances {
+		if isFragmentGroupStage(stageInstance) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandFragmentStages returns the stages with the fragment stages expanded, and the constants of the expanded
+// fragments prefixed with their fragment instance ids.
+func expandFragmentStages(
+	stageInstances []*StageConfiguration,
+	fragments []*PipelineFragmentConfiguration,
+	fragmentIdPath []string,
+) ([]*StageConfiguration, []Config, error) {
+	resolvedStages := make([]*StageConfiguration, 0, len(stageInstances))
+	resolvedConstants := make([]Config, 0)
+	for _, stageInstance := range stageInstances {
+		if !isFragmentGroupStage(stageInstance) {
+			resolvedStages = append(resolvedStages, stageInstance)
+			continue
+		}
 
-			} else {
-				resolvedStages = append(resolvedStages, stageInstance)
+		stageConfig := stageInstance.GetConfigurationMap()
+		fragmentId, _ := stageConfig[ConfFragmentId].Value.(string)
+		fragmentInstanceId, _ := stageConfig[ConfFragmentInstanceId].Value.(string)
+		fragment := findFragment(fragments, fragmentId, fragmentInstanceId)
+		if fragment == nil {
+			return nil, nil, fmt.Errorf(
+				"Fragment '%s' used by stage '%s' not found",
+				fragmentId,
+				stageInstance.InstanceName,
+			)
+		}
+		for _, id := range fragmentIdPath {
+			if id == fragmentId {
+				return nil, nil, fmt.Errorf("Fragment '%s' includes itself", fragmentId)
 			}
 		}
-		p.Stages = sortStageInstances(resolvedStages)
+
+		fragmentStages, nestedConstants, err := expandFragmentStages(
+			fragment.Stages,
+			append(fragment.Fragments, fragments...),
+			append(fragmentIdPath, fragmentId),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fragmentConstants := append(getConstants(fragment.Configuration), nestedConstants...)
+		resolvedStages = append(
+			resolvedStages,
+			renameFragmentStages(stageInstance, fragment, fragmentInstanceId, fragmentStages, fragmentConstants)...,
+		)
+		for _, constant := range fragmentConstants {
+			resolvedConstants = append(resolvedConstants, Config{
+				Name:  prefixFragmentName(fragmentInstanceId, constant.Name),
+				Value: constant.Value,
+			})
+		}
 	}
+	return resolvedStages, resolvedConstants, nil
 }
 
-func isFragmentGroupStage(stageInstance *StageConfiguration) bool {
-	return stageInstance.StageName == FragmentSourceStageName ||
-		stageInstance.StageName == FragmentProcessorStageName ||
-		stageInstance.StageName == FragmentTargetStageName
+func findFragment(
+	fragments []*PipelineFragmentConfiguration,
+	fragmentId string,
+	fragmentInstanceId string,
+) *PipelineFragmentConfiguration {
+	var fragmentWithId *PipelineFragmentConfiguration
+	for _, fragment := range fragments {
+		if fragment.PipelineId == fragmentId {
+			if fragment.FragmentInstanceId == fragmentInstanceId {
+				return fragment
+			}
+			if fragmentWithId == nil {
+				fragmentWithId = fragment
+			}
+		}
+	}
+	return fragmentWithId
 }
 
-func sortStageInstances(stageInstances []*StageConfiguration) []*StageConfiguration {
-	sorted := make([]*StageConfiguration, 0)
-	removedMap := make(map[string]bool)
-	producedOutputs := make([]string, 0)
-	ok := true
-	iteration := 0
-	for ok {
-		prior := len(sorted)
-		for _, stageInstance := range stageInstances {
-			if !removedMap[stageInstance.InstanceName] {
-				alreadyProduced := make([]string, 0)
-				for _, p := range producedOutputs {
-					for _, inputLane := range stageInstance.InputLanes {
-						if inputLane == p {
-							alreadyProduced = append(alreadyProduced, p)
-						}
-					}
-				}
-				if len(alreadyProduced) == len(stageInstance.InputLanes) {
-					producedOutputs = append(producedOutputs, stageInstance.OutputLanes...)
-					producedOutputs = append(producedOutputs, stageInstance.EventLanes...)
-					removedMap[stageInstance.InstanceName] = true
-					sorted = append(sorted, stageInstance)
-				}
+// renameFragmentStages returns copies of the fragment stages with instance names, lanes and parameter references
+// prefixed with the fragment instance id. Input lanes not produced within the fragment are connected to the input
+// lanes of the fragment stage, and output lanes not consumed within the fragment to its output lanes. Such boundary
+// lanes are matched by the lane names the fragment declares for its fragment stage, a fragment stage with a single
+// lane connects all boundary lanes to it.
+func renameFragmentStages(
+	fragmentStage *StageConfiguration,
+	fragment *PipelineFragmentConfiguration,
+	fragmentInstanceId string,
+	fragmentStages []*StageConfiguration,
+	fragmentConstants []Config,
+) []*StageConfiguration {
+	producedLanes := make(map[string]bool)
+	consumedLanes := make(map[string]bool)
+	for _, stageInstance := range fragmentStages {
+		for _, lane := range stageInstance.GetOutputAndEventLanes() {
+			producedLanes[lane] = true
+		}
+		for _, lane := range stageInstance.InputLanes {
+			consumedLanes[lane] = true
+		}
+	}
+
+	laneMapping := make(map[string]string)
+	mapLane := func(lane string, isBoundary bool, stageLanes []string, declaredLanes []string) {
+		if _, ok := laneMapping[lane]; ok {
+			return
+		}
+		if !isBoundary {
+			laneMapping[lane] = prefixFragmentName(fragmentInstanceId, lane)
+		} else if indexOfString(stageLanes, lane) >= 0 {
+			laneMapping[lane] = lane
+		} else if i := indexOfString(declaredLanes, lane); i >= 0 && i < len(stageLanes) {
+			laneMapping[lane] = stageLanes[i]
+		} else if len(stageLanes) == 1 {
+			laneMapping[lane] = stageLanes[0]
+		} else {
+			laneMapping[lane] = prefixFragmentName(fragmentInstanceId, lane)
+		}
+	}
+	declaredStage := getDeclaredFragmentStage(fragment)
+	for _, stageInstance := range fragmentStages {
+		for _, lane := range stageInstance.InputLanes {
+			mapLane(lane, !producedLanes[lane], fragmentStage.InputLanes, declaredStage.InputLanes)
+		}
+	}
+	for _, stageInstance := range fragmentStages {
+		for _, lane := range stageInstance.OutputLanes {
+			mapLane(lane, !consumedLanes[lane], fragmentStage.OutputLanes, declaredStage.OutputLanes)
+		}
+		for _, lane := range stageInstance.EventLanes {
+			mapLane(lane, !consumedLanes[lane], fragmentStage.EventLanes, declaredStage.EventLanes)
+		}
+	}
+
+	renameLanes := func(lanes []string) []string {
+		renamed := make([]string, len(lanes))
+		for i, lane := range lanes {
+			renamed[i] = laneMapping[lane]
+		}
+		return renamed
+	}
+
+	parameterNames := make([]string, len(fragmentConstants))
+	for i, constant := range fragmentConstants {
+		parameterNames[i] = constant.Name
+	}
+	replaceParameters := newFragmentParameterReplacer(fragmentInstanceId, parameterNames)
+
+	renamedStages := make([]*StageConfiguration, len(fragmentStages))
+	for i, stageInstance := range fragmentStages {
+		renamedStage := *stageInstance
+		renamedStage.InstanceName = prefixFragmentName(fragmentInstanceId, stageInstance.InstanceName)
+		renamedStage.InputLanes = renameLanes(stageInstance.InputLanes)
+		renamedStage.OutputLanes = renameLanes(stageInstance.OutputLanes)
+		renamedStage.EventLanes = renameLanes(stageInstance.EventLanes)
+		renamedStage.Configuration = make([]Config, len(stageInstance.Configuration))
+		for j, config := range stageInstance.Configuration {
+			renamedStage.Configuration[j] = Config{
+				Name:  config.Name,
+				Value: substituteFragmentParameters(config.Value, replaceParameters),
 			}
 		}
-		iteration++
-		if prior == len(sorted) && iteration >= len(sorted) {
-			ok = false
-			for _, stageInstance := range stageInstances {
-				if !removedMap[stageInstance.InstanceName] {
-					sorted = append(sorted, stageInstance)
+		renamedStages[i] = &renamedStage
+	}
+	return renamedStages
+}
+
+// getDeclaredFragmentStage returns the lanes of the fragment stage as declared by the fragment in its UI info.
+func getDeclaredFragmentStage(fragment *PipelineFragmentConfiguration) StageConfiguration {
+	stageConfiguration, _ := fragment.UiInfo[FragmentStageConfiguration].(map[string]interface{})
+	getLanes := func(key string) []string {
+		lanes := make([]string, 0)
+		laneList, _ := stageConfiguration[key].([]interface{})
+		for _, lane := range laneList {
+			if laneName, ok := lane.(string); ok {
+				lanes = append(lanes, laneName)
+			}
+		}
+		return lanes
+	}
+	return StageConfiguration{
+		InputLanes:  getLanes("inputLanes"),
+		OutputLanes: getLanes("outputLanes"),
+		EventLanes:  getLanes("eventLanes"),
+	}
+}
+
+// prefixFragmentName prefixes the name with the fragment instance id, unless it already is.
+func prefixFragmentName(fragmentInstanceId string, name string) string {
+	if strings.HasPrefix(name, fragmentInstanceId+"_") {
+		return name
+	}
+	return fragmentInstanceId + "_" + name
+}
+
+var elExpressionRegex = regexp.MustCompile(`\$\{[^}]*\}`)
+
+// newFragmentParameterReplacer returns a function replacing references to the fragment parameters in the expressions
+// of a string with references to the prefixed pipeline constants. The parameters are matched by a single regular
+// expression compiled once per fragment.
+func newFragmentParameterReplacer(fragmentInstanceId string, parameterNames []string) func(string) string {
+	if len(parameterNames) == 0 {
+		return func(value string) string {
+			return value
+		}
+	}
+
+	prefixedNames := make(map[string]string, len(parameterNames))
+	quotedNames := make([]string, len(parameterNames))
+	for i, parameterName := range parameterNames {
+		prefixedNames[parameterName] = prefixFragmentName(fragmentInstanceId, parameterName)
+		quotedNames[i] = regexp.QuoteMeta(parameterName)
+	}
+	// prefer the longest parameter name when names share a prefix
+	sort.Slice(quotedNames, func(i, j int) bool {
+		return len(quotedNames[i]) > len(quotedNames[j])
+	})
+	parameterRegex := regexp.MustCompile(`(^|[^\w:.])(` + strings.Join(quotedNames, "|") + `)\b`)
+
+	replaceParameter := func(match string) string {
+		submatches := parameterRegex.FindStringSubmatch(match)
+		return submatches[1] + prefixedNames[submatches[2]]
+	}
+	return func(value string) string {
+		if !strings.Contains(value, "${") {
+			return value
+		}
+		return elExpressionRegex.ReplaceAllStringFunc(value, func(expression string) string {
+			return parameterRegex.ReplaceAllStringFunc(expression, replaceParameter)
+		})
+	}
+}
+
+// substituteFragmentParameters replaces references to fragment parameters in expressions within the value with
+// references to the prefixed pipeline constants.
+func substituteFragmentParameters(value interface{}, replaceParameters func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return replaceParameters(v)
+	case []interface{}:
+		substituted := make([]interface{}, len(v))
+		for i, item := range v {
+			substituted[i] = substituteFragmentParameters(item, replaceParameters)
+		}
+		return substituted
+	case map[string]interface{}:
+		substituted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			substituted[key] = substituteFragmentParameters(item, replaceParameters)
+		}
+		return substituted
+	default:
+		return value
+	}
+}
+
+// getConstants returns the constants defined in the configuration as name value pairs.
+func getConstants(configuration []Config) []Config {
+	constants := make([]Config, 0)
+	for _, config := range configuration {
+		if config.Name != ConfConstants {
+			continue
+		}
+		if constantList, ok := config.Value.([]interface{}); ok {
+			for _, constant := range constantList {
+				if constantMap, ok := constant.(map[string]interface{}); ok {
+					if key, ok := constantMap["key"].(string); ok {
+						constants = append(constants, Config{Name: key, Value: constantMap["value"]})
+					}
 				}
 			}
 		}
 	}
-	return sorted
+	return constants
+}
+
+// addConstants returns a copy of the configuration with the constants added, constants already defined keep
+// their value.
+func addConstants(configuration []Config, constants []Config) []Config {
+	if len(constants) == 0 {
+		return configuration
+	}
+
+	definedConstants := make(map[string]bool)
+	constantList := make([]interface{}, 0)
+	for _, constant := range getConstants(configuration) {
+		definedConstants[constant.Name] = true
+		constantList = append(constantList, map[string]interface{}{"key": constant.Name, "value": constant.Value})
+	}
+	for _, constant := range constants {
+		if !definedConstants[constant.Name] {
+			definedConstants[constant.Name] = true
+			constantList = append(constantList, map[string]interface{}{"key": constant.Name, "value": constant.Value})
+		}
+	}
+
+	resolvedConfiguration := make([]Config, 0, len(configuration)+1)
+	for _, config := range configuration {
+		if config.Name != ConfConstants {
+			resolvedConfiguration = append(resolvedConfiguration, config)
+		}
+	}
+	return append(resolvedConfiguration, Config{Name: ConfConstants, Value: constantList})
+}
+
+func indexOfString(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func isFragmentGroupStage(stageInstance *StageConfiguration) bool {
+	return stageInstance.StageName == FragmentSourceStageName ||
+		stageInstance.StageName == FragmentProcessorStageName ||
+		stageInstance.StageName == FragmentTargetStageName
 }
@@ -27,7 +27,10 @@ func TestProcessFragmentStages(t *testing.T) {
 		return
 	}
 
-	pipelineConfiguration.ProcessFragmentStages()
+	err = pipelineConfiguration.ProcessFragmentStages()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(pipelineConfiguration.Stages) != 7 {
 		t.Error("Fragment stages not resolved properly")
@@ -44,6 +47,192 @@ func TestProcessFragmentStages(t *testing.T) {
 	}
 }
 
+func TestProcessFragmentStagesRenamesNestedFragments(t *testing.T) {
+	innerFragment := &PipelineFragmentConfiguration{
+		PipelineId:         "inner",
+		FragmentInstanceId: "inner_01",
+		Configuration: []Config{{Name: ConfConstants, Value: []interface{}{
+			map[string]interface{}{"key": "FIELD", "value": "/a"},
+		}}},
+		Stages: []*StageConfiguration{
+			{
+				InstanceName:  "Identity_01",
+				StageName:     "identity",
+				Configuration: []Config{{Name: "conf.field", Value: "${FIELD}"}},
+				InputLanes:    []string{"innerInput"},
+				OutputLanes:   []string{"innerOutput"},
+			},
+		},
+	}
+	outerFragment := &PipelineFragmentConfiguration{
+		PipelineId:         "outer",
+		FragmentInstanceId: "outer_01",
+		Fragments:          []*PipelineFragmentConfiguration{innerFragment},
+		Stages: []*StageConfiguration{
+			{
+				InstanceName: "Identity_01",
+				StageName:    "identity",
+				InputLanes:   []string{"outerInput"},
+				OutputLanes:  []string{"outerLane"},
+			},
+			{
+				InstanceName: "innerFragment_01",
+				StageName:    FragmentProcessorStageName,
+				Configuration: []Config{
+					{Name: ConfFragmentId, Value: "inner"},
+					{Name: ConfFragmentInstanceId, Value: "inner_01"},
+				},
+				InputLanes:  []string{"outerLane"},
+				OutputLanes: []string{"outerOutput"},
+			},
+		},
+	}
+	pipelineConfiguration := &PipelineConfiguration{
+		Fragments: []*PipelineFragmentConfiguration{outerFragment},
+		Stages: []*StageConfiguration{
+			{InstanceName: "Source_01", StageName: "source", OutputLanes: []string{"sourceOutput"}},
+			{
+				InstanceName: "outerFragment_01",
+				StageName:    FragmentProcessorStageName,
+				Configuration: []Config{
+					{Name: ConfFragmentId, Value: "outer"},
+					{Name: ConfFragmentInstanceId, Value: "outer_01"},
+				},
+				InputLanes:  []string{"sourceOutput"},
+				OutputLanes: []string{"fragmentOutput"},
+			},
+			{InstanceName: "Target_01", StageName: "target", InputLanes: []string{"fragmentOutput"}},
+		},
+	}
+
+	err := pipelineConfiguration.ProcessFragmentStages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pipelineConfiguration.Stages) != 4 {
+		t.Fatalf("Expected 4 stages, got %d", len(pipelineConfiguration.Stages))
+	}
+	outerStage := pipelineConfiguration.Stages[1]
+	innerStage := pipelineConfiguration.Stages[2]
+	if outerStage.InstanceName != "outer_01_Identity_01" ||
+		innerStage.InstanceName != "outer_01_inner_01_Identity_01" {
+		t.Errorf("Unexpected instance names: %s, %s", outerStage.InstanceName, innerStage.InstanceName)
+	}
+	if outerStage.InputLanes[0] != "sourceOutput" ||
+		outerStage.OutputLanes[0] != "outer_01_outerLane" ||
+		innerStage.InputLanes[0] != "outer_01_outerLane" ||
+		innerStage.OutputLanes[0] != "fragmentOutput" {
+		t.Errorf(
+			"Unexpected lanes: %v %v %v %v",
+			outerStage.InputLanes,
+			outerStage.OutputLanes,
+			innerStage.InputLanes,
+			innerStage.OutputLanes,
+		)
+	}
+	if innerStage.Configuration[0].Value != "${outer_01_inner_01_FIELD}" {
+		t.Errorf("Unexpected parameter substitution: %v", innerStage.Configuration[0].Value)
+	}
+	constants := getConstants(pipelineConfiguration.Configuration)
+	if len(constants) != 1 || constants[0].Name != "outer_01_inner_01_FIELD" || constants[0].Value != "/a" {
+		t.Errorf("Unexpected constants: %v", constants)
+	}
+	if outerFragment.Stages[0].InstanceName != "Identity_01" {
+		t.Error("Fragment definition must not be modified")
+	}
+}
+
+func TestProcessFragmentStagesMapsDeclaredLanes(t *testing.T) {
+	fragment := &PipelineFragmentConfiguration{
+		PipelineId:         "router",
+		FragmentInstanceId: "router_01",
+		UiInfo: map[string]interface{}{
+			FragmentStageConfiguration: map[string]interface{}{
+				"outputLanes": []interface{}{"routerOutputA", "routerOutputB"},
+			},
+		},
+		Configuration: []Config{{Name: ConfConstants, Value: []interface{}{
+			map[string]interface{}{"key": "FIELD", "value": "/a"},
+			map[string]interface{}{"key": "FIELD_NAME", "value": "a"},
+		}}},
+		Stages: []*StageConfiguration{
+			{
+				InstanceName: "Selector_01",
+				StageName:    "selector",
+				Configuration: []Config{{
+					Name:  "conf.expression",
+					Value: "${FIELD}/${FIELD_NAME} ${record:value(FIELD)} ${record:FIELD} FIELD",
+				}},
+				InputLanes:  []string{"routerInput"},
+				OutputLanes: []string{"routerOutputB", "routerOutputA"},
+			},
+		},
+	}
+	pipelineConfiguration := &PipelineConfiguration{
+		Fragments: []*PipelineFragmentConfiguration{fragment},
+		Stages: []*StageConfiguration{
+			{InstanceName: "Source_01", StageName: "source", OutputLanes: []string{"sourceOutput"}},
+			{
+				InstanceName: "routerFragment_01",
+				StageName:    FragmentProcessorStageName,
+				Configuration: []Config{
+					{Name: ConfFragmentId, Value: "router"},
+					{Name: ConfFragmentInstanceId, Value: "router_01"},
+				},
+				InputLanes:  []string{"sourceOutput"},
+				OutputLanes: []string{"pipelineOutputA", "pipelineOutputB"},
+			},
+			{InstanceName: "TargetA_01", StageName: "target", InputLanes: []string{"pipelineOutputA"}},
+			{InstanceName: "TargetB_01", StageName: "target", InputLanes: []string{"pipelineOutputB"}},
+		},
+	}
+
+	if err := pipelineConfiguration.ProcessFragmentStages(); err != nil {
+		t.Fatal(err)
+	}
+
+	selectorStage := pipelineConfiguration.Stages[1]
+	if selectorStage.InstanceName != "router_01_Selector_01" {
+		t.Fatalf("Unexpected stage '%s'", selectorStage.InstanceName)
+	}
+	if selectorStage.InputLanes[0] != "sourceOutput" {
+		t.Errorf("Expected input lane 'sourceOutput', but got %v", selectorStage.InputLanes)
+	}
+	if selectorStage.OutputLanes[0] != "pipelineOutputB" || selectorStage.OutputLanes[1] != "pipelineOutputA" {
+		t.Errorf("Expected output lanes mapped by their declared names, but got %v", selectorStage.OutputLanes)
+	}
+
+	expectedExpression := "${router_01_FIELD}/${router_01_FIELD_NAME} ${record:value(router_01_FIELD)} " +
+		"${record:FIELD} FIELD"
+	if selectorStage.Configuration[0].Value != expectedExpression {
+		t.Errorf("Expected expression '%s', but got '%v'", expectedExpression, selectorStage.Configuration[0].Value)
+	}
+}
+
+func TestGetOutputAndEventLanes(t *testing.T) {
+	stageConfiguration := StageConfiguration{OutputLanes: []string{"output"}, EventLanes: []string{"event"}}
+	lanes := stageConfiguration.GetOutputAndEventLanes()
+	if len(lanes) != 2 || lanes[0] != "output" || lanes[1] != "event" {
+		t.Errorf("Expected lanes [output event], but got %v", lanes)
+	}
+}
+
+func TestProcessFragmentStagesMissingFragment(t *testing.T) {
+	pipelineConfiguration := &PipelineConfiguration{
+		Stages: []*StageConfiguration{
+			{
+				InstanceName:  "fragment_01",
+				StageName:     FragmentSourceStageName,
+				Configuration: []Config{{Name: ConfFragmentId, Value: "missing"}},
+			},
+		},
+	}
+	if err := pipelineConfiguration.ProcessFragmentStages(); err == nil {
+		t.Error("Expected error for missing fragment")
+	}
+}
+
 var samplePipelineConfigWithFragments = `
 {
   "schemaVersion" : 5,
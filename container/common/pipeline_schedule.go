@@ -0,0 +1,32 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package common
+
+const (
+	ScheduleActionStart = "START"
+	ScheduleActionStop  = "STOP"
+)
+
+// PipelineSchedule starts or stops a pipeline whenever its cron expression matches in the schedule's time zone.
+// Schedules that start the pipeline pass their runtime parameters to the pipeline.
+type PipelineSchedule struct {
+	Id                 string                 `json:"id"`
+	Name               string                 `json:"name"`
+	Action             string                 `json:"action"`
+	CronExpression     string                 `json:"cronExpression"`
+	TimeZone           string                 `json:"timeZone"`
+	RuntimeParameters  map[string]interface{} `json:"runtimeParameters"`
+	Enabled            bool                   `json:"enabled"`
+	LastFiredTimestamp int64                  `json:"lastFiredTimestamp"`
+	NextFireTimestamp  int64                  `json:"nextFireTimestamp,omitempty"`
+}
@@ -34,3 +34,18 @@ type PipelineState struct {
 	Attributes map[string]interface{} `json:"attributes"`
 	Metrics    string                 `json:"metrics"`
 }
+
+// CopyPipelineState returns a copy of the state that doesn't share its attributes with the given state.
+func CopyPipelineState(pipelineState *PipelineState) *PipelineState {
+	if pipelineState == nil {
+		return nil
+	}
+	pipelineStateCopy := *pipelineState
+	if pipelineState.Attributes != nil {
+		pipelineStateCopy.Attributes = make(map[string]interface{}, len(pipelineState.Attributes))
+		for key, value := range pipelineState.Attributes {
+			pipelineStateCopy.Attributes[key] = value
+		}
+	}
+	return &pipelineStateCopy
+}
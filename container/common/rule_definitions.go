@@ -0,0 +1,86 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package common
+
+const (
+	RuleDefinitionsSchemaVersion = 2
+	RuleDefinitionsVersion       = 2
+
+	MetricTypeCounter   = "COUNTER"
+	MetricTypeMeter     = "METER"
+	MetricTypeTimer     = "TIMER"
+	MetricTypeHistogram = "HISTOGRAM"
+	MetricTypeGauge     = "GAUGE"
+
+	ThresholdTypeCount      = "COUNT"
+	ThresholdTypePercentage = "PERCENTAGE"
+)
+
+type RuleDefinitions struct {
+	SchemaVersion          int                      `json:"schemaVersion"`
+	Version                int                      `json:"version"`
+	MetricsRuleDefinitions []*MetricsRuleDefinition `json:"metricsRuleDefinitions"`
+	DataRuleDefinitions    []*DataRuleDefinition    `json:"dataRuleDefinitions"`
+	DriftRuleDefinitions   []*DataRuleDefinition    `json:"driftRuleDefinitions"`
+	EmailIds               []string                 `json:"emailIds"`
+	UUID                   string                   `json:"uuid"`
+	Configuration          []Config                 `json:"configuration"`
+	RuleIssues             []interface{}            `json:"ruleIssues"`
+	ConfigIssues           []interface{}            `json:"configIssues"`
+}
+
+// MetricsRuleDefinition raises an alert when the condition, an EL using value() for the selected element of the
+// metric, evaluates to true.
+type MetricsRuleDefinition struct {
+	Id            string `json:"id"`
+	AlertText     string `json:"alertText"`
+	MetricId      string `json:"metricId"`
+	MetricType    string `json:"metricType"`
+	MetricElement string `json:"metricElement"`
+	Condition     string `json:"condition"`
+	SendEmail     bool   `json:"sendEmail"`
+	Enabled       bool   `json:"enabled"`
+	Valid         bool   `json:"valid"`
+	Timestamp     int64  `json:"timestamp"`
+}
+
+// DataRuleDefinition evaluates the condition on a sample of the records passing through a lane and raises an
+// alert when the number, or the percentage, of matching records exceeds the threshold.
+type DataRuleDefinition struct {
+	Id                      string  `json:"id"`
+	Label                   string  `json:"label"`
+	Lane                    string  `json:"lane"`
+	SamplingPercentage      float64 `json:"samplingPercentage"`
+	SamplingRecordsToRetain int     `json:"samplingRecordsToRetain"`
+	Condition               string  `json:"condition"`
+	AlertEnabled            bool    `json:"alertEnabled"`
+	AlertText               string  `json:"alertText"`
+	ThresholdType           string  `json:"thresholdType"`
+	ThresholdValue          string  `json:"thresholdValue"`
+	MinVolume               int64   `json:"minVolume"`
+	MeterEnabled            bool    `json:"meterEnabled"`
+	SendEmail               bool    `json:"sendEmail"`
+	Enabled                 bool    `json:"enabled"`
+	Valid                   bool    `json:"valid"`
+	Timestamp               int64   `json:"timestamp"`
+}
+
+type AlertInfo struct {
+	PipelineName       string      `json:"pipelineName"`
+	RuleId             string      `json:"ruleId"`
+	RuleDefinition     interface{} `json:"ruleDefinition"`
+	AlertText          string      `json:"alertText"`
+	CurrentValue       interface{} `json:"currentValue"`
+	Timestamp          int64       `json:"timestamp"`
+	LastFiredTimestamp int64       `json:"lastFiredTimestamp"`
+}
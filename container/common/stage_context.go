@@ -24,6 +24,7 @@ import (
 	"github.com/streamsets/datacollector-edge/container/util"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -40,7 +41,9 @@ type StageContextImpl struct {
 	Services          map[string]api.Service
 	ElContext         context.Context
 	previewMode       bool
-	stop              bool
+	stopOnce          sync.Once
+	stopChanOnce      sync.Once
+	stopChan          chan struct{}
 }
 
 func (s *StageContextImpl) GetResolvedValue(configValue interface{}) (interface{}, error) {
@@ -215,12 +218,33 @@ func (s *StageContextImpl) GetPipelineParameters() map[string]interface{} {
 	return s.Parameters
 }
 
+// SetStop stops the stage, it may be called from any goroutine while the stage is running.
 func (s *StageContextImpl) SetStop() {
-	s.stop = true
+	stopChan := s.stopped()
+	s.stopOnce.Do(func() {
+		close(stopChan)
+	})
 }
 
 func (s *StageContextImpl) IsStopped() bool {
-	return s.stop
+	select {
+	case <-s.stopped():
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *StageContextImpl) Done() <-chan struct{} {
+	return s.stopped()
+}
+
+// stopped returns the channel that is closed once the stage is stopped.
+func (s *StageContextImpl) stopped() chan struct{} {
+	s.stopChanOnce.Do(func() {
+		s.stopChan = make(chan struct{})
+	})
+	return s.stopChan
 }
 
 func constructErrorRecord(instanceName string, err error, errorRecordPolicy string, record api.Record) api.Record {
@@ -34,6 +34,9 @@ type ConfigDefinition struct {
 	Required   bool
 	FieldName  string
 	Evaluation string
+	Group      string
+	Min        *float64
+	Max        *float64
 	Model      ModelDefinition
 }
 
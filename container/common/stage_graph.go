@@ -0,0 +1,125 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package common
+
+import (
+	"fmt"
+	"github.com/streamsets/datacollector-edge/api/validation"
+	"strings"
+)
+
+const (
+	DanglingInputLaneMessage     = "Stage has input lanes that are not produced by any stage: %s"
+	UnconnectedOutputLaneMessage = "Stage has output lanes that are not connected to any stage: %s"
+	StageCycleMessage            = "Stage is part of, or downstream of, a cycle in the pipeline graph"
+)
+
+// SortStages orders the given stages topologically from their input, output and event lanes, so that every
+// stage comes after all the stages producing its input lanes. Stages that don't depend on each other keep their
+// configured order. Dangling input lanes, unconnected output lanes and cycles are returned as issues.
+func SortStages(stages []*StageConfiguration) ([]*StageConfiguration, []validation.Issue) {
+	issues := make([]validation.Issue, 0)
+
+	laneProducers := make(map[string][]int)
+	laneConsumers := make(map[string]bool)
+	for i, stage := range stages {
+		for _, lane := range stage.OutputLanes {
+			laneProducers[lane] = append(laneProducers[lane], i)
+		}
+		for _, lane := range stage.EventLanes {
+			laneProducers[lane] = append(laneProducers[lane], i)
+		}
+		for _, lane := range stage.InputLanes {
+			laneConsumers[lane] = true
+		}
+	}
+
+	upstreamStages := make([][]int, len(stages))
+	for i, stage := range stages {
+		danglingLanes := make([]string, 0)
+		for _, lane := range stage.InputLanes {
+			if producers, ok := laneProducers[lane]; ok {
+				upstreamStages[i] = append(upstreamStages[i], producers...)
+			} else {
+				danglingLanes = append(danglingLanes, lane)
+			}
+		}
+		if len(danglingLanes) > 0 {
+			issues = append(issues, newStageGraphIssue(
+				stage.InstanceName,
+				fmt.Sprintf(DanglingInputLaneMessage, strings.Join(danglingLanes, ", ")),
+			))
+		}
+
+		unconnectedLanes := make([]string, 0)
+		for _, lane := range stage.OutputLanes {
+			if !laneConsumers[lane] {
+				unconnectedLanes = append(unconnectedLanes, lane)
+			}
+		}
+		if len(unconnectedLanes) > 0 {
+			issues = append(issues, newStageGraphIssue(
+				stage.InstanceName,
+				fmt.Sprintf(UnconnectedOutputLaneMessage, strings.Join(unconnectedLanes, ", ")),
+			))
+		}
+	}
+
+	sorted := make([]*StageConfiguration, 0, len(stages))
+	added := make([]bool, len(stages))
+	for len(sorted) < len(stages) {
+		progress := false
+		for i, stage := range stages {
+			if added[i] {
+				continue
+			}
+			ready := true
+			for _, upstreamStage := range upstreamStages[i] {
+				if !added[upstreamStage] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				sorted = append(sorted, stage)
+				added[i] = true
+				progress = true
+				// restart from the beginning to keep independent stages in their configured order
+				break
+			}
+		}
+		if !progress {
+			break
+		}
+	}
+
+	if len(sorted) < len(stages) {
+		for i, stage := range stages {
+			if !added[i] {
+				issues = append(issues, newStageGraphIssue(stage.InstanceName, StageCycleMessage))
+				sorted = append(sorted, stage)
+			}
+		}
+	}
+
+	return sorted, issues
+}
+
+func newStageGraphIssue(instanceName string, message string) validation.Issue {
+	return validation.Issue{
+		InstanceName: instanceName,
+		Level:        StageConfig,
+		Count:        1,
+		Message:      message,
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package common
+
+import (
+	"testing"
+)
+
+func newTestStage(instanceName string, inputLanes []string, outputLanes []string) *StageConfiguration {
+	return &StageConfiguration{
+		InstanceName: instanceName,
+		InputLanes:   inputLanes,
+		OutputLanes:  outputLanes,
+		EventLanes:   []string{},
+	}
+}
+
+func TestSortStages(t *testing.T) {
+	stages := []*StageConfiguration{
+		newTestStage("target", []string{"processor2Out"}, []string{}),
+		newTestStage("processor2", []string{"processor1Out"}, []string{"processor2Out"}),
+		newTestStage("origin", []string{}, []string{"originOut"}),
+		newTestStage("processor1", []string{"originOut"}, []string{"processor1Out"}),
+	}
+
+	sorted, issues := SortStages(stages)
+	if len(issues) != 0 {
+		t.Fatalf("Expected no issues, but got %v", issues)
+	}
+
+	expectedOrder := []string{"origin", "processor1", "processor2", "target"}
+	for i, instanceName := range expectedOrder {
+		if sorted[i].InstanceName != instanceName {
+			t.Errorf("Expected stage '%s' at position %d, but got '%s'", instanceName, i, sorted[i].InstanceName)
+		}
+	}
+}
+
+func TestSortStagesInvalidGraph(t *testing.T) {
+	stages := []*StageConfiguration{
+		newTestStage("origin", []string{}, []string{"originOut", "unconnectedOut"}),
+		newTestStage("processor1", []string{"originOut", "processor2Out"}, []string{"processor1Out"}),
+		newTestStage("processor2", []string{"processor1Out"}, []string{"processor2Out"}),
+		newTestStage("target", []string{"missingOut"}, []string{}),
+	}
+
+	sorted, issues := SortStages(stages)
+	if len(sorted) != len(stages) {
+		t.Errorf("Expected %d stages, but got %d", len(stages), len(sorted))
+	}
+
+	expectedIssues := map[string]string{
+		"origin":     "Stage has output lanes that are not connected to any stage: unconnectedOut",
+		"processor1": StageCycleMessage,
+		"processor2": StageCycleMessage,
+		"target":     "Stage has input lanes that are not produced by any stage: missingOut",
+	}
+	if len(issues) != len(expectedIssues) {
+		t.Fatalf("Expected %d issues, but got %v", len(expectedIssues), issues)
+	}
+	for _, issue := range issues {
+		if expectedIssues[issue.InstanceName] != issue.Message {
+			t.Errorf("Unexpected issue '%s' for stage '%s'", issue.Message, issue.InstanceName)
+		}
+	}
+}
@@ -117,10 +117,16 @@ func (m *MessageEventHandler) SendEvent(sendInfoEvent bool) error {
 					return err
 				}
 
+				alerts, err := runner.GetAlerts()
+				if err != nil {
+					log.WithError(err).Error()
+					return err
+				}
+
 				if pipelineState.Status != common.EDITED {
 					pipelineStatusEventList = append(
 						pipelineStatusEventList,
-						m.createPipelineStatusEvent(pipelineState, offsetString, runner.IsRemotePipeline()),
+						m.createPipelineStatusEvent(pipelineState, offsetString, runner.IsRemotePipeline(), alerts),
 					)
 				}
 			}
@@ -274,6 +280,7 @@ func (m *MessageEventHandler) createPipelineStatusEvent(
 	pipelineState *common.PipelineState,
 	offsetString string,
 	isRemote bool,
+	alerts []*common.AlertInfo,
 ) *PipelineStatusEvent {
 	pipelineStatusEvent := &PipelineStatusEvent{
 		Name:           pipelineState.PipelineId,
@@ -283,6 +290,7 @@ func (m *MessageEventHandler) createPipelineStatusEvent(
 		PipelineStatus: pipelineState.Status,
 		Message:        pipelineState.Message,
 		Offset:         offsetString,
+		Alerts:         alerts,
 	}
 	return pipelineStatusEvent
 }
@@ -335,6 +343,16 @@ func (m *MessageEventHandler) handleDPMEvent(serverEvent ServerEvent) *ClientEve
 			break
 		}
 
+		if len(pipelineSaveEvent.PipelineConfigurationAndRules.PipelineRules) > 0 {
+			err = m.saveRules(pipelineSaveEvent.Name, pipelineSaveEvent.PipelineConfigurationAndRules.PipelineRules)
+			if err != nil {
+				ackEventMessage = err.Error()
+				ackEventStatus = ACK_EVENT_ERROR
+				log.WithError(err).Error("Error during handling Control Hub SAVE Pipeline Event")
+				break
+			}
+		}
+
 		// Update offset
 		runner := m.manager.GetRunner(pipelineSaveEvent.Name)
 		if runner != nil && len(pipelineSaveEvent.Offset) > 0 {
@@ -351,6 +369,22 @@ func (m *MessageEventHandler) handleDPMEvent(serverEvent ServerEvent) *ClientEve
 				}
 			}
 		}
+	case SAVE_RULES_PIPELINE:
+		var pipelineSaveRulesEvent PipelineSaveRulesEvent
+		if err := json.Unmarshal([]byte(serverEvent.Payload), &pipelineSaveRulesEvent); err != nil {
+			ackEventMessage = err.Error()
+			ackEventStatus = ACK_EVENT_ERROR
+			log.WithError(err).Error("Error during handling Control Hub SAVE Rules Pipeline Event")
+			break
+		}
+
+		err := m.saveRules(pipelineSaveRulesEvent.Name, pipelineSaveRulesEvent.RuleDefinitions)
+		if err != nil {
+			ackEventMessage = err.Error()
+			ackEventStatus = ACK_EVENT_ERROR
+			log.WithError(err).Error("Error during handling Control Hub SAVE Rules Pipeline Event")
+			break
+		}
 	case START_PIPELINE:
 		var pipelineBaseEvent PipelineBaseEvent
 		if err := json.Unmarshal([]byte(serverEvent.Payload), &pipelineBaseEvent); err != nil {
@@ -451,6 +485,20 @@ func (m *MessageEventHandler) handleDPMEvent(serverEvent ServerEvent) *ClientEve
 	return ackClientEvent
 }
 
+// saveRules saves the rule definitions JSON sent by Control Hub and applies it to the pipeline runner.
+func (m *MessageEventHandler) saveRules(pipelineId string, ruleDefinitionsJson string) error {
+	var ruleDefinitions common.RuleDefinitions
+	if err := json.Unmarshal([]byte(ruleDefinitionsJson), &ruleDefinitions); err != nil {
+		return err
+	}
+
+	if _, err := m.pipelineStoreTask.SaveRules(pipelineId, ruleDefinitions); err != nil {
+		return err
+	}
+
+	return m.manager.GetRunner(pipelineId).ReloadRules()
+}
+
 func (m *MessageEventHandler) Shutdown() {
 	m.quitSendingEventToDPM <- true
 }
@@ -0,0 +1,302 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package creation
+
+import (
+	"fmt"
+	"github.com/spf13/cast"
+	"github.com/streamsets/datacollector-edge/api/configtype"
+	"github.com/streamsets/datacollector-edge/api/validation"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/el"
+	"github.com/streamsets/datacollector-edge/stages/stagelibrary"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxConfigNameDistance = 2
+
+// ValidateStageConfigs checks the configuration of the stage and its services against the config definitions of
+// the stage library, without creating the stage for the pipeline or initializing it.
+func ValidateStageConfigs(stageConfig *common.StageConfiguration) []validation.Issue {
+	issues := make([]validation.Issue, 0)
+
+	stageDefinition, err := stagelibrary.GetStageDefinition(stageConfig.Library, stageConfig.StageName)
+	if err != nil {
+		return append(issues, newConfigIssue(stageConfig.InstanceName, "", "", err.Error()))
+	}
+	issues = append(issues, validateConfigs(
+		stageConfig.InstanceName,
+		stageConfig.Configuration,
+		stageDefinition.ConfigDefinitionsMap,
+	)...)
+
+	for _, serviceConfig := range stageConfig.Services {
+		serviceDefinition, err := stagelibrary.GetServiceDefinition(serviceConfig.Service)
+		if err != nil {
+			issues = append(issues, newConfigIssue(
+				stageConfig.InstanceName,
+				"",
+				"",
+				fmt.Sprintf("Service '%s' is not available", serviceConfig.Service),
+			))
+			continue
+		}
+		issues = append(issues, validateConfigs(
+			stageConfig.InstanceName,
+			serviceConfig.Configuration,
+			serviceDefinition.ConfigDefinitionsMap,
+		)...)
+	}
+
+	return issues
+}
+
+func validateConfigs(
+	instanceName string,
+	configs []common.Config,
+	configDefinitionsMap map[string]*common.ConfigDefinition,
+) []validation.Issue {
+	issues := make([]validation.Issue, 0)
+	configMap := make(map[string]interface{})
+
+	for _, config := range configs {
+		configMap[config.Name] = config.Value
+		if _, ok := configDefinitionsMap[config.Name]; ok || isSystemConfig(config.Name) {
+			continue
+		}
+		// pipelines exported from Data Collector carry configs the edge stages don't support, so only names
+		// that look like a misspelling of a known config are reported
+		if similarConfigName := findSimilarConfigName(config.Name, configDefinitionsMap); similarConfigName != "" {
+			issues = append(issues, newConfigIssue(
+				instanceName,
+				configDefinitionsMap[similarConfigName].Group,
+				config.Name,
+				fmt.Sprintf("Unknown configuration '%s', did you mean '%s'?", config.Name, similarConfigName),
+			))
+		}
+	}
+
+	return append(issues, validateConfigValues(instanceName, configMap, configDefinitionsMap, true)...)
+}
+
+func validateConfigValues(
+	instanceName string,
+	configMap map[string]interface{},
+	configDefinitionsMap map[string]*common.ConfigDefinition,
+	checkRequired bool,
+) []validation.Issue {
+	issues := make([]validation.Issue, 0)
+
+	// sorted so that the issues are reported in a stable order
+	configNames := make([]string, 0, len(configDefinitionsMap))
+	for configName := range configDefinitionsMap {
+		configNames = append(configNames, configName)
+	}
+	sort.Strings(configNames)
+
+	for _, configName := range configNames {
+		configDef := configDefinitionsMap[configName]
+		value := configMap[configName]
+		newIssue := func(message string) validation.Issue {
+			return newConfigIssue(instanceName, configDef.Group, configName, message)
+		}
+
+		if value == nil || value == "" {
+			// the nested configs of a bean, like the data format configs, and the fields of list bean rows are
+			// only required for some of the options of the stage, which the config definitions don't capture
+			if checkRequired && configDef.Required && configDef.Type != configtype.BOOLEAN &&
+				!strings.Contains(configName, ".") {
+				issues = append(issues, newIssue(fmt.Sprintf("Configuration '%s' is required", configName)))
+			}
+			continue
+		}
+
+		if err := validateElSyntax(configName, value); err != nil {
+			issues = append(issues, newIssue(fmt.Sprintf("Invalid expression for configuration '%s': %s", configName, err)))
+			continue
+		}
+
+		if stringValue, ok := value.(string); ok && el.IsElString(stringValue) {
+			// the type and range of expressions can only be checked once they are evaluated
+			continue
+		}
+
+		if err := validateConfigType(configDef, value); err != nil {
+			issues = append(issues, newIssue(err.Error()))
+			continue
+		}
+
+		if configDef.Type == configtype.MODEL && len(configDef.Model.ConfigDefinitionsMap) > 0 {
+			for _, listBeanValue := range value.([]interface{}) {
+				issues = append(issues, validateConfigValues(
+					instanceName,
+					listBeanValue.(map[string]interface{}),
+					configDef.Model.ConfigDefinitionsMap,
+					false,
+				)...)
+			}
+		}
+	}
+
+	return issues
+}
+
+func validateConfigType(configDef *common.ConfigDefinition, value interface{}) error {
+	switch configDef.Type {
+	case configtype.STRING:
+		switch value.(type) {
+		case string, float64, bool:
+			return nil
+		}
+	case configtype.BOOLEAN:
+		switch value.(type) {
+		case bool:
+			return nil
+		case string:
+			if _, err := strconv.ParseBool(value.(string)); err == nil {
+				return nil
+			}
+		}
+	case configtype.NUMBER:
+		numberValue, err := cast.ToFloat64E(value)
+		if _, ok := value.(bool); ok || err != nil {
+			break
+		}
+		if configDef.Min != nil && numberValue < *configDef.Min {
+			return fmt.Errorf("Configuration '%s' must be at least %v, but was %v", configDef.Name, *configDef.Min, value)
+		}
+		if configDef.Max != nil && numberValue > *configDef.Max {
+			return fmt.Errorf("Configuration '%s' must be at most %v, but was %v", configDef.Name, *configDef.Max, value)
+		}
+		return nil
+	case configtype.LIST:
+		if _, ok := value.([]interface{}); ok {
+			return nil
+		}
+	case configtype.MAP:
+		if isListOfMaps(value) {
+			return nil
+		}
+	case configtype.MODEL:
+		if len(configDef.Model.ConfigDefinitionsMap) == 0 || isListOfMaps(value) {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return fmt.Errorf("Value '%v' of configuration '%s' is not of type %s", value, configDef.Name, configDef.Type)
+}
+
+func validateElSyntax(configName string, value interface{}) error {
+	switch t := value.(type) {
+	case string:
+		if el.IsElString(t) {
+			return el.ValidateSyntax(t, configName)
+		}
+	case []interface{}:
+		for _, val := range t {
+			if err := validateElSyntax(configName, val); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, val := range t {
+			if err := validateElSyntax(configName, val); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isListOfMaps(value interface{}) bool {
+	listValue, ok := value.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, val := range listValue {
+		if _, ok := val.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func findSimilarConfigName(configName string, configDefinitionsMap map[string]*common.ConfigDefinition) string {
+	similarConfigName := ""
+	minDistance := maxConfigNameDistance + 1
+	for definedConfigName := range configDefinitionsMap {
+		if strings.EqualFold(configName, definedConfigName) {
+			return definedConfigName
+		}
+		distance := editDistance(configName, definedConfigName)
+		if distance < minDistance || (distance == minDistance && definedConfigName < similarConfigName) {
+			similarConfigName = definedConfigName
+			minDistance = distance
+		}
+	}
+	return similarConfigName
+}
+
+// editDistance returns the Levenshtein distance between the two strings.
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, minInt(current[j-1]+1, previous[j-1]+cost))
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func isSystemConfig(configName string) bool {
+	switch configName {
+	case "stageOnRecordError",
+		"stageRequiredFields",
+		"stageRecordPreconditions",
+		"stageExecutionTimeout",
+		"stageRecordParallelism":
+		return true
+	}
+	return false
+}
+
+func newConfigIssue(instanceName string, configGroup string, configName string, message string) validation.Issue {
+	return validation.Issue{
+		InstanceName: instanceName,
+		ConfigGroup:  configGroup,
+		ConfigName:   configName,
+		Level:        common.StageConfig,
+		Count:        1,
+		Message:      message,
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package creation
+
+import (
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/stages/stagelibrary"
+	"testing"
+)
+
+const (
+	testLibrary   = "streamsets-datacollector-test-lib"
+	testStageName = "com_streamsets_pipeline_stage_test_ValidatedStage"
+)
+
+type validatedStage struct {
+	*common.BaseStage
+	Conf          validatedStageConfig `ConfigDefBean:"conf"`
+	Url           string               `ConfigDef:"type=STRING,required=true"`
+	BatchSize     float64              `ConfigDef:"type=NUMBER,required=true,min=1,max=1000,group=BATCH"`
+	Enabled       bool                 `ConfigDef:"type=BOOLEAN,required=true"`
+	FieldPaths    []string             `ConfigDef:"type=LIST,required=false"`
+	Headers       map[string]string    `ConfigDef:"type=MAP,required=false"`
+	FieldMappings []fieldMapping       `ConfigDef:"type=MODEL,evaluation=EXPLICIT" ListBeanModel:"name=fieldMappings"`
+}
+
+type validatedStageConfig struct {
+	DataFormat string `ConfigDef:"type=STRING,required=true"`
+}
+
+type fieldMapping struct {
+	Field      string `ConfigDef:"type=STRING,required=true"`
+	Expression string `ConfigDef:"type=STRING,evaluation=EXPLICIT,required=true"`
+}
+
+func init() {
+	stagelibrary.SetCreator(testLibrary, testStageName, func() api.Stage {
+		return &validatedStage{BaseStage: &common.BaseStage{}}
+	})
+}
+
+func newValidatedStageConfig(configs ...common.Config) *common.StageConfiguration {
+	configuration := []common.Config{
+		{Name: "conf.dataFormat", Value: "JSON"},
+		{Name: "url", Value: "http://localhost:9999"},
+		{Name: "batchSize", Value: float64(100)},
+		{Name: "enabled", Value: true},
+		{Name: "fieldPaths", Value: []interface{}{"/a", "/b"}},
+		{Name: "headers", Value: []interface{}{map[string]interface{}{"key": "k", "value": "v"}}},
+		{Name: "fieldMappings", Value: []interface{}{
+			map[string]interface{}{"field": "/c", "expression": "${1 + 2}"},
+		}},
+		{Name: "stageOnRecordError", Value: "TO_ERROR"},
+	}
+	for _, config := range configs {
+		replaced := false
+		for i := range configuration {
+			if configuration[i].Name == config.Name {
+				configuration[i] = config
+				replaced = true
+			}
+		}
+		if !replaced {
+			configuration = append(configuration, config)
+		}
+	}
+	return &common.StageConfiguration{
+		InstanceName:  "validatedStage_01",
+		Library:       testLibrary,
+		StageName:     testStageName,
+		Configuration: configuration,
+	}
+}
+
+func TestValidateStageConfigs(t *testing.T) {
+	issues := ValidateStageConfigs(newValidatedStageConfig())
+	if len(issues) != 0 {
+		t.Fatalf("Expected no issues, but got %v", issues)
+	}
+
+	// the configs of a bean are only required for some options and the pipeline may not set them
+	issues = ValidateStageConfigs(newValidatedStageConfig(common.Config{Name: "conf.dataFormat", Value: nil}))
+	if len(issues) != 0 {
+		t.Fatalf("Expected no issues, but got %v", issues)
+	}
+}
+
+func TestValidateStageConfigsInvalid(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      common.Config
+		configName  string
+		configGroup string
+	}{
+		{"required", common.Config{Name: "url", Value: ""}, "url", ""},
+		{"type", common.Config{Name: "batchSize", Value: "abc"}, "batchSize", "BATCH"},
+		{"min", common.Config{Name: "batchSize", Value: float64(0)}, "batchSize", "BATCH"},
+		{"max", common.Config{Name: "batchSize", Value: "1001"}, "batchSize", "BATCH"},
+		{"boolean", common.Config{Name: "enabled", Value: "yes"}, "enabled", ""},
+		{"list", common.Config{Name: "fieldPaths", Value: "/a"}, "fieldPaths", ""},
+		{"map", common.Config{Name: "headers", Value: []interface{}{"k"}}, "headers", ""},
+		{"el", common.Config{Name: "url", Value: "${1 +}"}, "url", ""},
+		{
+			"model el",
+			common.Config{Name: "fieldMappings", Value: []interface{}{
+				map[string]interface{}{"field": "/c", "expression": "${(1 + 2}"},
+			}},
+			"fieldMappings",
+			"",
+		},
+		{"unknown", common.Config{Name: "batchSise", Value: float64(100)}, "batchSise", "BATCH"},
+	}
+
+	for _, testCase := range testCases {
+		issues := ValidateStageConfigs(newValidatedStageConfig(testCase.config))
+		if len(issues) != 1 {
+			t.Errorf("%s: Expected 1 issue, but got %v", testCase.name, issues)
+			continue
+		}
+		if issues[0].InstanceName != "validatedStage_01" {
+			t.Errorf("%s: Unexpected instance name '%s'", testCase.name, issues[0].InstanceName)
+		}
+		if issues[0].ConfigName != testCase.configName {
+			t.Errorf("%s: Expected config name '%s', but got '%s'", testCase.name, testCase.configName, issues[0].ConfigName)
+		}
+		if issues[0].ConfigGroup != testCase.configGroup {
+			t.Errorf("%s: Expected config group '%s', but got '%s'", testCase.name, testCase.configGroup, issues[0].ConfigGroup)
+		}
+	}
+}
+
+func TestValidateStageConfigsUnknownStageAndService(t *testing.T) {
+	stageConfig := newValidatedStageConfig()
+	stageConfig.StageName = "unknownStage"
+	if issues := ValidateStageConfigs(stageConfig); len(issues) != 1 {
+		t.Errorf("Expected 1 issue for unknown stage, but got %v", issues)
+	}
+
+	stageConfig = newValidatedStageConfig()
+	stageConfig.Services = []*common.ServiceConfiguration{{Service: "unknownService"}}
+	issues := ValidateStageConfigs(stageConfig)
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue for unknown service, but got %v", issues)
+	}
+	if issues[0].Message != "Service 'unknownService' is not available" {
+		t.Errorf("Unexpected message: %s", issues[0].Message)
+	}
+}
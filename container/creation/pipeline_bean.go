@@ -36,6 +36,13 @@ func NewPipelineBean(
 	var pipelineBean PipelineBean
 	var err error
 
+	for _, stageConfig := range getStageConfigs(pipelineConfig) {
+		issues = append(issues, ValidateStageConfigs(stageConfig)...)
+	}
+	if len(issues) > 0 {
+		return pipelineBean, issues
+	}
+
 	pipelineBean.Config = NewPipelineConfigBean(pipelineConfig)
 	pipelineBean.ElContext = initializeElContext(pipelineConfig, pipelineBean.Config)
 
@@ -84,6 +91,18 @@ func NewPipelineBean(
 	return pipelineBean, issues
 }
 
+func getStageConfigs(pipelineConfig common.PipelineConfiguration) []*common.StageConfiguration {
+	stageConfigs := make([]*common.StageConfiguration, 0, len(pipelineConfig.Stages)+2)
+	stageConfigs = append(stageConfigs, pipelineConfig.Stages...)
+	if pipelineConfig.ErrorStage != nil && pipelineConfig.ErrorStage.InstanceName != "" {
+		stageConfigs = append(stageConfigs, pipelineConfig.ErrorStage)
+	}
+	if pipelineConfig.StatsAggregatorStage != nil && pipelineConfig.StatsAggregatorStage.InstanceName != "" {
+		stageConfigs = append(stageConfigs, pipelineConfig.StatsAggregatorStage)
+	}
+	return stageConfigs
+}
+
 func initializeElContext(
 	pipelineConfig common.PipelineConfiguration,
 	configBean PipelineConfigBean,
@@ -13,6 +13,7 @@
 package creation
 
 import (
+	"github.com/spf13/cast"
 	"github.com/streamsets/datacollector-edge/container/common"
 )
 
@@ -39,6 +40,14 @@ const (
 	MesosDispatchUrl     = "mesosDispatcherURL"
 	HdfsS3ConfigDir      = "hdfsS3ConfDir"
 	WebHookConfigs       = "webhookConfigs"
+	TriggerPipelines     = "triggerPipelines"
+
+	StoreAndForward               = "storeAndForward"
+	StoreAndForwardMaxSizeMB      = "storeAndForwardMaxSizeMB"
+	StoreAndForwardMaxAgeSecs     = "storeAndForwardMaxAgeSecs"
+	StoreAndForwardOverflowPolicy = "storeAndForwardOverflowPolicy"
+
+	WebhookAuthTypeBasic = "BASIC"
 )
 
 type PipelineConfigBean struct {
@@ -58,6 +67,33 @@ type PipelineConfigBean struct {
 	StatsAggregatorStage string
 	RateLimit            float64
 	MaxRunners           float64
+
+	StoreAndForward               bool
+	StoreAndForwardMaxSizeMB      float64
+	StoreAndForwardMaxAgeSecs     float64
+	StoreAndForwardOverflowPolicy string
+
+	WebhookConfigs   []PipelineWebhookConfig
+	TriggerPipelines []PipelineTriggerConfig
+}
+
+// PipelineTriggerConfig starts the downstream pipeline when the pipeline moves into one of the trigger states, with
+// runtime parameters evaluated from the final state and offset of the pipeline.
+type PipelineTriggerConfig struct {
+	PipelineId        string
+	OnStates          []string
+	RuntimeParameters map[string]string
+}
+
+type PipelineWebhookConfig struct {
+	WebhookUrl  string
+	Headers     map[string]string
+	HttpMethod  string
+	Payload     string
+	ContentType string
+	AuthType    string
+	Username    string
+	Password    string
 }
 
 func NewPipelineConfigBean(pipelineConfig common.PipelineConfiguration) PipelineConfigBean {
@@ -104,12 +140,93 @@ func NewPipelineConfigBean(pipelineConfig common.PipelineConfiguration) Pipeline
 			pipelineConfigBean.RateLimit = config.Value.(float64)
 		case MaxRunners:
 			pipelineConfigBean.MaxRunners = config.Value.(float64)
+		case StoreAndForward:
+			pipelineConfigBean.StoreAndForward = config.Value.(bool)
+		case StoreAndForwardMaxSizeMB:
+			pipelineConfigBean.StoreAndForwardMaxSizeMB = config.Value.(float64)
+		case StoreAndForwardMaxAgeSecs:
+			pipelineConfigBean.StoreAndForwardMaxAgeSecs = config.Value.(float64)
+		case StoreAndForwardOverflowPolicy:
+			pipelineConfigBean.StoreAndForwardOverflowPolicy = config.Value.(string)
+		case WebHookConfigs:
+			pipelineConfigBean.WebhookConfigs = newPipelineWebhookConfigs(config.Value.([]interface{}))
+		case TriggerPipelines:
+			pipelineConfigBean.TriggerPipelines = newPipelineTriggerConfigs(config.Value.([]interface{}))
 		}
 	}
 
 	return pipelineConfigBean
 }
 
+func newPipelineWebhookConfigs(webhookConfigValues []interface{}) []PipelineWebhookConfig {
+	webhookConfigs := make([]PipelineWebhookConfig, 0, len(webhookConfigValues))
+	for _, webhookConfigValue := range webhookConfigValues {
+		webhookConfigMap := cast.ToStringMap(webhookConfigValue)
+		webhookConfig := PipelineWebhookConfig{
+			WebhookUrl:  cast.ToString(webhookConfigMap["webhookUrl"]),
+			Headers:     make(map[string]string),
+			HttpMethod:  cast.ToString(webhookConfigMap["httpMethod"]),
+			Payload:     cast.ToString(webhookConfigMap["payload"]),
+			ContentType: cast.ToString(webhookConfigMap["contentType"]),
+			AuthType:    cast.ToString(webhookConfigMap["authType"]),
+			Username:    cast.ToString(webhookConfigMap["username"]),
+			Password:    cast.ToString(webhookConfigMap["password"]),
+		}
+
+		// map configs are serialized as a list of key/value pairs
+		switch headers := webhookConfigMap["headers"].(type) {
+		case []interface{}:
+			for _, header := range headers {
+				headerMap := cast.ToStringMap(header)
+				webhookConfig.Headers[cast.ToString(headerMap["key"])] = cast.ToString(headerMap["value"])
+			}
+		case map[string]interface{}:
+			for key, value := range headers {
+				webhookConfig.Headers[key] = cast.ToString(value)
+			}
+		}
+
+		if webhookConfig.WebhookUrl != "" {
+			webhookConfigs = append(webhookConfigs, webhookConfig)
+		}
+	}
+	return webhookConfigs
+}
+
+func newPipelineTriggerConfigs(triggerConfigValues []interface{}) []PipelineTriggerConfig {
+	triggerConfigs := make([]PipelineTriggerConfig, 0, len(triggerConfigValues))
+	for _, triggerConfigValue := range triggerConfigValues {
+		triggerConfigMap := cast.ToStringMap(triggerConfigValue)
+		triggerConfig := PipelineTriggerConfig{
+			PipelineId:        cast.ToString(triggerConfigMap["pipelineId"]),
+			OnStates:          cast.ToStringSlice(triggerConfigMap["onStates"]),
+			RuntimeParameters: make(map[string]string),
+		}
+		if len(triggerConfig.OnStates) == 0 {
+			triggerConfig.OnStates = []string{common.FINISHED}
+		}
+
+		// map configs are serialized as a list of key/value pairs
+		switch runtimeParameters := triggerConfigMap["runtimeParameters"].(type) {
+		case []interface{}:
+			for _, runtimeParameter := range runtimeParameters {
+				parameterMap := cast.ToStringMap(runtimeParameter)
+				triggerConfig.RuntimeParameters[cast.ToString(parameterMap["key"])] =
+					cast.ToString(parameterMap["value"])
+			}
+		case map[string]interface{}:
+			for key, value := range runtimeParameters {
+				triggerConfig.RuntimeParameters[key] = cast.ToString(value)
+			}
+		}
+
+		if triggerConfig.PipelineId != "" {
+			triggerConfigs = append(triggerConfigs, triggerConfig)
+		}
+	}
+	return triggerConfigs
+}
+
 func GetDefaultPipelineConfigs() []common.Config {
 	pipelineConfigs := []common.Config{
 		{Name: ExecutionMode, Value: "STANDALONE"},
@@ -131,6 +248,11 @@ func GetDefaultPipelineConfigs() []common.Config {
 		{Name: RateLimit, Value: 0},
 		{Name: MaxRunners, Value: 0},
 		{Name: WebHookConfigs, Value: []interface{}{}},
+		{Name: TriggerPipelines, Value: []interface{}{}},
+		{Name: StoreAndForward, Value: false},
+		{Name: StoreAndForwardMaxSizeMB, Value: 1024},
+		{Name: StoreAndForwardMaxAgeSecs, Value: 0},
+		{Name: StoreAndForwardOverflowPolicy, Value: "DROP_OLDEST"},
 		{Name: StatsAggregatorStage, Value: "streamsets-datacollector-basic-lib::com_streamsets_pipeline_stage_destination_devnull_StatsDpmDirectlyDTarget::1"},
 	}
 
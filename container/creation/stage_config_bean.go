@@ -13,6 +13,7 @@
 package creation
 
 import (
+	"github.com/spf13/cast"
 	"github.com/streamsets/datacollector-edge/container/common"
 )
 
@@ -20,6 +21,10 @@ type StageConfigBean struct {
 	StageOnRecordError       string
 	StageRequiredFields      []interface{}
 	StageRecordPreconditions []interface{}
+	// StageExecutionTimeout is the time in milliseconds a single batch may take in the stage, 0 means no timeout
+	StageExecutionTimeout float64
+	// StageRecordParallelism is the number of records of a batch processed at the same time by record processors
+	StageRecordParallelism float64
 }
 
 func NewStageConfigBean(pipelineConfig *common.StageConfiguration) StageConfigBean {
@@ -36,6 +41,12 @@ func NewStageConfigBean(pipelineConfig *common.StageConfiguration) StageConfigBe
 		case "stageRecordPreconditions":
 			stageConfigBean.StageRecordPreconditions = config.Value.([]interface{})
 			break
+		case "stageExecutionTimeout":
+			stageConfigBean.StageExecutionTimeout = cast.ToFloat64(config.Value)
+			break
+		case "stageRecordParallelism":
+			stageConfigBean.StageRecordParallelism = cast.ToFloat64(config.Value)
+			break
 		}
 	}
 	return stageConfigBean
@@ -20,6 +20,8 @@ import (
 	"github.com/streamsets/datacollector-edge/container/common"
 	"github.com/streamsets/datacollector-edge/container/controlhub"
 	"github.com/streamsets/datacollector-edge/container/execution/manager"
+	"github.com/streamsets/datacollector-edge/container/execution/scheduler"
+	pipelineStateStore "github.com/streamsets/datacollector-edge/container/execution/store"
 	"github.com/streamsets/datacollector-edge/container/http"
 	"github.com/streamsets/datacollector-edge/container/process"
 	"github.com/streamsets/datacollector-edge/container/store"
@@ -46,6 +48,7 @@ type DataCollectorEdgeMain struct {
 	WebServerTask          *http.WebServerTask
 	PipelineStoreTask      store.PipelineStoreTask
 	Manager                manager.Manager
+	Scheduler              *scheduler.Scheduler
 	processManager         process.Manager
 	DPMMessageEventHandler *controlhub.MessageEventHandler
 }
@@ -108,6 +111,9 @@ func newDataCollectorEdge(
 
 	hostName, _ := os.Hostname()
 	var httpUrl = "http://" + hostName + config.Http.BindAddress
+	if config.Http.TLS.Enabled {
+		httpUrl = "https://" + hostName + config.Http.BindAddress
+	}
 
 	if len(config.Http.BaseHttpUrl) > 0 {
 		httpUrl = config.Http.BaseHttpUrl
@@ -123,16 +129,38 @@ func newDataCollectorEdge(
 	}
 
 	runtimeInfo, _ := common.NewRuntimeInfo(httpUrl, baseDir)
-	pipelineStoreTask := store.NewFilePipelineStoreTask(*runtimeInfo)
+	runtimeStore, err := pipelineStateStore.NewRuntimeStore(config.Execution.RuntimeStore, baseDir)
+	if err != nil {
+		return nil, err
+	}
+	pipelineStateStore.SetRuntimeStore(runtimeStore)
+	pipelineStoreTask := store.NewPipelineStoreTask(*runtimeInfo, runtimeStore)
 	pipelineManager, _ := manager.NewManager(config.Execution, runtimeInfo, pipelineStoreTask)
 
+	pipelineScheduler := scheduler.NewScheduler(pipelineManager, pipelineStoreTask)
+	if err = pipelineScheduler.Init(); err != nil {
+		return nil, err
+	}
+	go pipelineScheduler.Run()
+
 	processManager, err := process.NewManager(config.Process)
 
 	if err != nil {
 		return nil, err
 	}
 
-	webServerTask, _ := http.NewWebServerTask(config.Http, buildInfo, pipelineManager, pipelineStoreTask, processManager)
+	config.Http.ResolvePaths(baseDir)
+	webServerTask, err := http.NewWebServerTask(
+		config.Http,
+		buildInfo,
+		pipelineManager,
+		pipelineStoreTask,
+		processManager,
+		pipelineScheduler,
+	)
+	if err != nil {
+		return nil, err
+	}
 	controlhub.RegisterWithControlHub(config.SCH, buildInfo, runtimeInfo)
 
 	var messagingEventHandler *controlhub.MessageEventHandler
@@ -152,6 +180,7 @@ func newDataCollectorEdge(
 		RuntimeInfo:            runtimeInfo,
 		WebServerTask:          webServerTask,
 		Manager:                pipelineManager,
+		Scheduler:              pipelineScheduler,
 		PipelineStoreTask:      pipelineStoreTask,
 		DPMMessageEventHandler: messagingEventHandler,
 	}, nil
@@ -14,6 +14,8 @@ package el
 
 import (
 	"github.com/madhukard/govaluate"
+	"github.com/spf13/cast"
+	"regexp"
 	"strings"
 )
 
@@ -22,6 +24,8 @@ const (
 	PARAMETER_SUFFIX = "}"
 )
 
+var templateExpressionRegexp = regexp.MustCompile(`\$\{[^}]*\}`)
+
 type Evaluator struct {
 	configName string
 	parameters map[string]interface{}
@@ -36,12 +40,8 @@ func (elEvaluator *Evaluator) Evaluate(expression string) (interface{}, error) {
 	if len(expression) == 0 {
 		return expression, nil
 	}
-	expression = strings.Replace(expression, PARAMETER_PREFIX, "", 1)
-	if strings.HasSuffix(expression, PARAMETER_SUFFIX) {
-		expression = expression[:len(expression)-1]
-	}
 
-	evaluableExpression, err := govaluate.NewEvaluableExpressionWithFunctions(expression, elEvaluator.functions)
+	evaluableExpression, err := elEvaluator.parse(expression)
 	if err != nil {
 		return nil, err
 	}
@@ -61,6 +61,41 @@ func (elEvaluator *Evaluator) Evaluate(expression string) (interface{}, error) {
 	return result, err
 }
 
+// Validate checks that the given expression can be parsed, without evaluating it.
+func (elEvaluator *Evaluator) Validate(expression string) error {
+	if len(expression) == 0 {
+		return nil
+	}
+	_, err := elEvaluator.parse(expression)
+	return err
+}
+
+func (elEvaluator *Evaluator) parse(expression string) (*govaluate.EvaluableExpression, error) {
+	expression = strings.Replace(expression, PARAMETER_PREFIX, "", 1)
+	if strings.HasSuffix(expression, PARAMETER_SUFFIX) {
+		expression = expression[:len(expression)-1]
+	}
+	return govaluate.NewEvaluableExpressionWithFunctions(expression, elEvaluator.functions)
+}
+
+// EvaluateTemplate replaces every EL expression embedded in the given template with its evaluated value,
+// e.g. "http://host/${pipeline:id()}/status".
+func (elEvaluator *Evaluator) EvaluateTemplate(template string) (string, error) {
+	var evaluationError error
+	result := templateExpressionRegexp.ReplaceAllStringFunc(template, func(expression string) string {
+		if evaluationError != nil {
+			return expression
+		}
+		value, err := elEvaluator.Evaluate(expression)
+		if err != nil {
+			evaluationError = err
+			return expression
+		}
+		return cast.ToString(value)
+	})
+	return result, evaluationError
+}
+
 func NewEvaluator(
 	configName string,
 	parameters map[string]interface{},
@@ -70,6 +70,27 @@ func TestSimpleExpression(test *testing.T) {
 	RunEvaluationTests(evaluationTests, nil, test)
 }
 
+func TestEvaluateTemplate(test *testing.T) {
+	evaluator, _ := NewEvaluator(
+		"Test template",
+		map[string]interface{}{"HOST": "localhost"},
+		[]Definitions{&StringEL{}},
+	)
+
+	result, err := evaluator.EvaluateTemplate("http://${HOST}:8080/${str:toUpper('status')}")
+	if err != nil {
+		test.Fatal(err)
+	}
+	if result != "http://localhost:8080/STATUS" {
+		test.Errorf("Template result '%s' does not match expected: 'http://localhost:8080/STATUS'", result)
+	}
+
+	_, err = evaluator.EvaluateTemplate("http://${UNKNOWN}/status")
+	if err == nil {
+		test.Error("Expected error for undefined parameter")
+	}
+}
+
 func RunEvaluationTests(evaluationTests []EvaluationTest, definitionsList []Definitions, test *testing.T) {
 	fmt.Printf("Running %d evaluation test cases...\n", len(evaluationTests))
 	for _, evaluationTest := range evaluationTests {
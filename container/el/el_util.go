@@ -46,3 +46,22 @@ func Evaluate(
 	)
 	return evaluator.Evaluate(value)
 }
+
+// ValidateSyntax checks that the given EL can be parsed with the functions available to stages, without
+// evaluating it.
+func ValidateSyntax(value string, configName string) error {
+	evaluator, _ := NewEvaluator(
+		configName,
+		nil,
+		[]Definitions{
+			&StringEL{},
+			&MathEL{},
+			&RecordEL{},
+			&MapListEL{},
+			&PipelineEL{},
+			&JobEL{},
+			&SdcEL{},
+		},
+	)
+	return evaluator.Validate(value)
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package el
+
+import (
+	"errors"
+	"fmt"
+	"github.com/madhukard/govaluate"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"time"
+)
+
+// MetricRuleEL provides the value of the metric element a metric rule condition is evaluated against.
+type MetricRuleEL struct {
+	Value interface{}
+}
+
+func (m *MetricRuleEL) GetValue(args ...interface{}) (interface{}, error) {
+	if len(args) != 0 {
+		return nil, errors.New(
+			fmt.Sprintf("The function 'value' requires 0 arguments but was passed %d", len(args)),
+		)
+	}
+	return util.CastToFloat64(m.Value), nil
+}
+
+func (m *MetricRuleEL) GetTimeNow(args ...interface{}) (interface{}, error) {
+	if len(args) != 0 {
+		return nil, errors.New(
+			fmt.Sprintf("The function 'time:now' requires 0 arguments but was passed %d", len(args)),
+		)
+	}
+	return float64(util.ConvertTimeToLong(time.Now())), nil
+}
+
+func (m *MetricRuleEL) GetELFunctionDefinitions() map[string]govaluate.ExpressionFunction {
+	functions := map[string]govaluate.ExpressionFunction{
+		"value":    m.GetValue,
+		"time:now": m.GetTimeNow,
+	}
+	return functions
+}
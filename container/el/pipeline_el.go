@@ -26,6 +26,9 @@ const (
 	PipelineTitleContextVar     = "PIPELINE_TITLE"
 	PipelineUserContextVar      = "PIPELINE_USER"
 	PipelineStartTimeContextVar = "PIPELINE_START_TIME"
+	PipelineStateContextVar     = "PIPELINE_STATE"
+	PipelineMessageContextVar   = "PIPELINE_MESSAGE"
+	PipelineOffsetContextVar    = "PIPELINE_OFFSET"
 	UndefinedValue              = "UNDEFINED"
 )
 
@@ -93,12 +96,66 @@ func (p *PipelineEL) GetStartTime(args ...interface{}) (interface{}, error) {
 	return time.Now(), nil
 }
 
+func (p *PipelineEL) GetState(args ...interface{}) (interface{}, error) {
+	if len(args) != 0 {
+		return "", errors.New(
+			fmt.Sprintf("The function 'pipeline:state' requires 0 arguments but was passed %d", len(args)),
+		)
+	}
+
+	if p.Context != nil && p.Context.Value(PipelineElContextVar) != nil {
+		pipelineELContextValues := p.Context.Value(PipelineElContextVar).(map[string]interface{})
+		if state, ok := pipelineELContextValues[PipelineStateContextVar]; ok {
+			return state, nil
+		}
+	}
+
+	return UndefinedValue, nil
+}
+
+func (p *PipelineEL) GetMessage(args ...interface{}) (interface{}, error) {
+	if len(args) != 0 {
+		return "", errors.New(
+			fmt.Sprintf("The function 'pipeline:message' requires 0 arguments but was passed %d", len(args)),
+		)
+	}
+
+	if p.Context != nil && p.Context.Value(PipelineElContextVar) != nil {
+		pipelineELContextValues := p.Context.Value(PipelineElContextVar).(map[string]interface{})
+		if message, ok := pipelineELContextValues[PipelineMessageContextVar]; ok {
+			return message, nil
+		}
+	}
+
+	return UndefinedValue, nil
+}
+
+func (p *PipelineEL) GetOffset(args ...interface{}) (interface{}, error) {
+	if len(args) != 0 {
+		return "", errors.New(
+			fmt.Sprintf("The function 'pipeline:offset' requires 0 arguments but was passed %d", len(args)),
+		)
+	}
+
+	if p.Context != nil && p.Context.Value(PipelineElContextVar) != nil {
+		pipelineELContextValues := p.Context.Value(PipelineElContextVar).(map[string]interface{})
+		if offset, ok := pipelineELContextValues[PipelineOffsetContextVar]; ok {
+			return offset, nil
+		}
+	}
+
+	return UndefinedValue, nil
+}
+
 func (p *PipelineEL) GetELFunctionDefinitions() map[string]govaluate.ExpressionFunction {
 	functions := map[string]govaluate.ExpressionFunction{
 		"pipeline:id":        p.GetId,
 		"pipeline:title":     p.GetTitle,
 		"pipeline:user":      p.GetUser,
 		"pipeline:startTime": p.GetStartTime,
+		"pipeline:state":     p.GetState,
+		"pipeline:message":   p.GetMessage,
+		"pipeline:offset":    p.GetOffset,
 	}
 	return functions
 }
@@ -23,6 +23,9 @@ func TestPipelineEL(test *testing.T) {
 	pipelineTitle := "Sample Pipeline"
 	pipelineUser := "admin"
 	pipelineStartTime := time.Now()
+	pipelineState := "RUN_ERROR"
+	pipelineMessage := "Sample error"
+	pipelineOffset := "file1.log::1024"
 	evaluationTests := []EvaluationTest{
 		{
 			Name:       "Test pipeline:id()",
@@ -71,6 +74,22 @@ func TestPipelineEL(test *testing.T) {
 			Expected:   "The function 'pipeline:startTime' requires 0 arguments but was passed 1",
 			ErrorCase:  true,
 		},
+
+		{
+			Name:       "Test pipeline:state()",
+			Expression: "${pipeline:state()}",
+			Expected:   pipelineState,
+		},
+		{
+			Name:       "Test pipeline:message()",
+			Expression: "${pipeline:message()}",
+			Expected:   pipelineMessage,
+		},
+		{
+			Name:       "Test pipeline:offset()",
+			Expression: "${pipeline:offset()}",
+			Expected:   pipelineOffset,
+		},
 	}
 
 	pipelineELContextValues := map[string]interface{}{
@@ -78,6 +97,9 @@ func TestPipelineEL(test *testing.T) {
 		PipelineTitleContextVar:     pipelineTitle,
 		PipelineUserContextVar:      pipelineUser,
 		PipelineStartTimeContextVar: pipelineStartTime,
+		PipelineStateContextVar:     pipelineState,
+		PipelineMessageContextVar:   pipelineMessage,
+		PipelineOffsetContextVar:    pipelineOffset,
 	}
 	pipelineElContext := context.WithValue(context.Background(), PipelineElContextVar, pipelineELContextValues)
 
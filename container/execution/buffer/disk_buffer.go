@@ -0,0 +1,406 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/rcrowley/go-metrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/container/recordio"
+	"github.com/streamsets/datacollector-edge/container/recordio/sdcrecord"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DropOldest   = "DROP_OLDEST"
+	DropNewest   = "DROP_NEWEST"
+	StopPipeline = "STOP_PIPELINE"
+
+	SegmentFileSuffix    = ".sdc"
+	tmpSegmentFileSuffix = ".tmp"
+
+	BufferDepth          = ".storeAndForwardDepth"
+	BufferSize           = ".storeAndForwardSize"
+	BufferAge            = ".storeAndForwardAge"
+	BufferDroppedRecords = ".storeAndForwardDroppedRecords"
+)
+
+var ErrBufferFull = errors.New("store and forward buffer is full")
+
+type segment struct {
+	fileName    string
+	sequence    int64
+	createdTime time.Time
+	size        int64
+	recordCount int64
+}
+
+// DiskBuffer is a durable FIFO queue of record batches. Each batch is stored in its own segment file, named after
+// its sequence number, creation time and record count, so the queue survives restarts of the pipeline and of the
+// edge process.
+type DiskBuffer struct {
+	dir                   string
+	maxSize               int64
+	maxAge                time.Duration
+	overflowPolicy        string
+	segments              []*segment
+	nextSequence          int64
+	droppedRecordsCounter metrics.Counter
+	mutex                 sync.Mutex
+	forwardMutex          sync.Mutex
+
+	// read by the metric gauges without taking the lock
+	totalSize         int64
+	totalRecords      int64
+	oldestCreatedTime int64
+}
+
+// LockForwarding and UnlockForwarding serialize the writers forwarding the buffer, callers hold the lock across the
+// check for buffered batches, the write to the destination and the Enqueue or Remove that follows it, so that each
+// buffered batch is written once and in order. The buffer state has its own lock that is never held while writing
+// to the destination.
+func (b *DiskBuffer) LockForwarding() {
+	b.forwardMutex.Lock()
+}
+
+func (b *DiskBuffer) UnlockForwarding() {
+	b.forwardMutex.Unlock()
+}
+
+func (b *DiskBuffer) IsEmpty() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.expireSegments()
+	return len(b.segments) == 0
+}
+
+// Enqueue appends the given records as a new segment, applying the overflow policy if the buffer is full.
+func (b *DiskBuffer) Enqueue(stageContext api.StageContext, records []api.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	var segmentBuffer bytes.Buffer
+	recordWriter, err := (&sdcrecord.SDCRecordWriterFactoryImpl{}).CreateWriter(stageContext, &segmentBuffer)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err = recordWriter.WriteRecord(record); err != nil {
+			return err
+		}
+	}
+	if err = recordio.Flush(recordWriter); err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.expireSegments()
+
+	segmentSize := int64(segmentBuffer.Len())
+	if b.maxSize > 0 {
+		for b.totalSize+segmentSize > b.maxSize && len(b.segments) > 0 {
+			switch b.overflowPolicy {
+			case DropNewest:
+				log.WithField("dir", b.dir).Warnf("Store and forward buffer is full, dropping %d records", len(records))
+				b.droppedRecordsCounter.Inc(int64(len(records)))
+				return nil
+			case StopPipeline:
+				return ErrBufferFull
+			default:
+				log.WithField("dir", b.dir).Warn("Store and forward buffer is full, dropping oldest batch")
+				if err = b.removeOldest(true); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	newSegment := &segment{
+		sequence:    b.nextSequence,
+		createdTime: time.Now(),
+		size:        segmentSize,
+		recordCount: int64(len(records)),
+	}
+	newSegment.fileName = fmt.Sprintf(
+		"%020d-%d-%d%s",
+		newSegment.sequence,
+		util.ConvertTimeToLong(newSegment.createdTime),
+		newSegment.recordCount,
+		SegmentFileSuffix,
+	)
+
+	if err = b.writeSegmentFile(newSegment.fileName, segmentBuffer.Bytes()); err != nil {
+		return err
+	}
+
+	b.nextSequence++
+	b.segments = append(b.segments, newSegment)
+	b.updateTotals(newSegment.size, newSegment.recordCount)
+	return nil
+}
+
+// writeSegmentFile writes to a temporary file that is synced before it is renamed, so a crash never leaves a
+// partially written segment behind, and syncs the directory so the segment survives the crash.
+func (b *DiskBuffer) writeSegmentFile(fileName string, data []byte) error {
+	segmentPath := filepath.Join(b.dir, fileName)
+	tmpFile, err := os.OpenFile(segmentPath+tmpSegmentFileSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(segmentPath+tmpSegmentFileSuffix, segmentPath); err != nil {
+		return err
+	}
+	return util.SyncDir(b.dir)
+}
+
+// Peek returns the records of the oldest buffered batch without removing it.
+func (b *DiskBuffer) Peek(stageContext api.StageContext) ([]api.Record, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.expireSegments()
+	if len(b.segments) == 0 {
+		return nil, nil
+	}
+
+	segmentFile, err := os.Open(filepath.Join(b.dir, b.segments[0].fileName))
+	if err != nil {
+		return nil, err
+	}
+	defer segmentFile.Close()
+
+	recordReader, err := (&sdcrecord.SDCRecordReaderFactoryImpl{}).CreateReader(stageContext, segmentFile, "")
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]api.Record, 0, b.segments[0].recordCount)
+	for {
+		record, err := recordReader.ReadRecord()
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			break
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// Remove deletes the oldest buffered batch, after it was written to the destination.
+func (b *DiskBuffer) Remove() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.removeOldest(false)
+}
+
+func (b *DiskBuffer) GetDepth() int64 {
+	return atomic.LoadInt64(&b.totalRecords)
+}
+
+func (b *DiskBuffer) GetSize() int64 {
+	return atomic.LoadInt64(&b.totalSize)
+}
+
+// GetAge returns the age of the oldest buffered batch in milliseconds.
+func (b *DiskBuffer) GetAge() int64 {
+	oldestCreatedTime := atomic.LoadInt64(&b.oldestCreatedTime)
+	if oldestCreatedTime == 0 {
+		return 0
+	}
+	return int64(time.Since(time.Unix(0, oldestCreatedTime)) / time.Millisecond)
+}
+
+// updateTotals adds the given size and record count to the totals and updates the creation time of the oldest
+// batch, the caller holds the lock.
+func (b *DiskBuffer) updateTotals(size int64, recordCount int64) {
+	atomic.AddInt64(&b.totalSize, size)
+	atomic.AddInt64(&b.totalRecords, recordCount)
+	if len(b.segments) == 0 {
+		atomic.StoreInt64(&b.oldestCreatedTime, 0)
+	} else {
+		atomic.StoreInt64(&b.oldestCreatedTime, b.segments[0].createdTime.UnixNano())
+	}
+}
+
+func (b *DiskBuffer) removeOldest(dropped bool) error {
+	if len(b.segments) == 0 {
+		return nil
+	}
+	oldest := b.segments[0]
+	if err := os.Remove(filepath.Join(b.dir, oldest.fileName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	b.segments = b.segments[1:]
+	b.updateTotals(-oldest.size, -oldest.recordCount)
+	if dropped {
+		b.droppedRecordsCounter.Inc(oldest.recordCount)
+	}
+	return nil
+}
+
+func (b *DiskBuffer) expireSegments() {
+	if b.maxAge <= 0 {
+		return
+	}
+	for len(b.segments) > 0 && time.Since(b.segments[0].createdTime) > b.maxAge {
+		log.WithField("dir", b.dir).WithField("segment", b.segments[0].fileName).
+			Warn("Dropping expired store and forward batch")
+		if err := b.removeOldest(true); err != nil {
+			log.WithError(err).Error("Failed to remove expired store and forward batch")
+			return
+		}
+	}
+}
+
+func (b *DiskBuffer) loadSegments() error {
+	fileInfos, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, fileInfo := range fileInfos {
+		fileName := fileInfo.Name()
+		if strings.HasSuffix(fileName, tmpSegmentFileSuffix) {
+			_ = os.Remove(filepath.Join(b.dir, fileName))
+			continue
+		}
+		if fileInfo.IsDir() || !strings.HasSuffix(fileName, SegmentFileSuffix) {
+			continue
+		}
+
+		// segment file names are <sequence>-<created time in millis>-<record count>.sdc
+		nameParts := strings.Split(strings.TrimSuffix(fileName, SegmentFileSuffix), "-")
+		if len(nameParts) != 3 {
+			log.WithField("file", fileName).Warn("Ignoring unknown file in store and forward buffer")
+			continue
+		}
+		nameValues := make([]int64, len(nameParts))
+		for i, namePart := range nameParts {
+			if nameValues[i], err = strconv.ParseInt(namePart, 10, 64); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			log.WithField("file", fileName).Warn("Ignoring unknown file in store and forward buffer")
+			continue
+		}
+
+		b.segments = append(b.segments, &segment{
+			fileName:    fileName,
+			sequence:    nameValues[0],
+			createdTime: time.Unix(0, nameValues[1]*int64(time.Millisecond)),
+			recordCount: nameValues[2],
+			size:        fileInfo.Size(),
+		})
+	}
+
+	sort.Slice(b.segments, func(i, j int) bool {
+		return b.segments[i].sequence < b.segments[j].sequence
+	})
+
+	for _, loadedSegment := range b.segments {
+		b.updateTotals(loadedSegment.size, loadedSegment.recordCount)
+		b.nextSequence = loadedSegment.sequence + 1
+	}
+
+	// the maximum size may have been lowered since the batches were buffered, the newest batch is always kept like
+	// when enqueuing and batches are never dropped if the pipeline is to be stopped instead
+	for b.maxSize > 0 && b.totalSize > b.maxSize && len(b.segments) > 1 {
+		switch b.overflowPolicy {
+		case DropNewest:
+			log.WithField("dir", b.dir).Warn("Store and forward buffer exceeds its maximum size, dropping newest batch")
+			if err = b.removeNewest(); err != nil {
+				return err
+			}
+		case StopPipeline:
+			return nil
+		default:
+			log.WithField("dir", b.dir).Warn("Store and forward buffer exceeds its maximum size, dropping oldest batch")
+			if err = b.removeOldest(true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *DiskBuffer) removeNewest() error {
+	newest := b.segments[len(b.segments)-1]
+	if err := os.Remove(filepath.Join(b.dir, newest.fileName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	b.segments = b.segments[:len(b.segments)-1]
+	b.updateTotals(-newest.size, -newest.recordCount)
+	b.droppedRecordsCounter.Inc(newest.recordCount)
+	return nil
+}
+
+// NewDiskBuffer creates a buffer in the given directory, loading batches left over from a previous run.
+// maxSize (in bytes) and maxAge are not enforced when zero.
+func NewDiskBuffer(
+	dir string,
+	maxSize int64,
+	maxAge time.Duration,
+	overflowPolicy string,
+	metricRegistry metrics.Registry,
+	metricsKey string,
+) (*DiskBuffer, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	diskBuffer := &DiskBuffer{
+		dir:            dir,
+		maxSize:        maxSize,
+		maxAge:         maxAge,
+		overflowPolicy: overflowPolicy,
+		segments:       make([]*segment, 0),
+	}
+
+	diskBuffer.droppedRecordsCounter = util.CreateCounter(metricRegistry, metricsKey+BufferDroppedRecords)
+	if err := diskBuffer.loadSegments(); err != nil {
+		return nil, err
+	}
+
+	util.CreateFunctionalGauge(metricRegistry, metricsKey+BufferDepth, diskBuffer.GetDepth)
+	util.CreateFunctionalGauge(metricRegistry, metricsKey+BufferSize, diskBuffer.GetSize)
+	util.CreateFunctionalGauge(metricRegistry, metricsKey+BufferAge, diskBuffer.GetAge)
+
+	return diskBuffer, nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package buffer
+
+import (
+	"github.com/rcrowley/go-metrics"
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func createStageContext() api.StageContext {
+	return &common.StageContextImpl{
+		StageConfig: &common.StageConfiguration{InstanceName: "Dummy Stage"},
+		Parameters:  nil,
+	}
+}
+
+func createRecords(t *testing.T, stageContext api.StageContext, values ...string) []api.Record {
+	records := make([]api.Record, len(values))
+	for i, value := range values {
+		record, err := stageContext.CreateRecord("Sample Record Id", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records[i] = record
+	}
+	return records
+}
+
+func checkRecords(t *testing.T, records []api.Record, expectedValues ...string) {
+	if len(records) != len(expectedValues) {
+		t.Fatalf("Expected %d records, but got %d", len(expectedValues), len(records))
+	}
+	for i, record := range records {
+		field, err := record.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if field.Value != expectedValues[i] {
+			t.Errorf("Expected record value '%s', but got '%v'", expectedValues[i], field.Value)
+		}
+	}
+}
+
+func TestDiskBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestDiskBuffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stageContext := createStageContext()
+	diskBuffer, err := NewDiskBuffer(dir, 0, 0, DropOldest, metrics.NewRegistry(), "stage.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !diskBuffer.IsEmpty() {
+		t.Fatal("Expected empty buffer")
+	}
+
+	if err = diskBuffer.Enqueue(stageContext, createRecords(t, stageContext, "a", "b")); err != nil {
+		t.Fatal(err)
+	}
+	if err = diskBuffer.Enqueue(stageContext, createRecords(t, stageContext, "c")); err != nil {
+		t.Fatal(err)
+	}
+
+	if diskBuffer.GetDepth() != 3 {
+		t.Errorf("Expected buffer depth 3, but got %d", diskBuffer.GetDepth())
+	}
+
+	// a new buffer on the same directory picks up the batches left over by the previous one
+	diskBuffer, err = NewDiskBuffer(dir, 0, 0, DropOldest, metrics.NewRegistry(), "stage.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diskBuffer.GetDepth() != 3 {
+		t.Errorf("Expected buffer depth 3 after reload, but got %d", diskBuffer.GetDepth())
+	}
+
+	records, err := diskBuffer.Peek(stageContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, records, "a", "b")
+
+	if err = diskBuffer.Remove(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err = diskBuffer.Peek(stageContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, records, "c")
+
+	if err = diskBuffer.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if !diskBuffer.IsEmpty() {
+		t.Error("Expected empty buffer")
+	}
+}
+
+func TestDiskBufferOverflow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestDiskBufferOverflow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stageContext := createStageContext()
+	diskBuffer, err := NewDiskBuffer(dir, 1, 0, DropOldest, metrics.NewRegistry(), "stage.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = diskBuffer.Enqueue(stageContext, createRecords(t, stageContext, "a"))
+	_ = diskBuffer.Enqueue(stageContext, createRecords(t, stageContext, "b"))
+
+	records, err := diskBuffer.Peek(stageContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, records, "b")
+
+	diskBuffer.overflowPolicy = StopPipeline
+	if err = diskBuffer.Enqueue(stageContext, createRecords(t, stageContext, "c")); err != ErrBufferFull {
+		t.Errorf("Expected buffer full error, but got %v", err)
+	}
+}
+
+func TestDiskBufferMetricsWhileForwarding(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestDiskBufferMetricsWhileForwarding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stageContext := createStageContext()
+	metricRegistry := metrics.NewRegistry()
+	diskBuffer, err := NewDiskBuffer(dir, 0, 0, DropOldest, metricRegistry, "stage.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = diskBuffer.Enqueue(stageContext, createRecords(t, stageContext, "a", "b")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the gauges must not wait for a destination write in progress
+	diskBuffer.LockForwarding()
+	defer diskBuffer.UnlockForwarding()
+	depthGauge := metricRegistry.Get("stage.test" + BufferDepth + util.GAUGE_SUFFIX).(metrics.Gauge)
+	if depthGauge.Value() != 2 {
+		t.Errorf("Expected buffer depth gauge 2, but got %d", depthGauge.Value())
+	}
+	if diskBuffer.GetSize() <= 0 {
+		t.Errorf("Expected positive buffer size, but got %d", diskBuffer.GetSize())
+	}
+	if diskBuffer.GetAge() < 0 {
+		t.Errorf("Expected buffer age, but got %d", diskBuffer.GetAge())
+	}
+}
+
+func TestDiskBufferReloadWithLowerMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestDiskBufferReloadWithLowerMaxSize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stageContext := createStageContext()
+	diskBuffer, err := NewDiskBuffer(dir, 0, 0, DropOldest, metrics.NewRegistry(), "stage.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"a", "b", "c"} {
+		if err = diskBuffer.Enqueue(stageContext, createRecords(t, stageContext, value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diskBuffer, err = NewDiskBuffer(dir, 1, 0, StopPipeline, metrics.NewRegistry(), "stage.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diskBuffer.GetDepth() != 3 {
+		t.Errorf("Expected batches to be kept when the pipeline is to be stopped, but got %d", diskBuffer.GetDepth())
+	}
+
+	diskBuffer, err = NewDiskBuffer(dir, 1, 0, DropOldest, metrics.NewRegistry(), "stage.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diskBuffer.GetDepth() != 1 || diskBuffer.droppedRecordsCounter.Count() != 2 {
+		t.Errorf("Expected oldest batches to be dropped on reload, but got depth %d", diskBuffer.GetDepth())
+	}
+	records, err := diskBuffer.Peek(stageContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, records, "c")
+
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fileInfos) != 1 {
+		t.Errorf("Expected 1 segment file, but got %d", len(fileInfos))
+	}
+}
@@ -13,16 +13,46 @@
 package execution
 
 const (
-	DefaultMaxBatchSize = 1000
+	DefaultMaxBatchSize            = 1000
+	DefaultErrorStoreMaxFileSizeKB = 1024
+	DefaultErrorStoreMaxFiles      = 10
+	DefaultWebhookMaxRetries       = 3
+	DefaultWebhookRetryBaseDelay   = 1000
+	DefaultWebhookTimeout          = 10000
+	DefaultRuntimeStore            = "file"
+	DefaultStatsAggregatorInterval = 60000
+	DefaultStopDrainTimeout        = 30000
+	DefaultPreviewTtl              = 300000
+	DefaultMaxConcurrentPreviews   = 5
 )
 
 type Config struct {
-	MaxBatchSize int `toml:"max-batch-size"`
+	MaxBatchSize            int    `toml:"max-batch-size"`
+	ErrorStoreMaxFileSizeKB int    `toml:"error-store-max-file-size-kb"`
+	ErrorStoreMaxFiles      int    `toml:"error-store-max-files"`
+	WebhookMaxRetries       int    `toml:"webhook-max-retries"`
+	WebhookRetryBaseDelay   int    `toml:"webhook-retry-base-delay"`
+	WebhookTimeout          int    `toml:"webhook-timeout"`
+	RuntimeStore            string `toml:"runtime-store"`
+	StatsAggregatorInterval int    `toml:"stats-aggregator-interval"`
+	StopDrainTimeout        int    `toml:"stop-drain-timeout"`
+	PreviewTtl              int    `toml:"preview-ttl"`
+	MaxConcurrentPreviews   int    `toml:"max-concurrent-previews"`
 }
 
 // NewConfig returns a new Config with default settings.
 func NewConfig() Config {
 	return Config{
-		MaxBatchSize: DefaultMaxBatchSize,
+		MaxBatchSize:            DefaultMaxBatchSize,
+		ErrorStoreMaxFileSizeKB: DefaultErrorStoreMaxFileSizeKB,
+		ErrorStoreMaxFiles:      DefaultErrorStoreMaxFiles,
+		WebhookMaxRetries:       DefaultWebhookMaxRetries,
+		WebhookRetryBaseDelay:   DefaultWebhookRetryBaseDelay,
+		WebhookTimeout:          DefaultWebhookTimeout,
+		RuntimeStore:            DefaultRuntimeStore,
+		StatsAggregatorInterval: DefaultStatsAggregatorInterval,
+		StopDrainTimeout:        DefaultStopDrainTimeout,
+		PreviewTtl:              DefaultPreviewTtl,
+		MaxConcurrentPreviews:   DefaultMaxConcurrentPreviews,
 	}
 }
@@ -27,4 +27,6 @@ type Manager interface {
 	) (*common.PipelineState, error)
 	StopPipeline(pipelineId string) (*common.PipelineState, error)
 	ResetOffset(pipelineId string) error
+	// DeletePipeline deletes a pipeline that is not running together with its runtime data.
+	DeletePipeline(pipelineId string) error
 }
@@ -15,40 +15,101 @@ package manager
 import (
 	"errors"
 	"fmt"
+	log "github.com/sirupsen/logrus"
 	"github.com/streamsets/datacollector-edge/container/common"
 	"github.com/streamsets/datacollector-edge/container/execution"
 	"github.com/streamsets/datacollector-edge/container/execution/preview"
 	"github.com/streamsets/datacollector-edge/container/execution/runner"
 	"github.com/streamsets/datacollector-edge/container/store"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"sync"
+	"time"
 )
 
 type PipelineManager struct {
 	config            execution.Config
 	runnerMap         map[string]execution.Runner
-	previewerMap      map[string]execution.Previewer
+	runnerMutex       sync.Mutex
+	previewerMap      map[string]*previewerEntry
+	previewerMutex    sync.Mutex
 	runtimeInfo       *common.RuntimeInfo
 	pipelineStoreTask store.PipelineStoreTask
 }
 
+type previewerEntry struct {
+	previewer      execution.Previewer
+	lastAccessTime time.Time
+}
+
 func (p *PipelineManager) CreatePreviewer(pipelineId string) (execution.Previewer, error) {
+	p.previewerMutex.Lock()
+	defer p.previewerMutex.Unlock()
+	p.evictPreviewers()
+
+	if p.config.MaxConcurrentPreviews > 0 {
+		activePreviews := 0
+		for _, entry := range p.previewerMap {
+			if preview.IsActive(entry.previewer.GetStatus()) {
+				activePreviews++
+			}
+		}
+		if activePreviews >= p.config.MaxConcurrentPreviews {
+			return nil, errors.New(fmt.Sprintf(
+				"Maximum number of concurrent previews (%d) reached",
+				p.config.MaxConcurrentPreviews,
+			))
+		}
+	}
+
 	previewer, err := preview.NewAsyncPreviewer(pipelineId, p.config, p.pipelineStoreTask)
 	if err != nil {
 		return nil, err
 	}
-	p.previewerMap[previewer.GetId()] = previewer
+	p.previewerMap[previewer.GetId()] = &previewerEntry{previewer: previewer, lastAccessTime: time.Now()}
 	return previewer, nil
 }
 
 func (p *PipelineManager) GetPreviewer(previewerId string) (execution.Previewer, error) {
-	if p.previewerMap[previewerId] == nil {
+	p.previewerMutex.Lock()
+	defer p.previewerMutex.Unlock()
+	p.evictPreviewers()
+
+	entry := p.previewerMap[previewerId]
+	if entry == nil {
 		return nil, errors.New(fmt.Sprintf("Cannot find the previewer in cache for id: %s", previewerId))
 	}
-	return p.previewerMap[previewerId], nil
+	entry.lastAccessTime = time.Now()
+	return entry.previewer, nil
+}
+
+// evictPreviewers stops and removes the previewers that were not accessed within the preview TTL.
+func (p *PipelineManager) evictPreviewers() {
+	if p.config.PreviewTtl <= 0 {
+		return
+	}
+	expiryTime := time.Now().Add(-time.Duration(p.config.PreviewTtl) * time.Millisecond)
+	for previewerId, entry := range p.previewerMap {
+		if entry.lastAccessTime.Before(expiryTime) {
+			log.WithField("id", previewerId).Debug("Evicting expired previewer")
+			if err := entry.previewer.Stop(); err != nil {
+				log.WithError(err).WithField("id", previewerId).Error("Failed to stop expired previewer")
+			}
+			delete(p.previewerMap, previewerId)
+		}
+	}
 }
 
 func (p *PipelineManager) GetRunner(pipelineId string) execution.Runner {
+	p.runnerMutex.Lock()
+	defer p.runnerMutex.Unlock()
 	if p.runnerMap[pipelineId] == nil {
-		pRunner, err := runner.NewEdgeRunner(pipelineId, p.config, p.runtimeInfo, p.pipelineStoreTask)
+		pRunner, err := runner.NewEdgeRunner(
+			pipelineId,
+			p.config,
+			p.runtimeInfo,
+			p.pipelineStoreTask,
+			p.startTriggeredPipeline,
+		)
 		if err != nil {
 			panic(err)
 		}
@@ -68,10 +129,47 @@ func (p *PipelineManager) StopPipeline(pipelineId string) (*common.PipelineState
 	return p.GetRunner(pipelineId).StopPipeline()
 }
 
+// startTriggeredPipeline starts a downstream pipeline for a pipeline trigger. Unlike requests, triggers are
+// configured in the pipelines, so a downstream pipeline that doesn't exist is reported as an error.
+func (p *PipelineManager) startTriggeredPipeline(
+	pipelineId string,
+	runtimeParameters map[string]interface{},
+	triggerChain []string,
+) (pipelineState *common.PipelineState, err error) {
+	// GetRunner panics if the runner can't be created
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return p.GetRunner(pipelineId).StartTriggeredPipeline(runtimeParameters, triggerChain)
+}
+
 func (p *PipelineManager) ResetOffset(pipelineId string) error {
 	return p.GetRunner(pipelineId).ResetOffset()
 }
 
+// DeletePipeline deletes a pipeline that isn't running. The runner lock is held from the status check until the
+// runner is removed, so that no runner is created for the pipeline while it is deleted.
+func (p *PipelineManager) DeletePipeline(pipelineId string) error {
+	p.runnerMutex.Lock()
+	defer p.runnerMutex.Unlock()
+	if pipelineRunner := p.runnerMap[pipelineId]; pipelineRunner != nil {
+		pipelineState, err := pipelineRunner.GetStatus()
+		if err != nil {
+			return err
+		}
+		if util.Contains(runner.RestOffsetDisallowedStatuses, pipelineState.Status) {
+			return errors.New("cannot delete the pipeline when the pipeline is running")
+		}
+	}
+	if err := p.pipelineStoreTask.Delete(pipelineId); err != nil {
+		return err
+	}
+	delete(p.runnerMap, pipelineId)
+	return nil
+}
+
 func NewManager(
 	config execution.Config,
 	runtimeInfo *common.RuntimeInfo,
@@ -80,7 +178,7 @@ func NewManager(
 	pipelineManager := PipelineManager{
 		config:            config,
 		runnerMap:         make(map[string]execution.Runner),
-		previewerMap:      make(map[string]execution.Previewer),
+		previewerMap:      make(map[string]*previewerEntry),
 		runtimeInfo:       runtimeInfo,
 		pipelineStoreTask: pipelineStoreTask,
 	}
@@ -0,0 +1,118 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package manager
+
+import (
+	"fmt"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/execution"
+	"github.com/streamsets/datacollector-edge/container/execution/preview"
+	"github.com/streamsets/datacollector-edge/container/store"
+	"sync"
+	"testing"
+	"time"
+)
+
+type finishedPreviewer struct {
+	execution.Previewer
+	stopped bool
+}
+
+func (f *finishedPreviewer) GetStatus() string {
+	return preview.Finished
+}
+
+func (f *finishedPreviewer) Stop() error {
+	f.stopped = true
+	return nil
+}
+
+func TestPreviewerLimitAndEviction(t *testing.T) {
+	config := execution.NewConfig()
+	config.MaxConcurrentPreviews = 2
+	config.PreviewTtl = 60000
+	pipelineManager, _ := NewManager(config, nil, nil)
+	p := pipelineManager.(*PipelineManager)
+
+	finished := &finishedPreviewer{}
+	p.previewerMap["finished"] = &previewerEntry{previewer: finished, lastAccessTime: time.Now()}
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.CreatePreviewer("testPipeline"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := p.CreatePreviewer("testPipeline"); err == nil {
+		t.Fatal("Expected error when exceeding the maximum number of concurrent previews")
+	}
+
+	if _, err := p.GetPreviewer("finished"); err != nil {
+		t.Fatal(err)
+	}
+	p.previewerMap["finished"].lastAccessTime = time.Now().Add(-2 * time.Minute)
+	if _, err := p.GetPreviewer("finished"); err == nil {
+		t.Error("Expected expired previewer to be evicted")
+	}
+	if !finished.stopped {
+		t.Error("Expected expired previewer to be stopped")
+	}
+}
+
+type statusRunner struct {
+	execution.Runner
+	status string
+}
+
+func (r *statusRunner) GetStatus() (*common.PipelineState, error) {
+	return &common.PipelineState{Status: r.status}, nil
+}
+
+type deletingPipelineStore struct {
+	store.PipelineStoreTask
+}
+
+func (s *deletingPipelineStore) Delete(pipelineId string) error {
+	return nil
+}
+
+func TestDeletePipeline(t *testing.T) {
+	pipelineManager, _ := NewManager(execution.NewConfig(), nil, &deletingPipelineStore{})
+	p := pipelineManager.(*PipelineManager)
+
+	p.runnerMap["running"] = &statusRunner{status: common.RUNNING}
+	if err := p.DeletePipeline("running"); err == nil {
+		t.Error("Expected error when deleting a running pipeline")
+	}
+	if p.runnerMap["running"] == nil {
+		t.Error("Expected runner of running pipeline to be kept")
+	}
+
+	for i := 0; i < 10; i++ {
+		p.runnerMap[fmt.Sprintf("stopped%d", i)] = &statusRunner{status: common.STOPPED}
+	}
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		pipelineId := fmt.Sprintf("stopped%d", i)
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if err := p.DeletePipeline(pipelineId); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	waitGroup.Wait()
+	if len(p.runnerMap) != 1 {
+		t.Errorf("Expected runners of deleted pipelines to be removed, but got %d runners", len(p.runnerMap))
+	}
+}
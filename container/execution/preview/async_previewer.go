@@ -18,16 +18,18 @@ import (
 	pipelineStore "github.com/streamsets/datacollector-edge/container/store"
 )
 
+// AsyncPreviewer runs the preview in the background, the status and output are polled while it runs.
 type AsyncPreviewer struct {
-	syncPreviewer SyncPreviewer
+	syncPreviewer *SyncPreviewer
 }
 
 func (p *AsyncPreviewer) GetId() string {
 	return p.syncPreviewer.GetId()
 }
 
+// ValidateConfigs validates the pipeline configuration synchronously, it only initializes the stages.
 func (p *AsyncPreviewer) ValidateConfigs(timeoutMillis int64) error {
-	return nil
+	return p.syncPreviewer.ValidateConfigs(timeoutMillis)
 }
 
 func (p *AsyncPreviewer) Start(
@@ -65,7 +67,8 @@ func NewAsyncPreviewer(
 		previewerId:       uuid.NewV4().String(),
 		config:            config,
 		pipelineStoreTask: pipelineStoreTask,
-		previewOutput:     execution.PreviewOutput{},
+		previewOutput:     execution.PreviewOutput{PreviewStatus: CREATED},
+		cancel:            make(chan struct{}),
 	}
-	return syncPreviewer, nil
+	return &AsyncPreviewer{syncPreviewer: syncPreviewer}, nil
 }
@@ -21,6 +21,7 @@ import (
 	"github.com/streamsets/datacollector-edge/container/creation"
 	"github.com/streamsets/datacollector-edge/container/execution"
 	"github.com/streamsets/datacollector-edge/container/execution/runner"
+	"sync"
 )
 
 type Pipeline struct {
@@ -36,6 +37,7 @@ type Pipeline struct {
 	eventSink         *common.EventSink
 	BatchesOutput     [][]execution.StageOutput
 	stagesToSkip      map[string]execution.StageOutputJson
+	stopOnce          sync.Once
 }
 
 func (p *Pipeline) ValidateConfigs() []validation.Issue {
@@ -123,13 +125,20 @@ func (p *Pipeline) runBatch(batchCount int, batchSize int, skipTargets bool) err
 	return nil
 }
 
+// Stop stops and destroys the stages once, it is also called when the preview times out or is cancelled while a
+// batch is running.
 func (p *Pipeline) Stop() {
 	log.Debug("Preview Pipeline Stop()")
-	for _, stagePipe := range p.pipes {
-		stagePipe.Destroy()
-	}
-	p.errorStageRuntime.Destroy()
-	p.stop = true
+	p.stopOnce.Do(func() {
+		p.stop = true
+		for _, stagePipe := range p.pipes {
+			stagePipe.GetStageContext().SetStop()
+		}
+		for _, stagePipe := range p.pipes {
+			stagePipe.Destroy()
+		}
+		p.errorStageRuntime.Destroy()
+	})
 }
 
 func NewPreviewPipeline(
@@ -137,6 +146,10 @@ func NewPreviewPipeline(
 	pipelineConfig common.PipelineConfiguration,
 ) (*Pipeline, []validation.Issue) {
 	issues := make([]validation.Issue, 0)
+	if pipelineConfig.Stages, issues = common.SortStages(pipelineConfig.Stages); len(issues) > 0 {
+		return nil, issues
+	}
+
 	metricRegistry := metrics.NewRegistry()
 	sourceOffsetTracker := NewPreviewSourceOffsetTracker(pipelineConfig.PipelineId)
 	pipelineConfigForParam := creation.NewPipelineConfigBean(pipelineConfig)
@@ -13,11 +13,16 @@
 package preview
 
 import (
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
 	"github.com/streamsets/datacollector-edge/api/validation"
 	"github.com/streamsets/datacollector-edge/container/common"
 	"github.com/streamsets/datacollector-edge/container/execution"
 	"github.com/streamsets/datacollector-edge/container/execution/runner"
 	pipelineStore "github.com/streamsets/datacollector-edge/container/store"
+	"sync"
+	"time"
 )
 
 const (
@@ -48,6 +53,9 @@ type SyncPreviewer struct {
 	previewPipeline      *Pipeline
 	metricsEventRunnable *runner.MetricsEventRunnable
 	pipelineStoreTask    pipelineStore.PipelineStoreTask
+	mutex                sync.Mutex
+	cancel               chan struct{}
+	cancelOnce           sync.Once
 }
 
 func (p *SyncPreviewer) GetId() string {
@@ -55,31 +63,29 @@ func (p *SyncPreviewer) GetId() string {
 }
 
 func (p *SyncPreviewer) ValidateConfigs(timeoutMillis int64) error {
-	p.previewOutput.PreviewStatus = Validating
-	var err error
-	p.pipelineConfig, err = p.pipelineStoreTask.LoadPipelineConfig(p.pipelineId)
+	p.setStatus(Validating)
+	pipelineConfig, err := p.pipelineStoreTask.LoadPipelineConfig(p.pipelineId)
 	if err != nil {
-		p.previewOutput.PreviewStatus = ValidationError
-		p.previewOutput.Message = err.Error()
+		p.setStatusAndMessage(ValidationError, err.Error())
 		return err
 	}
+	p.pipelineConfig = pipelineConfig
 
 	previewPipeline, issues := NewPreviewPipeline(p.config, p.pipelineConfig)
 	if len(issues) > 0 {
-		p.previewOutput.PreviewStatus = ValidationError
-		p.previewOutput.Issues = validation.NewIssues(issues)
+		p.setStatusAndIssues(ValidationError, issues)
 		return err
 	}
 
-	issues = previewPipeline.ValidateConfigs()
-	p.previewOutput.Issues = validation.NewIssues(issues)
-	if len(issues) > 0 {
-		p.previewOutput.PreviewStatus = InValid
-	} else {
-		p.previewOutput.PreviewStatus = Valid
-	}
-
-	return nil
+	return p.runWithTimeout(previewPipeline, timeoutMillis, func() error {
+		issues = previewPipeline.ValidateConfigs()
+		if len(issues) > 0 {
+			p.setStatusAndIssues(InValid, issues)
+		} else {
+			p.setStatusAndIssues(Valid, issues)
+		}
+		return nil
+	})
 }
 
 func (p *SyncPreviewer) Start(
@@ -91,13 +97,13 @@ func (p *SyncPreviewer) Start(
 	timeoutMillis int64,
 	testOrigin bool,
 ) error {
-	p.previewOutput.PreviewStatus = Starting
-	var err error
-	p.pipelineConfig, err = p.pipelineStoreTask.LoadPipelineConfig(p.pipelineId)
+	p.setStatus(Starting)
+	pipelineConfig, err := p.pipelineStoreTask.LoadPipelineConfig(p.pipelineId)
 	if err != nil {
-		p.previewOutput.Message = err.Error()
+		p.setStatusAndMessage(StartError, err.Error())
 		return err
 	}
+	p.pipelineConfig = pipelineConfig
 
 	if testOrigin && p.pipelineConfig.TestOriginStage != nil {
 		p.pipelineConfig.Stages[0] = p.pipelineConfig.TestOriginStage
@@ -105,46 +111,142 @@ func (p *SyncPreviewer) Start(
 
 	previewPipeline, issues := NewPreviewPipeline(p.config, p.pipelineConfig)
 	if len(issues) > 0 {
-		p.previewOutput.PreviewStatus = StartError
-		p.previewOutput.Issues = validation.NewIssues(issues)
+		p.setStatusAndIssues(StartError, issues)
 		return err
 	}
 
-	issues = previewPipeline.Init()
-	if len(issues) > 0 {
-		p.previewOutput.PreviewStatus = InValid
-		p.previewOutput.Issues = validation.NewIssues(issues)
+	return p.runWithTimeout(previewPipeline, timeoutMillis, func() error {
+		issues = previewPipeline.Init()
+		if len(issues) > 0 {
+			p.setStatusAndIssues(InValid, issues)
+			previewPipeline.Stop()
+			return nil
+		}
+		p.setStatus(Running)
+
+		previewPipeline.Run(batches, batchSize, skipTargets, stopStage, stagesOverride)
+
+		previewOutput, err := execution.NewPreviewOutput(previewPipeline.BatchesOutput)
+		if err != nil {
+			p.setStatusAndMessage(RunError, err.Error())
+			previewPipeline.Stop()
+			return err
+		}
+
+		p.mutex.Lock()
+		if !p.isAborted() {
+			p.previewOutput.Output = previewOutput
+		}
+		p.mutex.Unlock()
+
+		p.setStatus(Finishing)
+		previewPipeline.Stop()
+		p.setStatus(Finished)
 		return nil
-	} else {
-		p.previewOutput.PreviewStatus = Running
-	}
-
-	previewPipeline.Run(batches, batchSize, skipTargets, stopStage, stagesOverride)
+	})
+}
 
-	previewOutput, err := execution.NewPreviewOutput(previewPipeline.BatchesOutput)
-	if err != nil {
-		p.previewOutput.PreviewStatus = RunError
-		p.previewOutput.Message = err.Error()
+// runWithTimeout runs the preview and waits until it is done, times out or is cancelled. On time out or
+// cancellation the stages of the preview pipeline are stopped and destroyed to release their resources.
+func (p *SyncPreviewer) runWithTimeout(previewPipeline *Pipeline, timeoutMillis int64, run func() error) error {
+	p.mutex.Lock()
+	p.previewPipeline = previewPipeline
+	p.mutex.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				p.setStatusAndMessage(RunError, fmt.Sprintf("%v", r))
+				previewPipeline.Stop()
+				done <- errors.New(fmt.Sprintf("preview failed: %v", r))
+			}
+		}()
+		done <- run()
+	}()
+
+	timeout := time.NewTimer(time.Duration(timeoutMillis) * time.Millisecond)
+	defer timeout.Stop()
+
+	select {
+	case err := <-done:
 		return err
+	case <-timeout.C:
+		log.WithField("id", p.previewerId).WithField("timeout", timeoutMillis).Warn("Preview timed out")
+		p.setStatus(TimingOut)
+		previewPipeline.Stop()
+		message := fmt.Sprintf("Preview timed out after %d milliseconds", timeoutMillis)
+		p.setStatusAndMessage(TimedOut, message)
+		return errors.New(message)
+	case <-p.cancel:
+		log.WithField("id", p.previewerId).Info("Preview cancelled")
+		p.setStatus(Cancelling)
+		previewPipeline.Stop()
+		p.setStatus(Cancelled)
+		return nil
 	}
-
-	p.previewOutput.Output = previewOutput
-
-	p.previewOutput.PreviewStatus = Finishing
-
-	previewPipeline.Stop()
-	p.previewOutput.PreviewStatus = Finished
-	return nil
 }
 
+// Stop cancels the preview, a preview that is done is not affected.
 func (p *SyncPreviewer) Stop() error {
+	p.cancelOnce.Do(func() {
+		close(p.cancel)
+	})
 	return nil
 }
 
 func (p *SyncPreviewer) GetStatus() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
 	return p.previewOutput.PreviewStatus
 }
 
 func (p *SyncPreviewer) GetOutput() execution.PreviewOutput {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
 	return p.previewOutput
 }
+
+func (p *SyncPreviewer) setStatus(status string) {
+	p.setStatusAndMessage(status, "")
+}
+
+func (p *SyncPreviewer) setStatusAndMessage(status string, message string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.isAborted() && status != TimedOut && status != Cancelled {
+		// the preview run goes on in the background after a time out or cancellation
+		return
+	}
+	p.previewOutput.PreviewStatus = status
+	if message != "" {
+		p.previewOutput.Message = message
+	}
+}
+
+func (p *SyncPreviewer) setStatusAndIssues(status string, issues []validation.Issue) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.isAborted() {
+		return
+	}
+	p.previewOutput.PreviewStatus = status
+	p.previewOutput.Issues = validation.NewIssues(issues)
+}
+
+func (p *SyncPreviewer) isAborted() bool {
+	switch p.previewOutput.PreviewStatus {
+	case TimingOut, TimedOut, Cancelling, Cancelled:
+		return true
+	}
+	return false
+}
+
+// IsActive returns whether a preview with the given status is still running or about to run.
+func IsActive(status string) bool {
+	switch status {
+	case CREATED, Validating, Starting, Running, Finishing, TimingOut, Cancelling:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package preview
+
+import (
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	"github.com/streamsets/datacollector-edge/container/execution"
+	"github.com/streamsets/datacollector-edge/container/execution/runner"
+	"testing"
+	"time"
+)
+
+func newTestPreviewer() (*SyncPreviewer, *Pipeline) {
+	previewer := &SyncPreviewer{
+		previewerId:   "testPreviewer",
+		previewOutput: execution.PreviewOutput{PreviewStatus: CREATED},
+		cancel:        make(chan struct{}),
+	}
+	previewPipeline := &Pipeline{
+		errorStageRuntime: runner.NewStageRuntime(
+			creation.PipelineBean{},
+			creation.StageBean{Stage: &common.BaseStage{}},
+			nil,
+		),
+	}
+	return previewer, previewPipeline
+}
+
+func TestPreviewTimeout(t *testing.T) {
+	previewer, previewPipeline := newTestPreviewer()
+	release := make(chan struct{})
+	finished := make(chan struct{})
+
+	err := previewer.runWithTimeout(previewPipeline, 10, func() error {
+		previewer.setStatus(Running)
+		<-release
+		previewer.setStatus(Finished)
+		close(finished)
+		return nil
+	})
+	if err == nil {
+		t.Fatal("Expected preview to time out")
+	}
+	if previewer.GetStatus() != TimedOut {
+		t.Errorf("Expected status %s, but got %s", TimedOut, previewer.GetStatus())
+	}
+	if !previewPipeline.stop {
+		t.Error("Expected preview pipeline to be stopped")
+	}
+
+	// the preview finishing late doesn't change the status
+	close(release)
+	<-finished
+	if previewer.GetStatus() != TimedOut {
+		t.Errorf("Expected status %s, but got %s", TimedOut, previewer.GetStatus())
+	}
+}
+
+func TestPreviewCancel(t *testing.T) {
+	previewer, previewPipeline := newTestPreviewer()
+	release := make(chan struct{})
+	defer close(release)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		previewer.Stop()
+	}()
+
+	err := previewer.runWithTimeout(previewPipeline, 10000, func() error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previewer.GetStatus() != Cancelled {
+		t.Errorf("Expected status %s, but got %s", Cancelled, previewer.GetStatus())
+	}
+	if !previewPipeline.stop {
+		t.Error("Expected preview pipeline to be stopped")
+	}
+}
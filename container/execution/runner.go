@@ -14,8 +14,9 @@ package execution
 
 import (
 	"github.com/rcrowley/go-metrics"
-	"github.com/streamsets/datacollector-edge/api"
 	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"github.com/streamsets/datacollector-edge/container/recordio/sdcrecord"
 )
 
 type Runner interface {
@@ -24,11 +25,25 @@ type Runner interface {
 	GetHistory() ([]*common.PipelineState, error)
 	GetMetrics() (metrics.Registry, error)
 	StartPipeline(runtimeParameters map[string]interface{}) (*common.PipelineState, error)
+	StartTriggeredPipeline(
+		runtimeParameters map[string]interface{},
+		triggerChain []string,
+	) (*common.PipelineState, error)
 	StopPipeline() (*common.PipelineState, error)
 	ResetOffset() error
 	CommitOffset(sourceOffset common.SourceOffset) error
 	GetOffset() (common.SourceOffset, error)
 	IsRemotePipeline() bool
-	GetErrorRecords(stageInstanceName string, size int) ([]api.Record, error)
-	GetErrorMessages(stageInstanceName string, size int) ([]api.ErrorMessage, error)
+	GetErrorRecords(query store.ErrorQuery) ([]sdcrecord.SDCRecord, error)
+	GetErrorMessages(query store.ErrorQuery) ([]store.StageErrorMessage, error)
+	ReplayErrorRecords(query store.ErrorQuery) (int, error)
+	GetAlerts() ([]*common.AlertInfo, error)
+	DeleteAlert(ruleId string) error
+	ReloadRules() error
+	GetWebhookDeliveries() ([]store.WebhookDelivery, error)
+	CaptureSnapshot(snapshotName string, snapshotLabel string, batches int) (*store.SnapshotInfo, error)
+	GetSnapshotsInfo() ([]*store.SnapshotInfo, error)
+	GetSnapshotInfo(snapshotName string) (*store.SnapshotInfo, error)
+	GetSnapshot(snapshotName string) (*Snapshot, error)
+	DeleteSnapshot(snapshotName string) error
 }
@@ -0,0 +1,109 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"sync"
+	"time"
+)
+
+// AlertManager keeps the alerts raised by the rules of a pipeline, one per rule, and saves them with the
+// pipeline run info.
+type AlertManager struct {
+	pipelineId string
+	alerts     []*common.AlertInfo
+	mutex      sync.Mutex
+}
+
+// Alert raises the alert of the given rule, or updates its current value if it was already raised.
+func (a *AlertManager) Alert(ruleId string, ruleDefinition interface{}, alertText string, currentValue interface{}) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := util.ConvertTimeToLong(time.Now())
+	alertInfo := a.getAlert(ruleId)
+	if alertInfo == nil {
+		log.WithField("id", a.pipelineId).WithField("rule", ruleId).Warnf("Alert: %s", alertText)
+		alertInfo = &common.AlertInfo{
+			PipelineName: a.pipelineId,
+			RuleId:       ruleId,
+			Timestamp:    now,
+		}
+		a.alerts = append(a.alerts, alertInfo)
+	}
+	alertInfo.RuleDefinition = ruleDefinition
+	alertInfo.AlertText = alertText
+	alertInfo.CurrentValue = currentValue
+	alertInfo.LastFiredTimestamp = now
+
+	if err := store.SaveAlerts(a.pipelineId, a.alerts); err != nil {
+		log.WithError(err).Error("Failed to save alerts")
+	}
+	alertInfoCopy := *alertInfo
+	store.PublishEvent(store.EVENT_ALERT, a.pipelineId, &alertInfoCopy)
+}
+
+func (a *AlertManager) GetAlerts() []*common.AlertInfo {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	alerts := make([]*common.AlertInfo, len(a.alerts))
+	for i, alertInfo := range a.alerts {
+		alertCopy := *alertInfo
+		alerts[i] = &alertCopy
+	}
+	return alerts
+}
+
+func (a *AlertManager) DeleteAlert(ruleId string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for i, alertInfo := range a.alerts {
+		if alertInfo.RuleId == ruleId {
+			a.alerts = append(a.alerts[:i], a.alerts[i+1:]...)
+			return store.SaveAlerts(a.pipelineId, a.alerts)
+		}
+	}
+	return errors.New("Alert for rule '" + ruleId + "' does not exist")
+}
+
+func (a *AlertManager) Clear() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.alerts = make([]*common.AlertInfo, 0)
+	return store.SaveAlerts(a.pipelineId, a.alerts)
+}
+
+func (a *AlertManager) getAlert(ruleId string) *common.AlertInfo {
+	for _, alertInfo := range a.alerts {
+		if alertInfo.RuleId == ruleId {
+			return alertInfo
+		}
+	}
+	return nil
+}
+
+func NewAlertManager(pipelineId string) (*AlertManager, error) {
+	alerts, err := store.GetAlerts(pipelineId)
+	if err != nil {
+		return nil, err
+	}
+	return &AlertManager{
+		pipelineId: pipelineId,
+		alerts:     alerts,
+	}, nil
+}
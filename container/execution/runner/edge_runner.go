@@ -14,18 +14,26 @@ package runner
 
 import (
 	"errors"
+	"fmt"
 	"github.com/rcrowley/go-metrics"
 	log "github.com/sirupsen/logrus"
-	"github.com/streamsets/datacollector-edge/api"
+	"github.com/spf13/cast"
 	"github.com/streamsets/datacollector-edge/api/validation"
 	"github.com/streamsets/datacollector-edge/container/common"
 	"github.com/streamsets/datacollector-edge/container/execution"
 	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"github.com/streamsets/datacollector-edge/container/recordio/sdcrecord"
 	pipelineStore "github.com/streamsets/datacollector-edge/container/store"
 	"github.com/streamsets/datacollector-edge/container/util"
+	"sync"
 	"time"
 )
 
+const (
+	RetryBaseDelay = 15 * time.Second
+	RetryMaxDelay  = 5 * time.Minute
+)
+
 var (
 	RestOffsetDisallowedStatuses = []string{
 		common.FINISHING,
@@ -51,6 +59,26 @@ type EdgeRunner struct {
 	prodPipeline         *ProductionPipeline
 	metricsEventRunnable *MetricsEventRunnable
 	pipelineStoreTask    pipelineStore.PipelineStoreTask
+	runtimeParameters    map[string]interface{}
+	retryStopChan        chan struct{}
+	errorStore           *store.ErrorStore
+	alertManager         *AlertManager
+	ruleDefinitions      common.RuleDefinitions
+	webhookNotifier      *WebhookNotifier
+	pipelineTrigger      *PipelineTrigger
+
+	// stateMutex guards the pipeline state, the production pipeline and the retry of the pipeline, which are changed
+	// by requests and by the goroutine running the pipeline
+	stateMutex         sync.Mutex
+	stateNotifications []stateNotification
+}
+
+// stateNotification is a state change to notify the webhooks and pipeline triggers of once the state lock is
+// released, so that slow webhooks and the pipelines started by triggers never wait for the lock of this runner.
+type stateNotification struct {
+	pipelineConfig    common.PipelineConfiguration
+	runtimeParameters map[string]interface{}
+	pipelineState     common.PipelineState
 }
 
 func (edgeRunner *EdgeRunner) init() error {
@@ -69,15 +97,35 @@ func (edgeRunner *EdgeRunner) init() error {
 
 	var err error
 	edgeRunner.pipelineState, err = store.GetState(edgeRunner.pipelineId)
+	if err != nil {
+		return err
+	}
+
+	edgeRunner.errorStore, err = store.NewErrorStore(
+		edgeRunner.pipelineId,
+		int64(edgeRunner.config.ErrorStoreMaxFileSizeKB)*1024,
+		edgeRunner.config.ErrorStoreMaxFiles,
+	)
+	if err != nil {
+		return err
+	}
+
+	edgeRunner.webhookNotifier = NewWebhookNotifier(edgeRunner.pipelineId, edgeRunner.config)
+
+	edgeRunner.alertManager, err = NewAlertManager(edgeRunner.pipelineId)
 	return err
 }
 
+// GetPipelineConfig is called while the production pipeline is created, with the state lock held.
 func (edgeRunner *EdgeRunner) GetPipelineConfig() common.PipelineConfiguration {
 	return edgeRunner.pipelineConfig
 }
 
+// GetStatus returns a copy of the pipeline state, the runner keeps changing its state.
 func (edgeRunner *EdgeRunner) GetStatus() (*common.PipelineState, error) {
-	return edgeRunner.pipelineState, nil
+	edgeRunner.stateMutex.Lock()
+	defer edgeRunner.stateMutex.Unlock()
+	return common.CopyPipelineState(edgeRunner.pipelineState), nil
 }
 
 func (edgeRunner *EdgeRunner) GetHistory() ([]*common.PipelineState, error) {
@@ -85,6 +133,8 @@ func (edgeRunner *EdgeRunner) GetHistory() ([]*common.PipelineState, error) {
 }
 
 func (edgeRunner *EdgeRunner) GetMetrics() (metrics.Registry, error) {
+	edgeRunner.stateMutex.Lock()
+	defer edgeRunner.stateMutex.Unlock()
 	if edgeRunner.prodPipeline != nil {
 		return edgeRunner.prodPipeline.MetricRegistry, nil
 	}
@@ -93,8 +143,27 @@ func (edgeRunner *EdgeRunner) GetMetrics() (metrics.Registry, error) {
 
 func (edgeRunner *EdgeRunner) StartPipeline(
 	runtimeParameters map[string]interface{},
+) (*common.PipelineState, error) {
+	return edgeRunner.startPipeline(runtimeParameters, nil)
+}
+
+// StartTriggeredPipeline starts the pipeline on behalf of the pipelines in the trigger chain, which is kept in the
+// pipeline state so that the downstream pipelines of this pipeline can detect cycles.
+func (edgeRunner *EdgeRunner) StartTriggeredPipeline(
+	runtimeParameters map[string]interface{},
+	triggerChain []string,
+) (*common.PipelineState, error) {
+	return edgeRunner.startPipeline(runtimeParameters, triggerChain)
+}
+
+func (edgeRunner *EdgeRunner) startPipeline(
+	runtimeParameters map[string]interface{},
+	triggerChain []string,
 ) (*common.PipelineState, error) {
 	log.WithField("id", edgeRunner.pipelineId).Info("Starting pipeline")
+	edgeRunner.stateMutex.Lock()
+	defer edgeRunner.unlockAndNotify()
+
 	var err error
 	err = edgeRunner.checkState(common.STARTING)
 	if err != nil {
@@ -108,16 +177,44 @@ func (edgeRunner *EdgeRunner) StartPipeline(
 		return nil, err
 	}
 
+	edgeRunner.ruleDefinitions, err = edgeRunner.pipelineStoreTask.RetrieveRules(edgeRunner.pipelineId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = edgeRunner.alertManager.Clear(); err != nil {
+		return nil, err
+	}
+
+	edgeRunner.runtimeParameters = runtimeParameters
+	if edgeRunner.pipelineState.Attributes == nil {
+		edgeRunner.pipelineState.Attributes = make(map[string]interface{})
+	}
+	delete(edgeRunner.pipelineState.Attributes, store.RETRY_ATTEMPT)
+	delete(edgeRunner.pipelineState.Attributes, store.NEXT_RETRY_TIMESTAMP)
+	if len(triggerChain) > 0 {
+		edgeRunner.pipelineState.Attributes[store.TRIGGER_CHAIN] = triggerChain
+	} else {
+		delete(edgeRunner.pipelineState.Attributes, store.TRIGGER_CHAIN)
+	}
+
+	pipelineState, err := edgeRunner.startProductionPipeline()
+	return common.CopyPipelineState(pipelineState), err
+}
+
+// startProductionPipeline is called with the state lock held.
+func (edgeRunner *EdgeRunner) startProductionPipeline() (*common.PipelineState, error) {
 	var issues []validation.Issue
-	if edgeRunner.prodPipeline, issues = NewProductionPipeline(
+	edgeRunner.prodPipeline, issues = NewProductionPipeline(
 		edgeRunner.pipelineId,
 		edgeRunner.config,
 		edgeRunner,
 		edgeRunner.pipelineConfig,
-		runtimeParameters,
-	); err != nil {
-		return nil, err
-	}
+		edgeRunner.runtimeParameters,
+		edgeRunner.errorStore,
+		edgeRunner.alertManager,
+		edgeRunner.ruleDefinitions,
+	)
 
 	if len(issues) != 0 {
 		return edgeRunner.setStateToStartError(issues)
@@ -129,19 +226,9 @@ func (edgeRunner *EdgeRunner) StartPipeline(
 		return edgeRunner.setStateToStartError(issues)
 	}
 
-	go func() {
-		edgeRunner.prodPipeline.Run()
-		if edgeRunner.prodPipeline.Pipeline.offsetTracker.IsFinished() {
-			edgeRunner.pipelineState.Status = common.FINISHED
-			edgeRunner.pipelineState.TimeStamp = util.ConvertTimeToLong(time.Now())
-			err = store.SaveState(edgeRunner.pipelineId, edgeRunner.pipelineState)
-			if err != nil {
-				log.WithError(err).Error("Failed to save pipeline state to finished")
-			}
-		}
-	}()
+	go edgeRunner.runProductionPipeline(edgeRunner.prodPipeline)
 
-	if edgeRunner.runtimeInfo.DPMEnabled && edgeRunner.IsRemotePipeline() {
+	if edgeRunner.runtimeInfo.DPMEnabled && edgeRunner.isRemotePipeline() {
 		edgeRunner.metricsEventRunnable = NewMetricsEventRunnable(
 			edgeRunner.pipelineId,
 			edgeRunner.pipelineConfig,
@@ -152,54 +239,272 @@ func (edgeRunner *EdgeRunner) StartPipeline(
 		go edgeRunner.metricsEventRunnable.Run()
 	}
 
-	edgeRunner.pipelineState.Status = common.RUNNING
-	edgeRunner.pipelineState.TimeStamp = util.ConvertTimeToLong(time.Now())
-
-	if err = store.SaveState(edgeRunner.pipelineId, edgeRunner.pipelineState); err != nil {
+	if err := edgeRunner.saveState(common.RUNNING, ""); err != nil {
 		return nil, err
 	}
 
 	return edgeRunner.pipelineState, nil
 }
 
-func (edgeRunner *EdgeRunner) setStateToStartError(issues []validation.Issue) (*common.PipelineState, error) {
-	edgeRunner.pipelineState.Status = common.START_ERROR
+func (edgeRunner *EdgeRunner) runProductionPipeline(prodPipeline *ProductionPipeline) {
+	if err := prodPipeline.Run(); err != nil {
+		edgeRunner.handleRunError(prodPipeline, err)
+		return
+	}
+
+	edgeRunner.stateMutex.Lock()
+	defer edgeRunner.unlockAndNotify()
+
+	// a pipeline that didn't drain in time may end after the pipeline was started again
+	if edgeRunner.prodPipeline != prodPipeline {
+		return
+	}
+
+	// the offset that finished the pipeline is saved with the FINISHED state, while the pipeline is being stopped
+	// the offset is saved with the STOPPED state instead
+	if prodPipeline.Pipeline.offsetTracker.IsFinished() && edgeRunner.pipelineState.Status == common.RUNNING {
+		var sourceOffset *common.SourceOffset
+		if uncommittedOffset, ok := prodPipeline.OffsetTracker.TakeUncommittedOffset(); ok {
+			sourceOffset = &uncommittedOffset
+		}
+		delete(edgeRunner.pipelineState.Attributes, store.RETRY_ATTEMPT)
+		delete(edgeRunner.pipelineState.Attributes, store.NEXT_RETRY_TIMESTAMP)
+		if err := edgeRunner.saveStateWithOffset(common.FINISHED, "", sourceOffset); err != nil {
+			log.WithError(err).Error("Failed to save pipeline state to finished")
+		}
+	} else if edgeRunner.pipelineState.Status != common.STOPPING {
+		if sourceOffset, ok := prodPipeline.OffsetTracker.TakeUncommittedOffset(); ok {
+			if err := store.SaveOffset(edgeRunner.pipelineId, sourceOffset); err != nil {
+				log.WithError(err).Error("Failed to save pipeline offset")
+			}
+		}
+	}
+}
+
+// handleRunError moves the pipeline to RUNNING_ERROR and, when the pipeline is configured to retry and attempts
+// are left, to RETRY. After the backoff delay the production pipeline is re-created from the last committed offset.
+func (edgeRunner *EdgeRunner) handleRunError(prodPipeline *ProductionPipeline, runError error) {
+	edgeRunner.stateMutex.Lock()
+	retryStopChan, retryDelay := edgeRunner.scheduleRetry(prodPipeline, runError)
+	edgeRunner.unlockAndNotify()
+	if retryStopChan == nil {
+		return
+	}
+
+	select {
+	case <-time.After(retryDelay):
+	case <-retryStopChan:
+		return
+	}
+
+	edgeRunner.stateMutex.Lock()
+	defer edgeRunner.unlockAndNotify()
+	if edgeRunner.pipelineState.Status != common.RETRY || edgeRunner.retryStopChan != retryStopChan {
+		return
+	}
+	edgeRunner.retryStopChan = nil
+
+	if err := edgeRunner.saveState(common.STARTING, ""); err != nil {
+		log.WithError(err).Error("Failed to save pipeline state to starting")
+		return
+	}
+
+	if _, err := edgeRunner.startProductionPipeline(); err != nil {
+		log.WithError(err).Error("Failed to restart pipeline")
+	}
+}
+
+// scheduleRetry saves the error states of the pipeline and returns the channel closed when the retry is canceled
+// and the delay before the retry, or a nil channel if the pipeline isn't retried. It is called with the state lock
+// held.
+func (edgeRunner *EdgeRunner) scheduleRetry(
+	prodPipeline *ProductionPipeline,
+	runError error,
+) (chan struct{}, time.Duration) {
+	if edgeRunner.prodPipeline != prodPipeline {
+		// pipeline didn't drain in time and was started again
+		return nil, 0
+	}
+
+	if edgeRunner.metricsEventRunnable != nil {
+		edgeRunner.metricsEventRunnable.Stop()
+		edgeRunner.metricsEventRunnable = nil
+	}
+
+	if edgeRunner.pipelineState.Status != common.RUNNING {
+		// pipeline was stopped while the failed batch was running
+		return nil, 0
+	}
+
+	if err := edgeRunner.saveState(common.RUNNING_ERROR, runError.Error()); err != nil {
+		log.WithError(err).Error("Failed to save pipeline state to running error")
+	}
+
+	retryAttempt := cast.ToInt(edgeRunner.pipelineState.Attributes[store.RETRY_ATTEMPT])
+
+	pipelineConfigBean := prodPipeline.Pipeline.pipelineBean.Config
+	if !pipelineConfigBean.ShouldRetry ||
+		(pipelineConfigBean.RetryAttempts >= 0 && retryAttempt >= int(pipelineConfigBean.RetryAttempts)) {
+		if err := edgeRunner.saveState(common.RUN_ERROR, runError.Error()); err != nil {
+			log.WithError(err).Error("Failed to save pipeline state to run error")
+		}
+		return nil, 0
+	}
+
+	retryAttempt++
+	retryDelay := getRetryDelay(retryAttempt)
+	edgeRunner.retryStopChan = make(chan struct{})
+	if edgeRunner.pipelineState.Attributes == nil {
+		edgeRunner.pipelineState.Attributes = make(map[string]interface{})
+	}
+	edgeRunner.pipelineState.Attributes[store.RETRY_ATTEMPT] = retryAttempt
+	edgeRunner.pipelineState.Attributes[store.NEXT_RETRY_TIMESTAMP] =
+		util.ConvertTimeToLong(time.Now().Add(retryDelay))
+	if err := edgeRunner.saveState(common.RETRY, runError.Error()); err != nil {
+		log.WithError(err).Error("Failed to save pipeline state to retry")
+	}
+
+	log.WithField("id", edgeRunner.pipelineId).
+		WithField("attempt", retryAttempt).
+		WithField("delay", retryDelay).
+		Info("Retrying pipeline")
+	return edgeRunner.retryStopChan, retryDelay
+}
+
+func (edgeRunner *EdgeRunner) saveState(status string, message string) error {
+	return edgeRunner.saveStateWithOffset(status, message, nil)
+}
+
+// saveStateWithOffset saves the state, and the source offset if given, together in one runtime store update.
+func (edgeRunner *EdgeRunner) saveStateWithOffset(
+	status string,
+	message string,
+	sourceOffset *common.SourceOffset,
+) error {
+	edgeRunner.pipelineState.Status = status
+	edgeRunner.pipelineState.Message = message
 	edgeRunner.pipelineState.TimeStamp = util.ConvertTimeToLong(time.Now())
-	edgeRunner.pipelineState.Message = issues[0].Message
+	var err error
+	if sourceOffset != nil {
+		err = store.SaveOffsetAndState(edgeRunner.pipelineId, *sourceOffset, edgeRunner.pipelineState)
+	} else {
+		err = store.SaveState(edgeRunner.pipelineId, edgeRunner.pipelineState)
+	}
+	if err != nil {
+		return err
+	}
+	edgeRunner.queueStateNotification()
+	return nil
+}
+
+// queueStateNotification queues the notification of the state the pipeline just moved into, it is sent by
+// unlockAndNotify.
+func (edgeRunner *EdgeRunner) queueStateNotification() {
+	if edgeRunner.pipelineConfig.PipelineId == "" {
+		pipelineConfig, err := edgeRunner.pipelineStoreTask.LoadPipelineConfig(edgeRunner.pipelineId)
+		if err != nil {
+			log.WithError(err).Error("Failed to load pipeline configuration for webhook notifications")
+			return
+		}
+		edgeRunner.pipelineConfig = pipelineConfig
+	}
+	edgeRunner.stateNotifications = append(edgeRunner.stateNotifications, stateNotification{
+		pipelineConfig:    edgeRunner.pipelineConfig,
+		runtimeParameters: edgeRunner.runtimeParameters,
+		pipelineState:     *common.CopyPipelineState(edgeRunner.pipelineState),
+	})
+}
+
+// unlockAndNotify releases the state lock and then fires the webhooks and pipeline triggers configured for the
+// states the pipeline moved into while the lock was held.
+func (edgeRunner *EdgeRunner) unlockAndNotify() {
+	stateNotifications := edgeRunner.stateNotifications
+	edgeRunner.stateNotifications = nil
+	edgeRunner.stateMutex.Unlock()
+
+	for _, notification := range stateNotifications {
+		edgeRunner.webhookNotifier.Notify(
+			notification.pipelineConfig,
+			notification.runtimeParameters,
+			notification.pipelineState,
+		)
+		edgeRunner.pipelineTrigger.Trigger(
+			notification.pipelineConfig,
+			notification.runtimeParameters,
+			notification.pipelineState,
+		)
+	}
+}
+
+func (edgeRunner *EdgeRunner) setStateToStartError(issues []validation.Issue) (*common.PipelineState, error) {
+	if edgeRunner.pipelineState.Attributes == nil {
+		edgeRunner.pipelineState.Attributes = make(map[string]interface{})
+	}
 	edgeRunner.pipelineState.Attributes[store.ISSUES] = validation.NewIssues(issues)
-	if err := store.SaveState(edgeRunner.pipelineId, edgeRunner.pipelineState); err != nil {
+	if err := edgeRunner.saveState(common.START_ERROR, issues[0].Message); err != nil {
 		return nil, err
 	}
 	return edgeRunner.pipelineState, nil
 }
 
+// StopPipeline moves the pipeline to STOPPING and waits for the batch in flight to be processed and its offset
+// committed, up to the configured drain timeout. The pipeline is STOPPED once all stages are destroyed. The state
+// lock isn't held while waiting, STOPPING doesn't allow other transitions until the pipeline is STOPPED.
 func (edgeRunner *EdgeRunner) StopPipeline() (*common.PipelineState, error) {
 	log.WithField("id", edgeRunner.pipelineId).Info("Stopping pipeline")
+	edgeRunner.stateMutex.Lock()
 	var err error
 	err = edgeRunner.checkState(common.STOPPING)
 	if err != nil {
+		edgeRunner.unlockAndNotify()
 		return nil, err
 	}
 
-	if edgeRunner.prodPipeline != nil {
-		edgeRunner.prodPipeline.Stop()
+	if err = edgeRunner.saveState(common.STOPPING, ""); err != nil {
+		edgeRunner.unlockAndNotify()
+		return nil, err
+	}
+
+	if edgeRunner.retryStopChan != nil {
+		close(edgeRunner.retryStopChan)
+		edgeRunner.retryStopChan = nil
 	}
 
 	if edgeRunner.metricsEventRunnable != nil {
 		edgeRunner.metricsEventRunnable.Stop()
+		edgeRunner.metricsEventRunnable = nil
+	}
+	prodPipeline := edgeRunner.prodPipeline
+	edgeRunner.unlockAndNotify()
+
+	// the offset of the batch in flight is saved with the STOPPED state
+	var sourceOffset *common.SourceOffset
+	if prodPipeline != nil {
+		prodPipeline.OffsetTracker.DeferCommit()
+		drainTimeout := time.Duration(edgeRunner.config.StopDrainTimeout) * time.Millisecond
+		if !prodPipeline.StopAndWait(drainTimeout) {
+			log.WithField("id", edgeRunner.pipelineId).
+				WithField("timeout", drainTimeout).
+				Warn("Pipeline stopped without finishing the batch in flight")
+		}
+		if uncommittedOffset, ok := prodPipeline.OffsetTracker.TakeUncommittedOffset(); ok {
+			sourceOffset = &uncommittedOffset
+		}
 	}
 
-	edgeRunner.pipelineState.Status = common.STOPPED
-	edgeRunner.pipelineState.TimeStamp = util.ConvertTimeToLong(time.Now())
-	err = store.SaveState(edgeRunner.pipelineId, edgeRunner.pipelineState)
+	edgeRunner.stateMutex.Lock()
+	defer edgeRunner.unlockAndNotify()
+	err = edgeRunner.saveStateWithOffset(common.STOPPED, "", sourceOffset)
 	if err != nil {
 		return nil, err
 	}
 
-	return edgeRunner.pipelineState, nil
+	return common.CopyPipelineState(edgeRunner.pipelineState), nil
 }
 
 func (edgeRunner *EdgeRunner) ResetOffset() error {
+	edgeRunner.stateMutex.Lock()
+	defer edgeRunner.stateMutex.Unlock()
 	if util.Contains(RestOffsetDisallowedStatuses, edgeRunner.pipelineState.Status) {
 		return errors.New("cannot reset the source offset when the pipeline is running")
 	}
@@ -208,6 +513,8 @@ func (edgeRunner *EdgeRunner) ResetOffset() error {
 }
 
 func (edgeRunner *EdgeRunner) CommitOffset(sourceOffset common.SourceOffset) error {
+	edgeRunner.stateMutex.Lock()
+	defer edgeRunner.stateMutex.Unlock()
 	if util.Contains(UpdateOffsetAllowedStatuses, edgeRunner.pipelineState.Status) {
 		return store.SaveOffset(edgeRunner.pipelineId, sourceOffset)
 	} else {
@@ -229,16 +536,143 @@ func (edgeRunner *EdgeRunner) checkState(toState string) error {
 }
 
 func (edgeRunner *EdgeRunner) IsRemotePipeline() bool {
+	edgeRunner.stateMutex.Lock()
+	defer edgeRunner.stateMutex.Unlock()
+	return edgeRunner.isRemotePipeline()
+}
+
+func (edgeRunner *EdgeRunner) isRemotePipeline() bool {
 	attributes := edgeRunner.pipelineState.Attributes
 	return attributes != nil && attributes[store.IS_REMOTE_PIPELINE] == true
 }
 
-func (edgeRunner *EdgeRunner) GetErrorRecords(stageInstanceName string, size int) ([]api.Record, error) {
-	return edgeRunner.prodPipeline.Pipeline.GetErrorRecords(stageInstanceName, size)
+func (edgeRunner *EdgeRunner) GetErrorRecords(query store.ErrorQuery) ([]sdcrecord.SDCRecord, error) {
+	return edgeRunner.errorStore.GetErrorRecords(query)
+}
+
+func (edgeRunner *EdgeRunner) GetErrorMessages(query store.ErrorQuery) ([]store.StageErrorMessage, error) {
+	return edgeRunner.errorStore.GetErrorMessages(query)
 }
 
-func (edgeRunner *EdgeRunner) GetErrorMessages(stageInstanceName string, size int) ([]api.ErrorMessage, error) {
-	return edgeRunner.prodPipeline.Pipeline.GetErrorMessages(stageInstanceName, size)
+// ReplayErrorRecords sends the error records matching the given query back into the running pipeline and returns
+// the number of replayed records.
+func (edgeRunner *EdgeRunner) ReplayErrorRecords(query store.ErrorQuery) (int, error) {
+	prodPipeline := edgeRunner.getRunningPipeline()
+	if prodPipeline == nil {
+		return 0, errors.New("pipeline is not running")
+	}
+	errorRecords, err := edgeRunner.errorStore.GetErrorRecords(query)
+	if err != nil {
+		return 0, err
+	}
+	if len(errorRecords) == 0 {
+		return 0, nil
+	}
+	// replay in the order the records were sent to error
+	for i, j := 0, len(errorRecords)-1; i < j; i, j = i+1, j-1 {
+		errorRecords[i], errorRecords[j] = errorRecords[j], errorRecords[i]
+	}
+	return len(errorRecords), prodPipeline.Pipeline.ReplayErrorRecords(errorRecords)
+}
+
+// getRunningPipeline returns the production pipeline if the pipeline is running, nil otherwise.
+func (edgeRunner *EdgeRunner) getRunningPipeline() *ProductionPipeline {
+	edgeRunner.stateMutex.Lock()
+	defer edgeRunner.stateMutex.Unlock()
+	if edgeRunner.pipelineState.Status != common.RUNNING {
+		return nil
+	}
+	return edgeRunner.prodPipeline
+}
+
+func (edgeRunner *EdgeRunner) GetAlerts() ([]*common.AlertInfo, error) {
+	return edgeRunner.alertManager.GetAlerts(), nil
+}
+
+func (edgeRunner *EdgeRunner) DeleteAlert(ruleId string) error {
+	return edgeRunner.alertManager.DeleteAlert(ruleId)
+}
+
+// ReloadRules loads the rules of the pipeline from the pipeline store, so changes apply to a running pipeline.
+func (edgeRunner *EdgeRunner) ReloadRules() error {
+	ruleDefinitions, err := edgeRunner.pipelineStoreTask.RetrieveRules(edgeRunner.pipelineId)
+	if err != nil {
+		return err
+	}
+	edgeRunner.stateMutex.Lock()
+	defer edgeRunner.stateMutex.Unlock()
+	edgeRunner.ruleDefinitions = ruleDefinitions
+	if edgeRunner.prodPipeline != nil && edgeRunner.prodPipeline.Pipeline != nil &&
+		edgeRunner.prodPipeline.Pipeline.rulesEvaluator != nil {
+		edgeRunner.prodPipeline.Pipeline.rulesEvaluator.SetRuleDefinitions(ruleDefinitions)
+	}
+	return nil
+}
+
+func (edgeRunner *EdgeRunner) GetWebhookDeliveries() ([]store.WebhookDelivery, error) {
+	return store.GetWebhookDeliveries(edgeRunner.pipelineId)
+}
+
+// CaptureSnapshot starts capturing the stage outputs of the next batches of the running pipeline into a snapshot.
+func (edgeRunner *EdgeRunner) CaptureSnapshot(
+	snapshotName string,
+	snapshotLabel string,
+	batches int,
+) (*store.SnapshotInfo, error) {
+	prodPipeline := edgeRunner.getRunningPipeline()
+	if prodPipeline == nil {
+		return nil, errors.New("pipeline is not running")
+	}
+	return prodPipeline.Pipeline.CaptureSnapshot(snapshotName, snapshotLabel, batches)
+}
+
+func (edgeRunner *EdgeRunner) GetSnapshotsInfo() ([]*store.SnapshotInfo, error) {
+	return store.GetSnapshotsInfo(edgeRunner.pipelineId)
+}
+
+func (edgeRunner *EdgeRunner) GetSnapshotInfo(snapshotName string) (*store.SnapshotInfo, error) {
+	return store.GetSnapshotInfo(edgeRunner.pipelineId, snapshotName)
+}
+
+func (edgeRunner *EdgeRunner) GetSnapshot(snapshotName string) (*execution.Snapshot, error) {
+	snapshotInfo, err := store.GetSnapshotInfo(edgeRunner.pipelineId, snapshotName)
+	if err != nil {
+		return nil, err
+	}
+	if snapshotInfo.InProgress {
+		return nil, errors.New(fmt.Sprintf("snapshot '%s' is being captured", snapshotName))
+	}
+	snapshot := &execution.Snapshot{}
+	err = store.GetSnapshotData(edgeRunner.pipelineId, snapshotName, snapshot)
+	return snapshot, err
+}
+
+func (edgeRunner *EdgeRunner) DeleteSnapshot(snapshotName string) error {
+	snapshotInfo, err := store.GetSnapshotInfo(edgeRunner.pipelineId, snapshotName)
+	if err != nil {
+		return err
+	}
+	edgeRunner.stateMutex.Lock()
+	prodPipeline := edgeRunner.prodPipeline
+	edgeRunner.stateMutex.Unlock()
+	if snapshotInfo.InProgress && prodPipeline != nil &&
+		prodPipeline.Pipeline.getCapturingSnapshotId() == snapshotName {
+		return errors.New(fmt.Sprintf("snapshot '%s' is being captured", snapshotName))
+	}
+	return store.DeleteSnapshot(edgeRunner.pipelineId, snapshotName)
+}
+
+// getRetryDelay returns the exponential backoff delay for the given retry attempt (starting at 1),
+// doubling from RetryBaseDelay and capped at RetryMaxDelay.
+func getRetryDelay(retryAttempt int) time.Duration {
+	retryDelay := RetryBaseDelay
+	for i := 1; i < retryAttempt && retryDelay < RetryMaxDelay; i++ {
+		retryDelay *= 2
+	}
+	if retryDelay > RetryMaxDelay {
+		retryDelay = RetryMaxDelay
+	}
+	return retryDelay
 }
 
 func NewEdgeRunner(
@@ -246,12 +680,14 @@ func NewEdgeRunner(
 	config execution.Config,
 	runtimeInfo *common.RuntimeInfo,
 	pipelineStoreTask pipelineStore.PipelineStoreTask,
+	startPipeline StartPipelineFunc,
 ) (execution.Runner, error) {
 	edgeRunner := EdgeRunner{
 		pipelineId:        pipelineId,
 		config:            config,
 		runtimeInfo:       runtimeInfo,
 		pipelineStoreTask: pipelineStoreTask,
+		pipelineTrigger:   NewPipelineTrigger(pipelineId, startPipeline),
 	}
 	store.BaseDir = runtimeInfo.BaseDir
 	err := edgeRunner.init()
@@ -0,0 +1,174 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"errors"
+	"github.com/rcrowley/go-metrics"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	"github.com/streamsets/datacollector-edge/container/execution"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetRetryDelay(t *testing.T) {
+	expectedDelays := map[int]time.Duration{
+		1:  15 * time.Second,
+		2:  30 * time.Second,
+		3:  60 * time.Second,
+		4:  120 * time.Second,
+		5:  240 * time.Second,
+		6:  RetryMaxDelay,
+		50: RetryMaxDelay,
+	}
+
+	for retryAttempt, expectedDelay := range expectedDelays {
+		if delay := getRetryDelay(retryAttempt); delay != expectedDelay {
+			t.Errorf("Expected retry delay %v for attempt %d, but got %v", expectedDelay, retryAttempt, delay)
+		}
+	}
+}
+
+func newRetryTestRunner(
+	t *testing.T,
+	retryAttempt int,
+	retryAttempts float64,
+	batchCount int64,
+) (*EdgeRunner, *ProductionPipeline) {
+	done := make(chan struct{})
+	close(done)
+	prodPipeline := &ProductionPipeline{
+		Pipeline: &Pipeline{
+			pipelineBean: creation.PipelineBean{
+				Config: creation.PipelineConfigBean{ShouldRetry: true, RetryAttempts: retryAttempts},
+			},
+			batchCountCounter: metrics.NewCounter(),
+			done:              done,
+		},
+		OffsetTracker: &ProductionSourceOffsetTracker{currentOffset: common.GetDefaultOffset()},
+	}
+	prodPipeline.Pipeline.batchCountCounter.Inc(batchCount)
+
+	edgeRunner := &EdgeRunner{
+		pipelineId:      "retryPipeline",
+		config:          execution.NewConfig(),
+		pipelineConfig:  common.PipelineConfiguration{PipelineId: "retryPipeline"},
+		prodPipeline:    prodPipeline,
+		webhookNotifier: NewWebhookNotifier("retryPipeline", execution.NewConfig()),
+		pipelineTrigger: NewPipelineTrigger("retryPipeline", nil),
+	}
+	if err := edgeRunner.init(); err != nil {
+		t.Fatal(err)
+	}
+	edgeRunner.pipelineState.Status = common.RUNNING
+	edgeRunner.pipelineState.Attributes = map[string]interface{}{store.RETRY_ATTEMPT: retryAttempt}
+	return edgeRunner, prodPipeline
+}
+
+func TestRetryAttemptsAfterProcessedBatches(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestRetryAttemptsAfterProcessedBatches")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	store.BaseDir = baseDir
+
+	// a run failing before processing any batch counts as another attempt
+	edgeRunner, prodPipeline := newRetryTestRunner(t, 3, -1, 0)
+	edgeRunner.stateMutex.Lock()
+	retryStopChan, retryDelay := edgeRunner.scheduleRetry(prodPipeline, errors.New("failed"))
+	edgeRunner.unlockAndNotify()
+	if retryStopChan == nil || retryDelay != getRetryDelay(4) {
+		t.Errorf("Expected retry attempt 4 with delay %v, but got %v", getRetryDelay(4), retryDelay)
+	}
+
+	// so does a run that processed batches before failing
+	edgeRunner, prodPipeline = newRetryTestRunner(t, 3, -1, 1)
+	edgeRunner.stateMutex.Lock()
+	retryStopChan, retryDelay = edgeRunner.scheduleRetry(prodPipeline, errors.New("failed"))
+	edgeRunner.unlockAndNotify()
+	if retryStopChan == nil || retryDelay != getRetryDelay(4) {
+		t.Errorf("Expected retry attempt 4 with delay %v, but got %v", getRetryDelay(4), retryDelay)
+	}
+	pipelineState, _ := edgeRunner.GetStatus()
+	if pipelineState.Status != common.RETRY || pipelineState.Attributes[store.RETRY_ATTEMPT] != 4 {
+		t.Errorf("Expected pipeline to retry with attempt 4, but got: %+v", pipelineState)
+	}
+
+	// an intermittently failing pipeline still runs out of attempts
+	edgeRunner, prodPipeline = newRetryTestRunner(t, 3, 3, 1)
+	edgeRunner.stateMutex.Lock()
+	retryStopChan, _ = edgeRunner.scheduleRetry(prodPipeline, errors.New("failed"))
+	edgeRunner.unlockAndNotify()
+	if retryStopChan != nil {
+		t.Error("Expected no retry once the retry attempts ran out")
+	}
+	pipelineState, _ = edgeRunner.GetStatus()
+	if pipelineState.Status != common.RUN_ERROR {
+		t.Errorf("Expected status RUN_ERROR, but got %s", pipelineState.Status)
+	}
+}
+
+func TestStopPipelineWhileRetrying(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestStopPipelineWhileRetrying")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	store.BaseDir = baseDir
+
+	edgeRunner, prodPipeline := newRetryTestRunner(t, 0, -1, 0)
+
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(1)
+	go func() {
+		defer waitGroup.Done()
+		edgeRunner.handleRunError(prodPipeline, errors.New("failed"))
+	}()
+
+	// the status is read while the run error is handled
+	for {
+		pipelineState, _ := edgeRunner.GetStatus()
+		if pipelineState.Status == common.RETRY {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	pipelineState, err := edgeRunner.StopPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pipelineState.Status != common.STOPPED {
+		t.Errorf("Expected status STOPPED, but got %s", pipelineState.Status)
+	}
+
+	retryDone := make(chan struct{})
+	go func() {
+		waitGroup.Wait()
+		close(retryDone)
+	}()
+	select {
+	case <-retryDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected retry to be canceled when the pipeline is stopped")
+	}
+	if pipelineState, _ = edgeRunner.GetStatus(); pipelineState.Status != common.STOPPED {
+		t.Errorf("Expected status STOPPED after the retry was canceled, but got %s", pipelineState.Status)
+	}
+}
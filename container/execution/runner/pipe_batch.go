@@ -32,6 +32,7 @@ type PipeBatch interface {
 	GetEventRecords() int64
 	GetErrorRecords() int64
 	GetErrorMessages() int64
+	GetLaneRecords(lane string) []api.Record
 	OverrideStageOutput(pipe Pipe, stageOutput *execution.StageOutput)
 	GetSnapshotsOfAllStagesOutput() []execution.StageOutput
 }
@@ -123,6 +124,28 @@ func (b *FullPipeBatch) GetEventSink() *common.EventSink {
 	return b.eventSink
 }
 
+// moveSinks copies the error records, error messages and events collected so far into the given sinks and
+// uses them for the remaining stages of the batch.
+func (b *FullPipeBatch) moveSinks(errorSink *common.ErrorSink, eventSink *common.EventSink) {
+	for stageInstanceName, errorRecords := range b.errorSink.GetErrorRecords() {
+		for _, errorRecord := range errorRecords {
+			errorSink.ToError(stageInstanceName, errorRecord)
+		}
+	}
+	for stageInstanceName, errorMessages := range b.errorSink.GetErrorMessages() {
+		for _, errorMessage := range errorMessages {
+			errorSink.ReportError(stageInstanceName, errorMessage)
+		}
+	}
+	for stageInstanceName, eventRecords := range b.eventSink.GetEventRecords() {
+		for _, eventRecord := range eventRecords {
+			eventSink.AddEvent(stageInstanceName, eventRecord)
+		}
+	}
+	b.errorSink = errorSink
+	b.eventSink = eventSink
+}
+
 func (b *FullPipeBatch) GetInputRecords() int64 {
 	return b.inputRecords
 }
@@ -143,6 +166,10 @@ func (b *FullPipeBatch) GetErrorMessages() int64 {
 	return b.errorSink.GetTotalErrorMessages()
 }
 
+func (b *FullPipeBatch) GetLaneRecords(lane string) []api.Record {
+	return b.fullPayload[lane]
+}
+
 func (b *FullPipeBatch) OverrideStageOutput(pipe Pipe, stageOutput *execution.StageOutput) {
 	b.fullPayload = make(map[string][]api.Record)
 	for _, outputLane := range pipe.GetOutputLanes() {
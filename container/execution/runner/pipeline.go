@@ -13,6 +13,7 @@
 package runner
 
 import (
+	"errors"
 	"github.com/rcrowley/go-metrics"
 	log "github.com/sirupsen/logrus"
 	"github.com/streamsets/datacollector-edge/api"
@@ -20,7 +21,12 @@ import (
 	"github.com/streamsets/datacollector-edge/container/common"
 	"github.com/streamsets/datacollector-edge/container/creation"
 	"github.com/streamsets/datacollector-edge/container/execution"
+	"github.com/streamsets/datacollector-edge/container/execution/buffer"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"github.com/streamsets/datacollector-edge/container/recordio/sdcrecord"
 	"github.com/streamsets/datacollector-edge/container/util"
+	"path/filepath"
+	"sync"
 	"time"
 )
 
@@ -32,9 +38,20 @@ type Pipeline struct {
 	pipes             []Pipe
 	errorStageRuntime StageRuntime
 	offsetTracker     execution.SourceOffsetTracker
-	stop              bool
+	stopOnce          sync.Once
+	stopChan          chan struct{}
+	stopChanOnce      sync.Once
 	errorSink         *common.ErrorSink
 	eventSink         *common.EventSink
+	originErrorSink   *common.ErrorSink
+	originEventSink   *common.EventSink
+
+	runners            []*pipelineRunner
+	runnerError        error
+	runnerMutex        sync.Mutex
+	batchMutex         sync.Mutex
+	nextCommitSequence int64
+	readyOffsets       map[int64]*string
 
 	MetricRegistry              metrics.Registry
 	batchProcessingTimer        metrics.Timer
@@ -53,8 +70,21 @@ type Pipeline struct {
 	batchErrorRecordsHistogram  metrics.Histogram
 	batchErrorMessagesHistogram metrics.Histogram
 
-	stageToErrorRecordsMap  map[string][]api.Record
-	stageToErrorMessagesMap map[string][]api.ErrorMessage
+	errorStore      *store.ErrorStore
+	replayBatches   chan []api.Record
+	rulesEvaluator  *RulesEvaluator
+	statsAggregator *StatsAggregator
+
+	destroyOnce sync.Once
+	done        chan struct{}
+
+	snapshotMutex   sync.Mutex
+	snapshotCapture *snapshotCapture
+
+	rateLimiter          *rateLimiter
+	memoryMonitor        *memoryMonitor
+	throttled            int64
+	throttledTimeCounter metrics.Counter
 }
 
 const (
@@ -70,7 +100,8 @@ const (
 	PipelineOutputRecordsPerBatch = "pipeline.outputRecordsPerBatch"
 	PipelineErrorRecordsPerBatch  = "pipeline.errorRecordsPerBatch"
 	PipelineErrorsPerBatch        = "pipeline.errorsPerBatch"
-	MaxCountInCache               = 10
+	MaxPendingReplayBatches       = 10
+	StoreAndForwardDir            = "storeAndForward"
 )
 
 func (p *Pipeline) Init() []validation.Issue {
@@ -83,31 +114,60 @@ func (p *Pipeline) Init() []validation.Issue {
 	errorStageIssues := p.errorStageRuntime.Init()
 	issues = append(issues, errorStageIssues...)
 
+	if p.statsAggregator != nil {
+		issues = append(issues, p.statsAggregator.Init()...)
+	}
+
+	if len(p.runners) > 1 {
+		for _, runner := range p.runners[1:] {
+			issues = append(issues, runner.init()...)
+		}
+	}
+
 	return issues
 }
 
-func (p *Pipeline) Run() {
+func (p *Pipeline) Run() error {
 	log.Debug("Pipeline Run()")
 
 	defer func() {
-		for _, stagePipe := range p.pipes {
-			stagePipe.Destroy()
-		}
-		p.errorStageRuntime.Destroy()
+		p.destroy()
+		close(p.done)
 	}()
 
-	for !p.offsetTracker.IsFinished() && !p.stop {
-		err := p.runBatch()
+	if p.statsAggregator != nil {
+		go p.statsAggregator.Run()
+	}
+
+	if len(p.runners) > 0 {
+		return p.runPipelineRunners()
+	}
+
+	for !p.offsetTracker.IsFinished() && !p.isStopped() {
+		if p.throttle(); p.isStopped() {
+			break
+		}
+		var err error
+		select {
+		case replayRecords := <-p.replayBatches:
+			err = p.runBatch(replayRecords)
+		default:
+			err = p.runBatch(nil)
+		}
 		if err != nil {
 			log.WithError(err).Error("Error while processing batch")
 			log.Info("Stopping Pipeline")
 			p.Stop()
+			return err
 		}
 	}
+	return nil
 }
 
-func (p *Pipeline) runBatch() error {
-	committed := false
+// runBatch runs a batch through all stages. When replay records are given they replace the origin output and
+// no offset is committed.
+func (p *Pipeline) runBatch(replayRecords []api.Record) error {
+	committed := replayRecords != nil
 	start := time.Now()
 
 	p.errorSink.ClearErrorRecordsAndMessages()
@@ -115,7 +175,13 @@ func (p *Pipeline) runBatch() error {
 
 	previousOffset := p.offsetTracker.GetOffset()
 
-	pipeBatch := NewFullPipeBatch(p.offsetTracker, p.config.MaxBatchSize, p.errorSink, p.eventSink, false)
+	pipeBatch := NewFullPipeBatch(
+		p.offsetTracker,
+		p.config.MaxBatchSize,
+		p.errorSink,
+		p.eventSink,
+		p.isCapturingSnapshot(),
+	)
 
 	for _, pipe := range p.pipes {
 		if p.pipelineBean.Config.DeliveryGuarantee == AtMostOnce &&
@@ -127,9 +193,15 @@ func (p *Pipeline) runBatch() error {
 			committed = true
 		}
 
+		if replayRecords != nil && pipe.IsSource() {
+			pipeBatch.OverrideStageOutput(pipe, newReplayStageOutput(pipe, replayRecords))
+			continue
+		}
+
 		err := pipe.Process(pipeBatch)
 		if err != nil {
-			log.WithError(err).Error()
+			log.WithError(err).WithField("stage", pipe.GetInstanceName()).Error()
+			return err
 		}
 	}
 
@@ -148,10 +220,21 @@ func (p *Pipeline) runBatch() error {
 		}
 	}
 
-	if p.pipelineBean.Config.DeliveryGuarantee == AtLeastOnce {
+	if p.pipelineBean.Config.DeliveryGuarantee == AtLeastOnce && !committed {
 		p.offsetTracker.CommitOffset()
 	}
 
+	p.updateBatchMetrics(start, pipeBatch)
+
+	return p.applyResourceLimits(start, pipeBatch.GetInputRecords())
+}
+
+// updateBatchMetrics does the bookkeeping after a batch is processed. Pipeline runners finish batches concurrently,
+// so the bookkeeping of one batch is done at a time.
+func (p *Pipeline) updateBatchMetrics(start time.Time, pipeBatch PipeBatch) {
+	p.batchMutex.Lock()
+	defer p.batchMutex.Unlock()
+
 	p.batchProcessingTimer.UpdateSince(start)
 	p.batchCountCounter.Inc(1)
 	p.batchCountMeter.Mark(1)
@@ -171,59 +254,135 @@ func (p *Pipeline) runBatch() error {
 	p.batchErrorMessagesHistogram.Update(pipeBatch.GetErrorMessages())
 	p.batchErrorRecordsHistogram.Update(pipeBatch.GetErrorRecords())
 
-	// Retain X number of error records and error messages per stage
-	p.retainErrorRecordsInMemory(pipeBatch.GetErrorSink().GetErrorRecords())
-	p.retainErrorMessagesInMemory(pipeBatch.GetErrorSink().GetErrorMessages())
+	if p.errorStore != nil {
+		if err := p.errorStore.SaveErrorRecords(pipeBatch.GetErrorSink().GetErrorRecords()); err != nil {
+			log.WithError(err).Error("Failed to save error records")
+		}
+		if err := p.errorStore.SaveErrorMessages(pipeBatch.GetErrorSink().GetErrorMessages()); err != nil {
+			log.WithError(err).Error("Failed to save error messages")
+		}
+	}
+
+	if p.rulesEvaluator != nil {
+		p.rulesEvaluator.Evaluate(pipeBatch)
+	}
 
-	return nil
+	if stageOutputs := pipeBatch.GetSnapshotsOfAllStagesOutput(); stageOutputs != nil {
+		p.addSnapshotBatch(stageOutputs)
+	}
 }
 
-func (p *Pipeline) retainErrorRecordsInMemory(stageErrorRecords map[string][]api.Record) {
-	for stageInstanceName, errorRecords := range stageErrorRecords {
-		if _, keyExists := p.stageToErrorRecordsMap[stageInstanceName]; !keyExists {
-			p.stageToErrorRecordsMap[stageInstanceName] = make([]api.Record, 0, MaxCountInCache)
-		}
+// ReplayErrorRecords queues the given error records to be processed again by the pipeline, as if they were
+// produced by the origin. Offsets are not committed for replayed records.
+func (p *Pipeline) ReplayErrorRecords(errorRecords []sdcrecord.SDCRecord) error {
+	if len(p.pipes) == 0 || !p.pipes[0].IsSource() || len(p.pipes[0].GetOutputLanes()) == 0 {
+		return errors.New("pipeline has no origin output to replay records to")
+	}
 
-		for _, record := range errorRecords {
-			if len(p.stageToErrorRecordsMap[stageInstanceName]) >= MaxCountInCache {
-				p.stageToErrorRecordsMap[stageInstanceName] = append(p.stageToErrorRecordsMap[stageInstanceName][1:], record)
-			} else {
-				p.stageToErrorRecordsMap[stageInstanceName] = append(p.stageToErrorRecordsMap[stageInstanceName], record)
-			}
+	originStageContext := p.pipes[0].GetStageContext()
+	records := make([]api.Record, 0, len(errorRecords))
+	for _, errorRecord := range errorRecords {
+		var header common.HeaderImpl
+		if errorRecord.Header != nil {
+			header = *errorRecord.Header
+		}
+		header.ErrorDataCollectorId = ""
+		header.ErrorPipelineName = ""
+		header.ErrorStageInstance = ""
+		header.ErrorMessage = ""
+		header.ErrorTimestamp = 0
+		errorRecord.Header = &header
+
+		record, err := sdcrecord.NewRecordFromSDCRecord(originStageContext, &errorRecord)
+		if err != nil {
+			return err
 		}
+		records = append(records, record)
+	}
+
+	select {
+	case p.replayBatches <- records:
+		return nil
+	default:
+		return errors.New("too many replay batches pending, try again later")
 	}
 }
 
-func (p *Pipeline) retainErrorMessagesInMemory(stageErrorMessages map[string][]api.ErrorMessage) {
-	for stageInstanceName, errorMessages := range stageErrorMessages {
-		if _, keyExists := p.stageToErrorMessagesMap[stageInstanceName]; !keyExists {
-			p.stageToErrorMessagesMap[stageInstanceName] = make([]api.ErrorMessage, 0, MaxCountInCache)
-		}
+func newReplayStageOutput(originPipe Pipe, replayRecords []api.Record) *execution.StageOutput {
+	return &execution.StageOutput{
+		InstanceName: originPipe.GetInstanceName(),
+		Output: map[string][]api.Record{
+			originPipe.GetOutputLanes()[0]: replayRecords,
+		},
+	}
+}
 
-		for _, errorMessage := range errorMessages {
-			if len(p.stageToErrorMessagesMap[stageInstanceName]) >= MaxCountInCache {
-				p.stageToErrorMessagesMap[stageInstanceName] = append(p.stageToErrorMessagesMap[stageInstanceName][1:], errorMessage)
-			} else {
-				p.stageToErrorMessagesMap[stageInstanceName] = append(p.stageToErrorMessagesMap[stageInstanceName], errorMessage)
+// StopAndWait stops the pipeline and waits until the batch in flight is processed, its offset committed and all
+// stages are destroyed. If that takes longer than the drain timeout the stages are destroyed right away and false
+// is returned.
+func (p *Pipeline) StopAndWait(drainTimeout time.Duration) bool {
+	p.Stop()
+	select {
+	case <-p.done:
+		return true
+	case <-time.After(drainTimeout):
+		log.WithField("timeout", drainTimeout).Warn("Pipeline did not drain in time, destroying stages")
+		p.destroy()
+		return false
+	}
+}
+
+// destroy destroys all stages once, either when the pipeline run ends or when it didn't drain in time.
+func (p *Pipeline) destroy() {
+	p.destroyOnce.Do(func() {
+		for _, stagePipe := range p.pipes {
+			stagePipe.Destroy()
+		}
+		p.errorStageRuntime.Destroy()
+		if len(p.runners) > 1 {
+			for _, runner := range p.runners[1:] {
+				runner.destroy()
 			}
 		}
-	}
+		if p.statsAggregator != nil {
+			p.statsAggregator.Stop()
+		}
+		p.finishSnapshotCapture()
+	})
 }
 
-func (p *Pipeline) GetErrorRecords(stageInstanceName string, size int) ([]api.Record, error) {
-	return p.stageToErrorRecordsMap[stageInstanceName], nil
+// stopped returns the channel that is closed once the pipeline is stopped.
+func (p *Pipeline) stopped() chan struct{} {
+	p.stopChanOnce.Do(func() {
+		p.stopChan = make(chan struct{})
+	})
+	return p.stopChan
 }
 
-func (p *Pipeline) GetErrorMessages(stageInstanceName string, size int) ([]api.ErrorMessage, error) {
-	return p.stageToErrorMessagesMap[stageInstanceName], nil
+// isStopped reports whether Stop has been called, it is safe to call from any goroutine.
+func (p *Pipeline) isStopped() bool {
+	select {
+	case <-p.stopped():
+		return true
+	default:
+		return false
+	}
 }
 
 func (p *Pipeline) Stop() {
 	log.Debug("Pipeline Stop()")
-	p.stop = true
+	stopChan := p.stopped()
+	p.stopOnce.Do(func() {
+		close(stopChan)
+	})
 	for _, pipe := range p.pipes {
 		pipe.GetStageContext().SetStop()
 	}
+	if len(p.runners) > 1 {
+		for _, runner := range p.runners[1:] {
+			runner.stop()
+		}
+	}
 }
 
 func NewPipeline(
@@ -234,13 +393,13 @@ func NewPipeline(
 	metricRegistry metrics.Registry,
 ) (*Pipeline, []validation.Issue) {
 	issues := make([]validation.Issue, 0)
-	pipelineConfigForParam := creation.NewPipelineConfigBean(pipelineConfig)
-	stageRuntimeList := make([]StageRuntime, len(pipelineConfig.Stages))
-	pipes := make([]Pipe, len(pipelineConfig.Stages))
 	errorSink := common.NewErrorSink()
-	eventSink := common.NewEventSink()
 
-	var errorStageRuntime StageRuntime
+	if pipelineConfig.Stages, issues = common.SortStages(pipelineConfig.Stages); len(issues) > 0 {
+		return nil, issues
+	}
+	eventSink := common.NewEventSink()
+	pipelineConfigForParam := creation.NewPipelineConfigBean(pipelineConfig)
 
 	var resolvedParameters = make(map[string]interface{})
 	for k, v := range pipelineConfigForParam.Constants {
@@ -256,7 +415,156 @@ func NewPipeline(
 		return nil, issues
 	}
 
-	for i, stageBean := range pipelineBean.Stages {
+	runnerCount := int(pipelineBean.Config.MaxRunners)
+	originErrorSink := errorSink
+	originEventSink := eventSink
+	if runnerCount > 1 {
+		// origin runs concurrently with the pipeline runners and must not share their sinks
+		originErrorSink = common.NewErrorSink()
+		originEventSink = common.NewEventSink()
+	}
+
+	var storeAndForwardBuffers map[string]*buffer.DiskBuffer
+	if pipelineBean.Config.StoreAndForward {
+		if storeAndForwardBuffers, issues = createStoreAndForwardBuffers(
+			pipelineConfig.PipelineId,
+			pipelineBean,
+			metricRegistry,
+		); len(issues) > 0 {
+			return nil, issues
+		}
+	}
+
+	pipes, errorStageRuntime, issues := createStagePipes(
+		config,
+		pipelineBean,
+		pipelineBean.Stages,
+		resolvedParameters,
+		metricRegistry,
+		errorSink,
+		eventSink,
+		originErrorSink,
+		originEventSink,
+		storeAndForwardBuffers,
+	)
+	if len(issues) > 0 {
+		return nil, issues
+	}
+
+	p := &Pipeline{
+		pipelineConf:      pipelineConfig,
+		pipelineBean:      pipelineBean,
+		pipes:             pipes,
+		errorStageRuntime: errorStageRuntime,
+		errorSink:         errorSink,
+		eventSink:         eventSink,
+		offsetTracker:     sourceOffsetTracker,
+		MetricRegistry:    metricRegistry,
+		config:            config,
+		done:              make(chan struct{}),
+	}
+
+	p.batchProcessingTimer = util.CreateTimer(metricRegistry, PipelineBatchProcessing)
+
+	p.batchCountCounter = util.CreateCounter(metricRegistry, PipelineBatchCount)
+	p.batchInputRecordsCounter = util.CreateCounter(metricRegistry, PipelineBatchInputRecords)
+	p.batchOutputRecordsCounter = util.CreateCounter(metricRegistry, PipelineBatchOutputRecords)
+	p.batchErrorRecordsCounter = util.CreateCounter(metricRegistry, PipelineBatchErrorRecords)
+	p.batchErrorMessagesCounter = util.CreateCounter(metricRegistry, PipelineBatchErrorMessages)
+
+	p.batchCountMeter = util.CreateMeter(metricRegistry, PipelineBatchCount)
+	p.batchInputRecordsMeter = util.CreateMeter(metricRegistry, PipelineBatchInputRecords)
+	p.batchOutputRecordsMeter = util.CreateMeter(metricRegistry, PipelineBatchOutputRecords)
+	p.batchErrorRecordsMeter = util.CreateMeter(metricRegistry, PipelineBatchErrorRecords)
+	p.batchErrorMessagesMeter = util.CreateMeter(metricRegistry, PipelineBatchErrorMessages)
+
+	p.batchInputRecordsHistogram = util.CreateHistogram5Min(metricRegistry, PipelineInputRecordsPerBatch)
+	p.batchOutputRecordsHistogram = util.CreateHistogram5Min(metricRegistry, PipelineOutputRecordsPerBatch)
+	p.batchErrorRecordsHistogram = util.CreateHistogram5Min(metricRegistry, PipelineErrorRecordsPerBatch)
+	p.batchErrorMessagesHistogram = util.CreateHistogram5Min(metricRegistry, PipelineErrorsPerBatch)
+
+	p.replayBatches = make(chan []api.Record, MaxPendingReplayBatches)
+
+	p.initResourceLimits(metricRegistry)
+
+	if isStatsAggregatorEnabled(pipelineBean) {
+		p.statsAggregator, issues = NewStatsAggregator(
+			config,
+			pipelineConfig,
+			pipelineBean,
+			resolvedParameters,
+			metricRegistry,
+		)
+		if len(issues) > 0 {
+			return nil, issues
+		}
+	}
+
+	if runnerCount > 1 {
+		p.originErrorSink = originErrorSink
+		p.originEventSink = originEventSink
+		issues = p.createPipelineRunners(runnerCount, pipelineConfig, resolvedParameters, storeAndForwardBuffers)
+		if len(issues) > 0 {
+			return nil, issues
+		}
+	}
+
+	return p, issues
+}
+
+// createStoreAndForwardBuffers creates a disk buffer for each destination, under the pipeline run info directory.
+// The buffers are shared by all pipeline runners.
+func createStoreAndForwardBuffers(
+	pipelineId string,
+	pipelineBean creation.PipelineBean,
+	metricRegistry metrics.Registry,
+) (map[string]*buffer.DiskBuffer, []validation.Issue) {
+	issues := make([]validation.Issue, 0)
+	storeAndForwardBuffers := make(map[string]*buffer.DiskBuffer)
+	for _, stageBean := range pipelineBean.Stages {
+		if !stageBean.IsTarget() {
+			continue
+		}
+		diskBuffer, err := buffer.NewDiskBuffer(
+			filepath.Join(store.GetRunInfoDir(pipelineId), StoreAndForwardDir, stageBean.Config.InstanceName),
+			int64(pipelineBean.Config.StoreAndForwardMaxSizeMB*1024*1024),
+			time.Duration(pipelineBean.Config.StoreAndForwardMaxAgeSecs)*time.Second,
+			pipelineBean.Config.StoreAndForwardOverflowPolicy,
+			metricRegistry,
+			"stage."+stageBean.Config.InstanceName,
+		)
+		if err != nil {
+			issues = append(issues, validation.Issue{
+				InstanceName: stageBean.Config.InstanceName,
+				Level:        common.StageConfig,
+				Count:        1,
+				Message:      err.Error(),
+			})
+			return nil, issues
+		}
+		storeAndForwardBuffers[stageBean.Config.InstanceName] = diskBuffer
+	}
+	return storeAndForwardBuffers, issues
+}
+
+// createStagePipes creates a stage pipe for each of the given stage beans and a runtime for the error stage.
+// The origin stage context is wired to the origin sinks, all other stages to the given error and event sinks.
+func createStagePipes(
+	config execution.Config,
+	pipelineBean creation.PipelineBean,
+	stageBeans []creation.StageBean,
+	resolvedParameters map[string]interface{},
+	metricRegistry metrics.Registry,
+	errorSink *common.ErrorSink,
+	eventSink *common.EventSink,
+	originErrorSink *common.ErrorSink,
+	originEventSink *common.EventSink,
+	storeAndForwardBuffers map[string]*buffer.DiskBuffer,
+) ([]Pipe, StageRuntime, []validation.Issue) {
+	issues := make([]validation.Issue, 0)
+	pipes := make([]Pipe, len(stageBeans))
+
+	for i, stageBean := range stageBeans {
 		var services map[string]api.Service
 		if stageBean.Services != nil && len(stageBean.Services) > 0 {
 			services = make(map[string]api.Service)
@@ -265,16 +573,23 @@ func NewPipeline(
 			}
 		}
 
+		stageErrorSink := errorSink
+		stageEventSink := eventSink
+		if stageBean.IsSource() {
+			stageErrorSink = originErrorSink
+			stageEventSink = originEventSink
+		}
+
 		stageContext, err := common.NewStageContext(
 			stageBean.Config,
 			resolvedParameters,
 			metricRegistry,
-			errorSink,
+			stageErrorSink,
 			false,
-			pipelineConfigForParam.ErrorRecordPolicy,
+			pipelineBean.Config.ErrorRecordPolicy,
 			services,
 			pipelineBean.ElContext,
-			eventSink,
+			stageEventSink,
 			false,
 		)
 		if err != nil {
@@ -284,10 +599,11 @@ func NewPipeline(
 				Count:        1,
 				Message:      err.Error(),
 			})
-			return nil, issues
+			return nil, StageRuntime{}, issues
 		}
-		stageRuntimeList[i] = NewStageRuntime(pipelineBean, stageBean, stageContext)
-		pipes[i] = NewStagePipe(stageRuntimeList[i], config)
+		stageRuntime := NewStageRuntime(pipelineBean, stageBean, stageContext)
+		stageRuntime.storeAndForwardBuffer = storeAndForwardBuffers[stageBean.Config.InstanceName]
+		pipes[i] = NewStagePipe(stageRuntime, config)
 	}
 
 	log.Debug("Error Stage:", pipelineBean.ErrorStage.Config.InstanceName)
@@ -297,7 +613,7 @@ func NewPipeline(
 		metricRegistry,
 		errorSink,
 		true,
-		pipelineConfigForParam.ErrorRecordPolicy,
+		pipelineBean.Config.ErrorRecordPolicy,
 		nil,
 		pipelineBean.ElContext,
 		eventSink,
@@ -310,43 +626,8 @@ func NewPipeline(
 			Count:        1,
 			Message:      err.Error(),
 		})
-		return nil, issues
-	}
-	errorStageRuntime = NewStageRuntime(pipelineBean, pipelineBean.ErrorStage, errorStageContext)
-
-	p := &Pipeline{
-		pipelineConf:      pipelineConfig,
-		pipelineBean:      pipelineBean,
-		pipes:             pipes,
-		errorStageRuntime: errorStageRuntime,
-		errorSink:         errorSink,
-		eventSink:         eventSink,
-		offsetTracker:     sourceOffsetTracker,
-		MetricRegistry:    metricRegistry,
-		config:            config,
+		return nil, StageRuntime{}, issues
 	}
 
-	p.batchProcessingTimer = util.CreateTimer(metricRegistry, PipelineBatchProcessing)
-
-	p.batchCountCounter = util.CreateCounter(metricRegistry, PipelineBatchCount)
-	p.batchInputRecordsCounter = util.CreateCounter(metricRegistry, PipelineBatchInputRecords)
-	p.batchOutputRecordsCounter = util.CreateCounter(metricRegistry, PipelineBatchOutputRecords)
-	p.batchErrorRecordsCounter = util.CreateCounter(metricRegistry, PipelineBatchErrorRecords)
-	p.batchErrorMessagesCounter = util.CreateCounter(metricRegistry, PipelineBatchErrorMessages)
-
-	p.batchCountMeter = util.CreateMeter(metricRegistry, PipelineBatchCount)
-	p.batchInputRecordsMeter = util.CreateMeter(metricRegistry, PipelineBatchInputRecords)
-	p.batchOutputRecordsMeter = util.CreateMeter(metricRegistry, PipelineBatchOutputRecords)
-	p.batchErrorRecordsMeter = util.CreateMeter(metricRegistry, PipelineBatchErrorRecords)
-	p.batchErrorMessagesMeter = util.CreateMeter(metricRegistry, PipelineBatchErrorMessages)
-
-	p.batchInputRecordsHistogram = util.CreateHistogram5Min(metricRegistry, PipelineInputRecordsPerBatch)
-	p.batchOutputRecordsHistogram = util.CreateHistogram5Min(metricRegistry, PipelineOutputRecordsPerBatch)
-	p.batchErrorRecordsHistogram = util.CreateHistogram5Min(metricRegistry, PipelineErrorRecordsPerBatch)
-	p.batchErrorMessagesHistogram = util.CreateHistogram5Min(metricRegistry, PipelineErrorsPerBatch)
-
-	p.stageToErrorRecordsMap = make(map[string][]api.Record)
-	p.stageToErrorMessagesMap = make(map[string][]api.ErrorMessage)
-
-	return p, issues
+	return pipes, NewStageRuntime(pipelineBean, pipelineBean.ErrorStage, errorStageContext), issues
 }
@@ -0,0 +1,374 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"fmt"
+	"github.com/rcrowley/go-metrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/api/validation"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	"github.com/streamsets/datacollector-edge/container/execution/buffer"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"sync"
+	"time"
+)
+
+const (
+	PipelineRunnerBatchProcessing = "pipeline.runner.%d.batchProcessing"
+	PipelineRunnerBatchCount      = "pipeline.runner.%d.batchCount"
+)
+
+// pipelineRunner holds one instance of every processor, destination and error stage of the pipeline.
+// In multithreaded mode the origin hands each batch over to an idle runner, so batches are processed
+// concurrently while every stage instance is only used by one goroutine at a time.
+type pipelineRunner struct {
+	runnerId             int
+	pipes                []Pipe
+	errorStageRuntime    StageRuntime
+	errorSink            *common.ErrorSink
+	eventSink            *common.EventSink
+	batchProcessingTimer metrics.Timer
+	batchCountCounter    metrics.Counter
+}
+
+// runnerBatch is a batch produced by the origin, waiting to be processed by a pipeline runner.
+// Replayed batches carry error records queued with Pipeline.ReplayErrorRecords and don't commit offsets.
+type runnerBatch struct {
+	replay        bool
+	sequence      int64
+	start         time.Time
+	pipeBatch     *FullPipeBatch
+	offsetTracker *runnerSourceOffsetTracker
+}
+
+// runnerSourceOffsetTracker tracks the offsets of a single batch. Offsets are committed by the pipeline in the
+// order the batches were produced, see Pipeline.commitRunnerBatch.
+type runnerSourceOffsetTracker struct {
+	offset        *string
+	newOffset     *string
+	lastBatchTime time.Time
+}
+
+func (o *runnerSourceOffsetTracker) IsFinished() bool {
+	return false
+}
+
+func (o *runnerSourceOffsetTracker) SetOffset(newOffset *string) {
+	o.newOffset = newOffset
+}
+
+func (o *runnerSourceOffsetTracker) CommitOffset() error {
+	return nil
+}
+
+func (o *runnerSourceOffsetTracker) GetOffset() *string {
+	return o.offset
+}
+
+func (o *runnerSourceOffsetTracker) GetLastBatchTime() time.Time {
+	return o.lastBatchTime
+}
+
+func (r *pipelineRunner) init() []validation.Issue {
+	var issues []validation.Issue
+	for _, stagePipe := range r.pipes {
+		issues = append(issues, stagePipe.Init()...)
+	}
+	return append(issues, r.errorStageRuntime.Init()...)
+}
+
+func (r *pipelineRunner) stop() {
+	for _, pipe := range r.pipes {
+		pipe.GetStageContext().SetStop()
+	}
+}
+
+func (r *pipelineRunner) destroy() {
+	for _, stagePipe := range r.pipes {
+		stagePipe.Destroy()
+	}
+	r.errorStageRuntime.Destroy()
+}
+
+// createPipelineRunners creates the given number of pipeline runners. The first runner reuses the stages created
+// for the pipeline itself, the other runners get their own stage instances created from the pipeline configuration.
+func (p *Pipeline) createPipelineRunners(
+	runnerCount int,
+	pipelineConfig common.PipelineConfiguration,
+	resolvedParameters map[string]interface{},
+	storeAndForwardBuffers map[string]*buffer.DiskBuffer,
+) []validation.Issue {
+	issues := make([]validation.Issue, 0)
+	if len(p.pipes) == 0 || !p.pipes[0].IsSource() {
+		issues = append(issues, validation.Issue{
+			Level:   common.StageConfig,
+			Count:   1,
+			Message: "Multithreaded pipeline requires an origin stage",
+		})
+		return issues
+	}
+
+	p.runners = make([]*pipelineRunner, runnerCount)
+	p.runners[0] = p.newPipelineRunner(0, p.pipes[1:], p.errorStageRuntime, p.errorSink, p.eventSink)
+
+	for runnerId := 1; runnerId < runnerCount; runnerId++ {
+		var runnerPipelineBean creation.PipelineBean
+		runnerPipelineBean, issues = creation.NewPipelineBean(pipelineConfig, resolvedParameters)
+		if len(issues) > 0 {
+			return issues
+		}
+		runnerPipelineBean.ElContext = p.pipelineBean.ElContext
+
+		runnerStageBeans := make([]creation.StageBean, 0, len(runnerPipelineBean.Stages))
+		for _, stageBean := range runnerPipelineBean.Stages {
+			if !stageBean.IsSource() {
+				runnerStageBeans = append(runnerStageBeans, stageBean)
+			}
+		}
+
+		errorSink := common.NewErrorSink()
+		eventSink := common.NewEventSink()
+		var pipes []Pipe
+		var errorStageRuntime StageRuntime
+		pipes, errorStageRuntime, issues = createStagePipes(
+			p.config,
+			runnerPipelineBean,
+			runnerStageBeans,
+			resolvedParameters,
+			p.MetricRegistry,
+			errorSink,
+			eventSink,
+			errorSink,
+			eventSink,
+			storeAndForwardBuffers,
+		)
+		if len(issues) > 0 {
+			return issues
+		}
+		p.runners[runnerId] = p.newPipelineRunner(runnerId, pipes, errorStageRuntime, errorSink, eventSink)
+	}
+
+	p.readyOffsets = make(map[int64]*string)
+	return issues
+}
+
+func (p *Pipeline) newPipelineRunner(
+	runnerId int,
+	pipes []Pipe,
+	errorStageRuntime StageRuntime,
+	errorSink *common.ErrorSink,
+	eventSink *common.EventSink,
+) *pipelineRunner {
+	return &pipelineRunner{
+		runnerId:             runnerId,
+		pipes:                pipes,
+		errorStageRuntime:    errorStageRuntime,
+		errorSink:            errorSink,
+		eventSink:            eventSink,
+		batchProcessingTimer: util.CreateTimer(p.MetricRegistry, fmt.Sprintf(PipelineRunnerBatchProcessing, runnerId)),
+		batchCountCounter:    util.CreateCounter(p.MetricRegistry, fmt.Sprintf(PipelineRunnerBatchCount, runnerId)),
+	}
+}
+
+// runPipelineRunners runs the origin on the calling goroutine and hands every produced batch over to the
+// pool of pipeline runners. It returns once the origin is finished or the pipeline is stopped and all batches
+// in flight are processed.
+func (p *Pipeline) runPipelineRunners() error {
+	originPipe := p.pipes[0]
+	batches := make(chan *runnerBatch)
+
+	var waitGroup sync.WaitGroup
+	for _, runner := range p.runners {
+		waitGroup.Add(1)
+		go func(runner *pipelineRunner) {
+			defer waitGroup.Done()
+			for batch := range batches {
+				if p.getRunnerError() != nil {
+					// drain remaining batches, their offsets are never committed
+					continue
+				}
+				if err := p.runRunnerBatch(runner, batch); err != nil {
+					log.WithError(err).WithField("runner", runner.runnerId).Error("Error while processing batch")
+					p.setRunnerError(err)
+					p.Stop()
+				}
+			}
+		}(runner)
+	}
+
+	var originError error
+	var sequence int64
+	offset := p.offsetTracker.GetOffset()
+	originFinished := p.offsetTracker.IsFinished()
+	for !originFinished && !p.isStopped() {
+		if p.throttle(); p.isStopped() {
+			break
+		}
+		start := time.Now()
+		p.originErrorSink.ClearErrorRecordsAndMessages()
+		p.originEventSink.ClearEventRecords()
+
+		batchOffsetTracker := &runnerSourceOffsetTracker{offset: offset, lastBatchTime: start}
+		pipeBatch := NewFullPipeBatch(
+			batchOffsetTracker,
+			p.config.MaxBatchSize,
+			p.originErrorSink,
+			p.originEventSink,
+			p.isCapturingSnapshot(),
+		).(*FullPipeBatch)
+
+		select {
+		case replayRecords := <-p.replayBatches:
+			pipeBatch.OverrideStageOutput(originPipe, newReplayStageOutput(originPipe, replayRecords))
+			pipeBatch.moveSinks(common.NewErrorSink(), common.NewEventSink())
+			batches <- &runnerBatch{
+				replay:        true,
+				start:         start,
+				pipeBatch:     pipeBatch,
+				offsetTracker: batchOffsetTracker,
+			}
+			continue
+		default:
+		}
+
+		if originError = originPipe.Process(pipeBatch); originError != nil {
+			log.WithError(originError).WithField("stage", originPipe.GetInstanceName()).Error()
+			p.Stop()
+			break
+		}
+
+		// detach the batch from the origin sinks, they are reused for the next batch
+		pipeBatch.moveSinks(common.NewErrorSink(), common.NewEventSink())
+
+		offset = batchOffsetTracker.newOffset
+		originFinished = offset == nil
+		batches <- &runnerBatch{
+			sequence:      sequence,
+			start:         start,
+			pipeBatch:     pipeBatch,
+			offsetTracker: batchOffsetTracker,
+		}
+		sequence++
+
+		if originError = p.applyResourceLimits(start, pipeBatch.GetInputRecords()); originError != nil {
+			p.Stop()
+			break
+		}
+	}
+
+	close(batches)
+	waitGroup.Wait()
+
+	if originError != nil {
+		log.WithError(originError).Error("Error while processing batch")
+		log.Info("Stopping Pipeline")
+		return originError
+	}
+
+	if err := p.getRunnerError(); err != nil {
+		log.Info("Stopping Pipeline")
+		return err
+	}
+	return nil
+}
+
+func (p *Pipeline) runRunnerBatch(runner *pipelineRunner, batch *runnerBatch) error {
+	runner.errorSink.ClearErrorRecordsAndMessages()
+	runner.eventSink.ClearEventRecords()
+	batch.pipeBatch.moveSinks(runner.errorSink, runner.eventSink)
+
+	committed := batch.replay
+	previousOffset := batch.offsetTracker.GetOffset()
+
+	for _, pipe := range runner.pipes {
+		if p.pipelineBean.Config.DeliveryGuarantee == AtMostOnce &&
+			pipe.IsTarget() && // if destination
+			!committed {
+			if err := p.commitRunnerBatch(batch); err != nil {
+				return err
+			}
+			committed = true
+		}
+
+		err := pipe.Process(batch.pipeBatch)
+		if err != nil {
+			log.WithError(err).WithField("stage", pipe.GetInstanceName()).Error()
+			return err
+		}
+	}
+
+	errorRecords := make([]api.Record, 0)
+	for _, stageBean := range p.pipelineBean.Stages {
+		errorRecordsForThisStage := runner.errorSink.GetStageErrorRecords(stageBean.Config.InstanceName)
+		if errorRecordsForThisStage != nil && len(errorRecordsForThisStage) > 0 {
+			errorRecords = append(errorRecords, errorRecordsForThisStage...)
+		}
+	}
+	if len(errorRecords) > 0 {
+		errorBatch := NewBatchImpl(runner.errorStageRuntime.config.InstanceName, errorRecords, previousOffset)
+		_, err := runner.errorStageRuntime.Execute(previousOffset, -1, errorBatch, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !committed {
+		if err := p.commitRunnerBatch(batch); err != nil {
+			return err
+		}
+	}
+
+	runner.batchProcessingTimer.UpdateSince(batch.start)
+	runner.batchCountCounter.Inc(1)
+	p.updateBatchMetrics(batch.start, batch.pipeBatch)
+
+	return nil
+}
+
+// commitRunnerBatch marks the offset of the given batch ready to be committed and commits all ready offsets
+// in the order the batches were produced by the origin.
+func (p *Pipeline) commitRunnerBatch(batch *runnerBatch) error {
+	p.runnerMutex.Lock()
+	defer p.runnerMutex.Unlock()
+
+	p.readyOffsets[batch.sequence] = batch.offsetTracker.newOffset
+	for {
+		offset, ready := p.readyOffsets[p.nextCommitSequence]
+		if !ready {
+			return nil
+		}
+		delete(p.readyOffsets, p.nextCommitSequence)
+		p.offsetTracker.SetOffset(offset)
+		if err := p.offsetTracker.CommitOffset(); err != nil {
+			return err
+		}
+		p.nextCommitSequence++
+	}
+}
+
+func (p *Pipeline) setRunnerError(err error) {
+	p.runnerMutex.Lock()
+	defer p.runnerMutex.Unlock()
+	if p.runnerError == nil {
+		p.runnerError = err
+	}
+}
+
+func (p *Pipeline) getRunnerError() error {
+	p.runnerMutex.Lock()
+	defer p.runnerMutex.Unlock()
+	return p.runnerError
+}
@@ -0,0 +1,298 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"errors"
+	"github.com/rcrowley/go-metrics"
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	"github.com/streamsets/datacollector-edge/container/execution"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"github.com/streamsets/datacollector-edge/stages/stagelibrary"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	runnerTestLibrary          = "streamsets-datacollector-runner-test-lib"
+	runnerTestOriginName       = "com_streamsets_pipeline_stage_test_RunnerTestOrigin"
+	runnerTestDestinationName  = "com_streamsets_pipeline_stage_test_RunnerTestDestination"
+	runnerTestErrorStageName   = "com_streamsets_pipeline_stage_test_RunnerTestErrorStage"
+	runnerTestBatches          = 200
+	runnerTestSnapshotBatches  = 10
+	runnerTestMaxRunners       = 4
+	runnerTestDestinationStage = "destination"
+)
+
+var (
+	runnerTestWrittenRecords int64
+	runnerTestErrorRecords   int64
+	runnerTestFailingRecord  int64 = -1
+)
+
+// runnerTestOrigin produces one record per batch, the offset is the number of records produced so far.
+// Once stopped it produces empty batches until the pipeline stops calling it.
+type runnerTestOrigin struct {
+	*common.BaseStage
+}
+
+func (o *runnerTestOrigin) Produce(
+	lastSourceOffset *string,
+	maxBatchSize int,
+	batchMaker api.BatchMaker,
+) (*string, error) {
+	produced := 0
+	if lastSourceOffset != nil {
+		produced, _ = strconv.Atoi(*lastSourceOffset)
+	}
+	if produced == runnerTestBatches {
+		return nil, nil
+	}
+	if o.GetStageContext().IsStopped() {
+		return lastSourceOffset, nil
+	}
+	record, err := o.GetStageContext().CreateRecord(strconv.Itoa(produced), map[string]interface{}{"n": produced})
+	if err != nil {
+		return nil, err
+	}
+	batchMaker.AddRecord(record)
+	newOffset := strconv.Itoa(produced + 1)
+	return &newOffset, nil
+}
+
+// runnerTestDestination sends every other record to error and fails the batch of runnerTestFailingRecord.
+type runnerTestDestination struct {
+	*common.BaseStage
+}
+
+func (d *runnerTestDestination) Write(batch api.Batch) error {
+	for _, record := range batch.GetRecords() {
+		n, _ := strconv.Atoi(record.GetHeader().GetSourceId())
+		if int64(n) == atomic.LoadInt64(&runnerTestFailingRecord) {
+			return errors.New("failing record")
+		}
+		if n%2 == 0 {
+			d.GetStageContext().ToError(errors.New("even record"), record)
+		} else {
+			atomic.AddInt64(&runnerTestWrittenRecords, 1)
+		}
+	}
+	return nil
+}
+
+type runnerTestErrorStage struct {
+	*common.BaseStage
+}
+
+func (e *runnerTestErrorStage) Write(batch api.Batch) error {
+	atomic.AddInt64(&runnerTestErrorRecords, int64(len(batch.GetRecords())))
+	return nil
+}
+
+func init() {
+	stagelibrary.SetCreator(runnerTestLibrary, runnerTestOriginName, func() api.Stage {
+		return &runnerTestOrigin{BaseStage: &common.BaseStage{}}
+	})
+	stagelibrary.SetCreator(runnerTestLibrary, runnerTestDestinationName, func() api.Stage {
+		return &runnerTestDestination{BaseStage: &common.BaseStage{}}
+	})
+	stagelibrary.SetCreator(runnerTestLibrary, runnerTestErrorStageName, func() api.Stage {
+		return &runnerTestErrorStage{BaseStage: &common.BaseStage{}}
+	})
+}
+
+type committedOffsetsTracker struct {
+	runnerSourceOffsetTracker
+	committedOffsets []string
+}
+
+func (o *committedOffsetsTracker) CommitOffset() error {
+	if o.newOffset != nil {
+		o.committedOffsets = append(o.committedOffsets, *o.newOffset)
+	}
+	return nil
+}
+
+func TestCommitRunnerBatchInOrder(t *testing.T) {
+	offsetTracker := &committedOffsetsTracker{}
+	p := &Pipeline{
+		offsetTracker: offsetTracker,
+		readyOffsets:  make(map[int64]*string),
+	}
+
+	newRunnerBatch := func(sequence int64, offset string) *runnerBatch {
+		return &runnerBatch{
+			sequence:      sequence,
+			start:         time.Now(),
+			offsetTracker: &runnerSourceOffsetTracker{newOffset: &offset},
+		}
+	}
+
+	_ = p.commitRunnerBatch(newRunnerBatch(1, "offset1"))
+	_ = p.commitRunnerBatch(newRunnerBatch(2, "offset2"))
+	if len(offsetTracker.committedOffsets) != 0 {
+		t.Fatalf("Expected no committed offsets before first batch completes, but got %v",
+			offsetTracker.committedOffsets)
+	}
+
+	_ = p.commitRunnerBatch(newRunnerBatch(0, "offset0"))
+	_ = p.commitRunnerBatch(newRunnerBatch(3, "offset3"))
+
+	expectedOffsets := []string{"offset0", "offset1", "offset2", "offset3"}
+	if len(offsetTracker.committedOffsets) != len(expectedOffsets) {
+		t.Fatalf("Expected committed offsets %v, but got %v", expectedOffsets, offsetTracker.committedOffsets)
+	}
+	for i, expectedOffset := range expectedOffsets {
+		if offsetTracker.committedOffsets[i] != expectedOffset {
+			t.Errorf("Expected committed offsets %v, but got %v", expectedOffsets, offsetTracker.committedOffsets)
+		}
+	}
+}
+
+func newRunnerTestPipeline(t *testing.T, failingRecord int64) (*Pipeline, *committedOffsetsTracker) {
+	atomic.StoreInt64(&runnerTestWrittenRecords, 0)
+	atomic.StoreInt64(&runnerTestErrorRecords, 0)
+	atomic.StoreInt64(&runnerTestFailingRecord, failingRecord)
+
+	pipelineConfig := common.PipelineConfiguration{
+		PipelineId: "runnerPipeline",
+		Configuration: []common.Config{
+			{Name: creation.DeliveryGuarantee, Value: AtLeastOnce},
+			{Name: creation.MaxRunners, Value: float64(runnerTestMaxRunners)},
+			{Name: creation.ErrorRecordPolicy, Value: common.ErrorRecordPolicyStage},
+		},
+		Stages: []*common.StageConfiguration{
+			{
+				InstanceName: "origin",
+				Library:      runnerTestLibrary,
+				StageName:    runnerTestOriginName,
+				UiInfo:       map[string]interface{}{creation.STAGE_TYPE: creation.SOURCE},
+				OutputLanes:  []string{"originOutput"},
+			},
+			{
+				InstanceName: runnerTestDestinationStage,
+				Library:      runnerTestLibrary,
+				StageName:    runnerTestDestinationName,
+				UiInfo:       map[string]interface{}{creation.STAGE_TYPE: creation.TARGET},
+				InputLanes:   []string{"originOutput"},
+			},
+		},
+		ErrorStage: &common.StageConfiguration{
+			InstanceName: "errorStage",
+			Library:      runnerTestLibrary,
+			StageName:    runnerTestErrorStageName,
+			UiInfo:       map[string]interface{}{creation.STAGE_TYPE: creation.TARGET},
+		},
+	}
+
+	offsetTracker := &committedOffsetsTracker{}
+	p, issues := NewPipeline(
+		execution.Config{MaxBatchSize: 1},
+		pipelineConfig,
+		offsetTracker,
+		nil,
+		metrics.NewRegistry(),
+	)
+	if len(issues) > 0 {
+		t.Fatalf("Expected no issues creating the pipeline, but got %v", issues)
+	}
+	if issues := p.Init(); len(issues) > 0 {
+		t.Fatalf("Expected no issues initializing the pipeline, but got %v", issues)
+	}
+	return p, offsetTracker
+}
+
+func TestRunPipelineRunners(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestRunPipelineRunners")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	store.BaseDir = baseDir
+
+	p, offsetTracker := newRunnerTestPipeline(t, -1)
+	if p.errorStore, err = store.NewErrorStore(p.pipelineConf.PipelineId, 1024*1024, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.CaptureSnapshot("snapshot1", "", runnerTestSnapshotBatches); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if written := atomic.LoadInt64(&runnerTestWrittenRecords); written != runnerTestBatches/2 {
+		t.Errorf("Expected %d records written, but got %d", runnerTestBatches/2, written)
+	}
+	if errorRecords := atomic.LoadInt64(&runnerTestErrorRecords); errorRecords != runnerTestBatches/2 {
+		t.Errorf("Expected %d records written to the error stage, but got %d", runnerTestBatches/2, errorRecords)
+	}
+	if count := p.batchCountCounter.Count(); count != runnerTestBatches+1 {
+		t.Errorf("Expected %d batches, but got %d", runnerTestBatches+1, count)
+	}
+	if count := p.batchErrorRecordsCounter.Count(); count != runnerTestBatches/2 {
+		t.Errorf("Expected %d error records in the batch metrics, but got %d", runnerTestBatches/2, count)
+	}
+
+	if len(offsetTracker.committedOffsets) != runnerTestBatches {
+		t.Fatalf("Expected %d committed offsets, but got %d", runnerTestBatches, len(offsetTracker.committedOffsets))
+	}
+	for i, offset := range offsetTracker.committedOffsets {
+		if offset != strconv.Itoa(i+1) {
+			t.Fatalf("Expected offsets to be committed in order, but got %v", offsetTracker.committedOffsets)
+		}
+	}
+
+	errorRecords, err := p.errorStore.GetErrorRecords(store.ErrorQuery{StageInstanceName: runnerTestDestinationStage})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errorRecords) != runnerTestBatches/2 {
+		t.Errorf("Expected %d records in the error store, but got %d", runnerTestBatches/2, len(errorRecords))
+	}
+
+	snapshotsInfo, err := store.GetSnapshotsInfo(p.pipelineConf.PipelineId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshotsInfo) != 1 || snapshotsInfo[0].InProgress ||
+		snapshotsInfo[0].BatchCount != runnerTestSnapshotBatches {
+		t.Errorf("Expected a complete snapshot of %d batches, but got %v", runnerTestSnapshotBatches, snapshotsInfo)
+	}
+}
+
+func TestRunPipelineRunnersStopOnError(t *testing.T) {
+	const failingRecord = 50
+	p, offsetTracker := newRunnerTestPipeline(t, failingRecord)
+
+	// the runner failing the batch stops the pipeline while the origin is producing the next batches
+	if err := p.Run(); err == nil {
+		t.Fatal("Expected the failing batch to fail the pipeline")
+	}
+
+	if len(offsetTracker.committedOffsets) > failingRecord {
+		t.Fatalf("Expected no offsets committed after the failing batch, but got %v", offsetTracker.committedOffsets)
+	}
+	for i, offset := range offsetTracker.committedOffsets {
+		if offset != strconv.Itoa(i+1) {
+			t.Fatalf("Expected offsets to be committed in order, but got %v", offsetTracker.committedOffsets)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type destroyCountingStage struct {
+	*common.BaseStage
+	destroyCount int32
+}
+
+func (d *destroyCountingStage) Destroy() error {
+	atomic.AddInt32(&d.destroyCount, 1)
+	return nil
+}
+
+func newStopTestPipeline(stage *destroyCountingStage) *Pipeline {
+	return &Pipeline{
+		errorStageRuntime: StageRuntime{stageBean: creation.StageBean{Stage: stage}},
+		done:              make(chan struct{}),
+	}
+}
+
+func TestStopAndWaitDrainsBatch(t *testing.T) {
+	stage := &destroyCountingStage{BaseStage: &common.BaseStage{}}
+	p := newStopTestPipeline(stage)
+
+	// simulate the run loop finishing its batch in flight after the pipeline is stopped
+	go func() {
+		<-p.stopped()
+		time.Sleep(50 * time.Millisecond)
+		p.destroy()
+		close(p.done)
+	}()
+
+	if !p.StopAndWait(5 * time.Second) {
+		t.Fatal("Expected pipeline to drain before the timeout")
+	}
+	if atomic.LoadInt32(&stage.destroyCount) != 1 {
+		t.Errorf("Expected stage to be destroyed once, but was destroyed %d times", stage.destroyCount)
+	}
+}
+
+func TestStopAndWaitTimeout(t *testing.T) {
+	stage := &destroyCountingStage{BaseStage: &common.BaseStage{}}
+	p := newStopTestPipeline(stage)
+
+	if p.StopAndWait(10 * time.Millisecond) {
+		t.Fatal("Expected pipeline not to drain before the timeout")
+	}
+	if atomic.LoadInt32(&stage.destroyCount) != 1 {
+		t.Errorf("Expected stage to be destroyed on timeout, but was destroyed %d times", stage.destroyCount)
+	}
+
+	// the run loop ending late must not destroy the stages again
+	p.destroy()
+	if atomic.LoadInt32(&stage.destroyCount) != 1 {
+		t.Errorf("Expected stage to be destroyed once, but was destroyed %d times", stage.destroyCount)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cast"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	"github.com/streamsets/datacollector-edge/container/el"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"strings"
+	"time"
+)
+
+const triggerRuntimeParametersConfig = "runtimeParameters"
+
+// StartPipelineFunc starts a pipeline triggered by the pipelines in the trigger chain, the last one being the
+// pipeline that triggered it.
+type StartPipelineFunc func(
+	pipelineId string,
+	runtimeParameters map[string]interface{},
+	triggerChain []string,
+) (*common.PipelineState, error)
+
+// PipelineTrigger starts the downstream pipelines configured in a pipeline when the pipeline moves into one of
+// their trigger states. A pipeline already in the trigger chain is not started again, so a cycle in the pipeline
+// dependencies ends the chain. Triggered pipelines are recorded in the history of both pipelines.
+type PipelineTrigger struct {
+	pipelineId    string
+	startPipeline StartPipelineFunc
+}
+
+func (t *PipelineTrigger) Trigger(
+	pipelineConfig common.PipelineConfiguration,
+	runtimeParameters map[string]interface{},
+	pipelineState common.PipelineState,
+) {
+	if t.startPipeline == nil {
+		return
+	}
+	pipelineConfigBean := creation.NewPipelineConfigBean(pipelineConfig)
+	triggerConfigs := make([]creation.PipelineTriggerConfig, 0)
+	for _, triggerConfig := range pipelineConfigBean.TriggerPipelines {
+		if util.Contains(triggerConfig.OnStates, pipelineState.Status) {
+			triggerConfigs = append(triggerConfigs, triggerConfig)
+		}
+	}
+	if len(triggerConfigs) == 0 {
+		return
+	}
+
+	triggerChain := append(cast.ToStringSlice(pipelineState.Attributes[store.TRIGGER_CHAIN]), t.pipelineId)
+
+	parameters := make(map[string]interface{})
+	for key, value := range pipelineConfigBean.Constants {
+		parameters[key] = value
+	}
+	for key, value := range runtimeParameters {
+		parameters[key] = value
+	}
+
+	sourceOffset, err := store.GetOffset(t.pipelineId)
+	if err != nil {
+		log.WithError(err).WithField("id", t.pipelineId).Warn("Failed to read the offset for pipeline triggers")
+	}
+	elContext := context.WithValue(context.Background(), el.PipelineElContextVar, map[string]interface{}{
+		el.PipelineIdContextVar:      pipelineConfig.PipelineId,
+		el.PipelineTitleContextVar:   pipelineConfig.Title,
+		el.PipelineUserContextVar:    pipelineConfig.Info.LastModifier,
+		el.PipelineStateContextVar:   pipelineState.Status,
+		el.PipelineMessageContextVar: pipelineState.Message,
+		el.PipelineOffsetContextVar:  getOffsetValue(sourceOffset),
+	})
+
+	for _, triggerConfig := range triggerConfigs {
+		downstreamChain := append(append([]string{}, triggerChain...), triggerConfig.PipelineId)
+		if util.Contains(triggerChain, triggerConfig.PipelineId) {
+			message := fmt.Sprintf(
+				"Pipeline '%s' not triggered, trigger chain %s is a cycle",
+				triggerConfig.PipelineId,
+				strings.Join(downstreamChain, " -> "),
+			)
+			log.WithField("id", t.pipelineId).Error(message)
+			t.addHistory(pipelineState, triggerConfig.PipelineId, downstreamChain, message)
+			continue
+		}
+
+		triggerParameters, err := evaluateTriggerParameters(triggerConfig, parameters, elContext)
+		if err == nil {
+			_, err = t.startPipeline(triggerConfig.PipelineId, triggerParameters, triggerChain)
+		}
+		if err != nil {
+			log.WithError(err).
+				WithField("id", t.pipelineId).
+				WithField("triggeredPipeline", triggerConfig.PipelineId).
+				Error("Failed to trigger pipeline")
+			message := fmt.Sprintf("Failed to trigger pipeline '%s': %s", triggerConfig.PipelineId, err)
+			t.addHistory(pipelineState, triggerConfig.PipelineId, downstreamChain, message)
+			continue
+		}
+
+		log.WithField("id", t.pipelineId).WithField("triggeredPipeline", triggerConfig.PipelineId).
+			Info("Triggered pipeline")
+		message := fmt.Sprintf("Triggered pipeline '%s'", triggerConfig.PipelineId)
+		t.addHistory(pipelineState, triggerConfig.PipelineId, downstreamChain, message)
+	}
+}
+
+func (t *PipelineTrigger) addHistory(
+	pipelineState common.PipelineState,
+	triggeredPipelineId string,
+	triggerChain []string,
+	message string,
+) {
+	historyEntry := &common.PipelineState{
+		PipelineId: t.pipelineId,
+		Status:     pipelineState.Status,
+		Message:    message,
+		TimeStamp:  util.ConvertTimeToLong(time.Now()),
+		Attributes: map[string]interface{}{
+			store.TRIGGERED_PIPELINE: triggeredPipelineId,
+			store.TRIGGER_CHAIN:      triggerChain,
+		},
+	}
+	if err := store.AddHistory(t.pipelineId, historyEntry); err != nil {
+		log.WithError(err).WithField("id", t.pipelineId).Error("Failed to record pipeline trigger in history")
+	}
+}
+
+func evaluateTriggerParameters(
+	triggerConfig creation.PipelineTriggerConfig,
+	parameters map[string]interface{},
+	elContext context.Context,
+) (map[string]interface{}, error) {
+	evaluator, _ := el.NewEvaluator(
+		triggerRuntimeParametersConfig,
+		parameters,
+		[]el.Definitions{
+			&el.StringEL{},
+			&el.MathEL{},
+			&el.PipelineEL{Context: elContext},
+			&el.SdcEL{},
+		},
+	)
+	triggerParameters := make(map[string]interface{})
+	for key, template := range triggerConfig.RuntimeParameters {
+		value, err := evaluator.EvaluateTemplate(template)
+		if err != nil {
+			return nil, err
+		}
+		triggerParameters[key] = value
+	}
+	return triggerParameters, nil
+}
+
+// getOffsetValue returns the offset of a pull origin, or the offsets of a multi offset origin as JSON.
+func getOffsetValue(sourceOffset common.SourceOffset) string {
+	if len(sourceOffset.Offset) == 1 {
+		if offset, ok := sourceOffset.Offset[common.PollSourceOffsetKey]; ok {
+			if offset == nil {
+				return ""
+			}
+			return *offset
+		}
+	}
+	offsetJson, _ := json.Marshal(sourceOffset.Offset)
+	return string(offsetJson)
+}
+
+func NewPipelineTrigger(pipelineId string, startPipeline StartPipelineFunc) *PipelineTrigger {
+	return &PipelineTrigger{
+		pipelineId:    pipelineId,
+		startPipeline: startPipeline,
+	}
+}
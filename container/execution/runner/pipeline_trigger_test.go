@@ -0,0 +1,146 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"errors"
+	"github.com/spf13/cast"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestPipelineTrigger(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestPipelineTrigger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	store.BaseDir = baseDir
+
+	offset := "file2.log::0"
+	if err = store.SaveOffset("pipelineA", common.SourceOffset{
+		Version: common.CurrentOffsetVersion,
+		Offset:  map[string]*string{common.PollSourceOffsetKey: &offset},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	pipelineConfig := common.PipelineConfiguration{
+		PipelineId: "pipelineA",
+		Configuration: []common.Config{
+			{Name: creation.Constants, Value: []interface{}{
+				map[string]interface{}{"key": "TARGET_DIR", "value": "/tmp/out"},
+			}},
+			{Name: creation.TriggerPipelines, Value: []interface{}{
+				map[string]interface{}{
+					"pipelineId": "pipelineB",
+					"runtimeParameters": []interface{}{
+						map[string]interface{}{"key": "inputDir", "value": "${TARGET_DIR}/${batch}"},
+					},
+				},
+				map[string]interface{}{
+					"pipelineId": "pipelineC",
+					"onStates":   []interface{}{common.RUN_ERROR},
+				},
+				map[string]interface{}{
+					"pipelineId": "pipelineRoot",
+					"onStates":   []interface{}{common.FINISHED},
+				},
+			}},
+		},
+	}
+
+	startedPipelines := make(map[string][]string)
+	startedParameters := make(map[string]map[string]interface{})
+	pipelineTrigger := NewPipelineTrigger(
+		"pipelineA",
+		func(
+			pipelineId string,
+			runtimeParameters map[string]interface{},
+			triggerChain []string,
+		) (*common.PipelineState, error) {
+			if pipelineId == "pipelineC" {
+				return nil, errors.New("pipeline is running")
+			}
+			startedPipelines[pipelineId] = triggerChain
+			startedParameters[pipelineId] = runtimeParameters
+			return &common.PipelineState{PipelineId: pipelineId, Status: common.RUNNING}, nil
+		},
+	)
+
+	// state without triggers
+	pipelineTrigger.Trigger(pipelineConfig, nil, common.PipelineState{Status: common.STOPPED})
+	if len(startedPipelines) != 0 {
+		t.Errorf("Expected no pipeline to be triggered but got %v", startedPipelines)
+	}
+
+	pipelineTrigger.Trigger(
+		pipelineConfig,
+		map[string]interface{}{"batch": "42"},
+		common.PipelineState{
+			Status:     common.FINISHED,
+			Attributes: map[string]interface{}{store.TRIGGER_CHAIN: []interface{}{"pipelineRoot"}},
+		},
+	)
+	if len(startedPipelines) != 1 {
+		t.Fatalf("Expected only pipelineB to be triggered but got %v", startedPipelines)
+	}
+	triggerChain := startedPipelines["pipelineB"]
+	if len(triggerChain) != 2 || triggerChain[0] != "pipelineRoot" || triggerChain[1] != "pipelineA" {
+		t.Errorf("Unexpected trigger chain: %v", triggerChain)
+	}
+	if startedParameters["pipelineB"]["inputDir"] != "/tmp/out/42" {
+		t.Errorf("Unexpected runtime parameters: %v", startedParameters["pipelineB"])
+	}
+
+	pipelineTrigger.Trigger(pipelineConfig, nil, common.PipelineState{Status: common.RUN_ERROR})
+
+	history, err := store.GetHistory("pipelineA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 history entries but got %d", len(history))
+	}
+	expectedTriggers := []string{"pipelineB", "pipelineRoot", "pipelineC"}
+	for i, pipelineState := range history {
+		if pipelineState.Attributes[store.TRIGGERED_PIPELINE] != expectedTriggers[i] {
+			t.Errorf("Expected history entry for %s but got %v", expectedTriggers[i], pipelineState)
+		}
+	}
+	if cast.ToStringSlice(history[1].Attributes[store.TRIGGER_CHAIN])[2] != "pipelineRoot" {
+		t.Errorf("Expected the cycle to be recorded but got %v", history[1])
+	}
+	if history[2].Status != common.RUN_ERROR {
+		t.Errorf("Expected status %s but got %s", common.RUN_ERROR, history[2].Status)
+	}
+}
+
+func TestGetOffsetValue(t *testing.T) {
+	offset := "file1.log::100"
+	if value := getOffsetValue(common.SourceOffset{
+		Offset: map[string]*string{common.PollSourceOffsetKey: &offset},
+	}); value != offset {
+		t.Errorf("Expected offset %s but got %s", offset, value)
+	}
+
+	if value := getOffsetValue(common.SourceOffset{
+		Offset: map[string]*string{"partition1": &offset},
+	}); value != `{"partition1":"file1.log::100"}` {
+		t.Errorf("Unexpected offsets: %s", value)
+	}
+}
@@ -18,6 +18,8 @@ import (
 	"github.com/streamsets/datacollector-edge/api/validation"
 	"github.com/streamsets/datacollector-edge/container/common"
 	"github.com/streamsets/datacollector-edge/container/execution"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"time"
 )
 
 const (
@@ -28,6 +30,7 @@ type ProductionPipeline struct {
 	PipelineConfig common.PipelineConfiguration
 	Pipeline       *Pipeline
 	MetricRegistry metrics.Registry
+	OffsetTracker  *ProductionSourceOffsetTracker
 }
 
 func (p *ProductionPipeline) Init() []validation.Issue {
@@ -40,9 +43,9 @@ func (p *ProductionPipeline) Init() []validation.Issue {
 	return issues
 }
 
-func (p *ProductionPipeline) Run() {
+func (p *ProductionPipeline) Run() error {
 	log.Debug("Production Pipeline Run")
-	p.Pipeline.Run()
+	return p.Pipeline.Run()
 }
 
 func (p *ProductionPipeline) Stop() {
@@ -50,12 +53,21 @@ func (p *ProductionPipeline) Stop() {
 	p.Pipeline.Stop()
 }
 
+// StopAndWait stops the pipeline, see Pipeline.StopAndWait.
+func (p *ProductionPipeline) StopAndWait(drainTimeout time.Duration) bool {
+	log.Debug("Production Pipeline Stop and Wait")
+	return p.Pipeline.StopAndWait(drainTimeout)
+}
+
 func NewProductionPipeline(
 	pipelineId string,
 	config execution.Config,
 	runner execution.Runner,
 	pipelineConfiguration common.PipelineConfiguration,
 	runtimeParameters map[string]interface{},
+	errorStore *store.ErrorStore,
+	alertManager *AlertManager,
+	ruleDefinitions common.RuleDefinitions,
 ) (*ProductionPipeline, []validation.Issue) {
 	if sourceOffsetTracker, err := NewProductionSourceOffsetTracker(pipelineId); err == nil {
 		metricRegistry := metrics.NewRegistry()
@@ -66,10 +78,25 @@ func NewProductionPipeline(
 			runtimeParameters,
 			metricRegistry,
 		)
+		if pipeline != nil {
+			pipeline.errorStore = errorStore
+			if pipeline.memoryMonitor != nil {
+				pipeline.memoryMonitor.alertManager = alertManager
+			}
+			if alertManager != nil && len(pipeline.pipes) > 0 {
+				pipeline.rulesEvaluator = NewRulesEvaluator(
+					ruleDefinitions,
+					alertManager,
+					metricRegistry,
+					pipeline.pipes[0].GetStageContext(),
+				)
+			}
+		}
 		return &ProductionPipeline{
 			PipelineConfig: pipelineConfiguration,
 			Pipeline:       pipeline,
 			MetricRegistry: metricRegistry,
+			OffsetTracker:  sourceOffsetTracker,
 		}, issues
 	} else {
 		issues := make([]validation.Issue, 0)
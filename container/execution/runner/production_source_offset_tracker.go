@@ -15,6 +15,7 @@ package runner
 import (
 	"github.com/streamsets/datacollector-edge/container/common"
 	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"sync"
 	"time"
 )
 
@@ -24,6 +25,9 @@ type ProductionSourceOffsetTracker struct {
 	newOffset     *string
 	finished      bool
 	lastBatchTime time.Time
+	deferCommit   bool
+	uncommitted   bool
+	mutex         sync.Mutex
 }
 
 var emptyOffset = ""
@@ -36,13 +40,45 @@ func (o *ProductionSourceOffsetTracker) SetOffset(newOffset *string) {
 	o.newOffset = newOffset
 }
 
+// CommitOffset saves the offset of the batch. The offset that finishes the pipeline and the offsets committed while
+// the pipeline is being stopped are kept for the runner, which saves them together with the FINISHED or STOPPED
+// state, see TakeUncommittedOffset.
 func (o *ProductionSourceOffsetTracker) CommitOffset() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
 	o.currentOffset.Offset[common.PollSourceOffsetKey] = o.newOffset
 	o.finished = o.currentOffset.Offset[common.PollSourceOffsetKey] == nil
 	o.newOffset = &emptyOffset
+	if o.finished || o.deferCommit {
+		o.uncommitted = true
+		return nil
+	}
 	return store.SaveOffset(o.pipelineId, o.currentOffset)
 }
 
+// DeferCommit keeps the offsets committed from now on for the runner to save them with the STOPPED state.
+func (o *ProductionSourceOffsetTracker) DeferCommit() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.deferCommit = true
+}
+
+// TakeUncommittedOffset returns a copy of the offset committed by the pipeline but not saved yet, if any. The
+// caller is responsible for saving it.
+func (o *ProductionSourceOffsetTracker) TakeUncommittedOffset() (common.SourceOffset, bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if !o.uncommitted {
+		return common.SourceOffset{}, false
+	}
+	o.uncommitted = false
+	sourceOffset := common.SourceOffset{Version: o.currentOffset.Version, Offset: make(map[string]*string)}
+	for key, value := range o.currentOffset.Offset {
+		sourceOffset.Offset[key] = value
+	}
+	return sourceOffset, true
+}
+
 func (o *ProductionSourceOffsetTracker) GetOffset() *string {
 	return o.currentOffset.Offset[common.PollSourceOffsetKey]
 }
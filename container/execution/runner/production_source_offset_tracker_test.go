@@ -0,0 +1,81 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"testing"
+)
+
+type offsetRecordingStore struct {
+	store.RuntimeStore
+	savedOffsets []*string
+}
+
+func (s *offsetRecordingStore) SaveOffset(pipelineId string, sourceOffset common.SourceOffset) error {
+	s.savedOffsets = append(s.savedOffsets, sourceOffset.Offset[common.PollSourceOffsetKey])
+	return nil
+}
+
+func TestProductionSourceOffsetTracker_UncommittedOffset(t *testing.T) {
+	runtimeStore := store.GetRuntimeStore()
+	defer store.SetRuntimeStore(runtimeStore)
+	recordingStore := &offsetRecordingStore{}
+	store.SetRuntimeStore(recordingStore)
+
+	offsetTracker := &ProductionSourceOffsetTracker{pipelineId: "test", currentOffset: common.GetDefaultOffset()}
+	offset1 := "offset1"
+	offsetTracker.SetOffset(&offset1)
+	if err := offsetTracker.CommitOffset(); err != nil {
+		t.Fatal(err)
+	}
+	if len(recordingStore.savedOffsets) != 1 {
+		t.Fatalf("Expected offset to be saved, but got %d saved offsets", len(recordingStore.savedOffsets))
+	}
+	if _, ok := offsetTracker.TakeUncommittedOffset(); ok {
+		t.Error("Expected no uncommitted offset")
+	}
+
+	// the offset that finishes the pipeline is saved by the runner with the FINISHED state
+	offsetTracker.SetOffset(nil)
+	if err := offsetTracker.CommitOffset(); err != nil {
+		t.Fatal(err)
+	}
+	if !offsetTracker.IsFinished() || len(recordingStore.savedOffsets) != 1 {
+		t.Errorf("Expected finished offset not to be saved, but got %d saved offsets", len(recordingStore.savedOffsets))
+	}
+	sourceOffset, ok := offsetTracker.TakeUncommittedOffset()
+	if !ok || sourceOffset.Offset[common.PollSourceOffsetKey] != nil {
+		t.Errorf("Expected finished offset to be uncommitted, but got: %v", sourceOffset.Offset)
+	}
+
+	// offsets committed while the pipeline is stopped are saved by the runner with the STOPPED state
+	offsetTracker = &ProductionSourceOffsetTracker{pipelineId: "test", currentOffset: common.GetDefaultOffset()}
+	offsetTracker.DeferCommit()
+	offset2 := "offset2"
+	offsetTracker.SetOffset(&offset2)
+	if err := offsetTracker.CommitOffset(); err != nil {
+		t.Fatal(err)
+	}
+	if len(recordingStore.savedOffsets) != 1 {
+		t.Errorf("Expected deferred offset not to be saved, but got %d saved offsets", len(recordingStore.savedOffsets))
+	}
+	sourceOffset, ok = offsetTracker.TakeUncommittedOffset()
+	if !ok || *sourceOffset.Offset[common.PollSourceOffsetKey] != offset2 {
+		t.Errorf("Expected deferred offset to be uncommitted, but got: %v", sourceOffset.Offset)
+	}
+	if _, ok = offsetTracker.TakeUncommittedOffset(); ok {
+		t.Error("Expected uncommitted offset to be taken once")
+	}
+}
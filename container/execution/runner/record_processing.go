@@ -0,0 +1,94 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"github.com/streamsets/datacollector-edge/api"
+	"sync"
+)
+
+// recordOutput buffers the records added by a record processor for a single input record, so that the output of
+// records processed in parallel can be added to the batch in the order of the input records.
+type recordOutput struct {
+	lanes       []string
+	records     []api.Record
+	outputLanes [][]string
+}
+
+func (r *recordOutput) GetLanes() []string {
+	return r.lanes
+}
+
+func (r *recordOutput) AddRecord(record api.Record, outputLanes ...string) {
+	// the processor may keep on changing the record after adding it
+	r.records = append(r.records, record.Clone())
+	r.outputLanes = append(r.outputLanes, outputLanes)
+}
+
+// processRecordsInParallel calls the record processor for the records of the batch on a pool of workers. Once all
+// records are processed their output records are added to the batch maker and the failed records are sent to error,
+// both in the order of the input records.
+func (s *StageRuntime) processRecordsInParallel(
+	recordProcessor api.RecordProcessor,
+	batch api.Batch,
+	batchMaker api.BatchMaker,
+) error {
+	records := batch.GetRecords()
+	recordOutputs := make([]recordOutput, len(records))
+	recordErrors := make([]error, len(records))
+	recordIndexes := make(chan int)
+
+	var panicError error
+	var panicMutex sync.Mutex
+	var waitGroup sync.WaitGroup
+	for i := 0; i < s.recordParallelism && i < len(records); i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					panicMutex.Lock()
+					panicError = s.newPanicError(r)
+					panicMutex.Unlock()
+					// keep on draining so that the batch is not blocked
+					for range recordIndexes {
+					}
+				}
+			}()
+			for index := range recordIndexes {
+				recordOutputs[index].lanes = batchMaker.GetLanes()
+				recordErrors[index] = recordProcessor.ProcessRecord(records[index], &recordOutputs[index])
+			}
+		}()
+	}
+
+	for index := range records {
+		recordIndexes <- index
+	}
+	close(recordIndexes)
+	waitGroup.Wait()
+
+	if panicError != nil {
+		return panicError
+	}
+
+	for index, record := range records {
+		for i, outputRecord := range recordOutputs[index].records {
+			batchMaker.AddRecord(outputRecord, recordOutputs[index].outputLanes[i]...)
+		}
+		if recordErrors[index] != nil {
+			s.stageContext.ToError(recordErrors[index], record)
+		}
+	}
+	return nil
+}
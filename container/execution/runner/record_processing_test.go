@@ -0,0 +1,119 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"errors"
+	"github.com/spf13/cast"
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type laneRoutingProcessor struct {
+	*common.BaseStage
+	concurrentCalls    int32
+	maxConcurrentCalls int32
+}
+
+func (p *laneRoutingProcessor) Process(batch api.Batch, batchMaker api.BatchMaker) error {
+	return errors.New("expected records to be processed in parallel")
+}
+
+func (p *laneRoutingProcessor) ProcessRecord(record api.Record, batchMaker api.BatchMaker) error {
+	concurrentCalls := atomic.AddInt32(&p.concurrentCalls, 1)
+	defer atomic.AddInt32(&p.concurrentCalls, -1)
+	for {
+		maxConcurrentCalls := atomic.LoadInt32(&p.maxConcurrentCalls)
+		if concurrentCalls <= maxConcurrentCalls ||
+			atomic.CompareAndSwapInt32(&p.maxConcurrentCalls, maxConcurrentCalls, concurrentCalls) {
+			break
+		}
+	}
+
+	rootField, _ := record.Get()
+	value := cast.ToInt(rootField.Value)
+	// later records finish first
+	time.Sleep(time.Duration(10-value) * time.Millisecond)
+	if value == 5 {
+		return errors.New("invalid record")
+	}
+	if value%2 == 0 {
+		batchMaker.AddRecord(record, "even")
+	} else {
+		batchMaker.AddRecord(record, "odd")
+	}
+	return nil
+}
+
+func (p *laneRoutingProcessor) IsRecordParallel() bool {
+	return true
+}
+
+func TestProcessRecordsInParallel(t *testing.T) {
+	stageConfig := &common.StageConfiguration{
+		InstanceName: "processor1",
+		OutputLanes:  []string{"even", "odd"},
+		UiInfo:       map[string]interface{}{creation.STAGE_TYPE: creation.PROCESSOR},
+	}
+	stageContext := &common.StageContextImpl{
+		StageConfig:       stageConfig,
+		ErrorSink:         common.NewErrorSink(),
+		ErrorRecordPolicy: common.ErrorRecordPolicyStage,
+	}
+	processor := &laneRoutingProcessor{BaseStage: &common.BaseStage{}}
+	stageRuntime := NewStageRuntime(creation.PipelineBean{}, creation.StageBean{
+		Config:        stageConfig,
+		Stage:         processor,
+		SystemConfigs: creation.StageConfigBean{StageRecordParallelism: 4},
+	}, stageContext)
+
+	records := make([]api.Record, 0)
+	for i := 0; i < 10; i++ {
+		record, _ := stageContext.CreateRecord("record", i)
+		records = append(records, record)
+	}
+	batchMaker := NewBatchMakerImpl(StagePipe{Stage: stageRuntime, OutputLanes: stageConfig.OutputLanes}, false)
+
+	if _, err := stageRuntime.Execute(nil, 10, NewBatchImpl("processor1", records, nil), batchMaker); err != nil {
+		t.Fatal(err)
+	}
+
+	if processor.maxConcurrentCalls < 2 || processor.maxConcurrentCalls > 4 {
+		t.Errorf("Expected between 2 and 4 concurrent calls, but got %d", processor.maxConcurrentCalls)
+	}
+
+	checkLane := func(lane string, expectedValues []int) {
+		laneRecords := batchMaker.GetStageOutput(lane)
+		if len(laneRecords) != len(expectedValues) {
+			t.Fatalf("Expected %d records in lane '%s', but got %d", len(expectedValues), lane, len(laneRecords))
+		}
+		for i, laneRecord := range laneRecords {
+			rootField, _ := laneRecord.Get()
+			if cast.ToInt(rootField.Value) != expectedValues[i] {
+				t.Errorf("Expected value %d at position %d of lane '%s', but got %v",
+					expectedValues[i], i, lane, rootField.Value)
+			}
+		}
+	}
+	checkLane("even", []int{0, 2, 4, 6, 8})
+	checkLane("odd", []int{1, 3, 7, 9})
+
+	errorRecords := stageContext.ErrorSink.GetStageErrorRecords("processor1")
+	if len(errorRecords) != 1 {
+		t.Fatalf("Expected 1 error record, but got %d", len(errorRecords))
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rcrowley/go-metrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/el"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	PipelineRateLimit           = "pipeline.rateLimit"
+	PipelineThrottled           = "pipeline.throttled"
+	PipelineThrottledTime       = "pipeline.throttledTime"
+	PipelineMemoryConsumed      = "pipeline.memoryConsumed"
+	PipelineMemoryLimit         = "pipeline.memoryLimit"
+	PipelineMemoryLimitExceeded = "pipeline.memoryLimitExceeded"
+
+	MemoryLimitExceededLog          = "LOG"
+	MemoryLimitExceededAlert        = "ALERT"
+	MemoryLimitExceededStopPipeline = "STOP_PIPELINE"
+	MemoryLimitAlertId              = "memoryLimitExceededAlert"
+
+	memoryCheckInterval = time.Second
+)
+
+// rateLimiter limits the number of records the origin produces per second across batches. A batch is allowed to
+// start right away and the records it produced delay the start of the next batch.
+type rateLimiter struct {
+	recordsPerSecond float64
+	nextFreeTime     time.Time
+	mutex            sync.Mutex
+}
+
+func newRateLimiter(recordsPerSecond float64) *rateLimiter {
+	if recordsPerSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{recordsPerSecond: recordsPerSecond}
+}
+
+// acquire accounts for the records produced by the batch started at the given time.
+func (r *rateLimiter) acquire(start time.Time, records int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.nextFreeTime.Before(start) {
+		r.nextFreeTime = start
+	}
+	r.nextFreeTime = r.nextFreeTime.Add(time.Duration(float64(records) / r.recordsPerSecond * float64(time.Second)))
+}
+
+// delay returns how long to wait before the next batch may start.
+func (r *rateLimiter) delay() time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return time.Until(r.nextFreeTime)
+}
+
+// memoryMonitor compares the heap allocated by the whole edge process with the memory limit of the pipeline, at most
+// once a second, and applies the configured action when it is exceeded. The Go runtime doesn't account memory per
+// goroutine, so the limit bounds the process heap while the pipeline runs rather than the memory of the pipeline
+// alone, the pipeline.memoryConsumed gauge keeps its Data Collector name but reports the process heap as well.
+type memoryMonitor struct {
+	pipelineId   string
+	limit        int64
+	action       string
+	alertManager *AlertManager
+	consumed     int64
+	exceeded     int64
+	lastCheck    time.Time
+	readHeap     func() int64
+}
+
+// newMemoryMonitor returns nil if the memory limit is not a number of megabytes. The default limit of pipelines
+// created in Data Collector is an expression on the JVM heap size, which doesn't apply to the edge.
+func newMemoryMonitor(pipelineId string, memoryLimit string, action string) *memoryMonitor {
+	memoryLimit = strings.TrimSpace(memoryLimit)
+	if memoryLimit == "" || el.IsElString(memoryLimit) {
+		return nil
+	}
+	limitMB, err := strconv.ParseFloat(memoryLimit, 64)
+	if err != nil || limitMB <= 0 {
+		log.WithField("memoryLimit", memoryLimit).Warn("Ignoring invalid pipeline memory limit")
+		return nil
+	}
+	return &memoryMonitor{
+		pipelineId: pipelineId,
+		limit:      int64(limitMB * 1024 * 1024),
+		action:     action,
+		readHeap:   readProcessHeapAlloc,
+	}
+}
+
+// check returns an error if the memory limit is exceeded and the pipeline is to be stopped.
+func (m *memoryMonitor) check() error {
+	if time.Since(m.lastCheck) < memoryCheckInterval {
+		return nil
+	}
+	m.lastCheck = time.Now()
+
+	consumed := m.readHeap()
+	atomic.StoreInt64(&m.consumed, consumed)
+	if consumed <= m.limit {
+		atomic.StoreInt64(&m.exceeded, 0)
+		return nil
+	}
+	wasExceeded := atomic.SwapInt64(&m.exceeded, 1) == 1
+
+	message := fmt.Sprintf(
+		"Process heap allocation %d MB exceeds the pipeline memory limit of %d MB",
+		consumed/1024/1024,
+		m.limit/1024/1024,
+	)
+	switch m.action {
+	case MemoryLimitExceededStopPipeline:
+		return errors.New(message)
+	case MemoryLimitExceededAlert:
+		if m.alertManager != nil {
+			m.alertManager.Alert(MemoryLimitAlertId, common.MetricsRuleDefinition{
+				Id:            MemoryLimitAlertId,
+				AlertText:     message,
+				MetricId:      PipelineMemoryConsumed + util.GAUGE_SUFFIX,
+				MetricType:    "GAUGE",
+				MetricElement: "CURRENT_VALUE",
+				Enabled:       true,
+				Valid:         true,
+			}, message, consumed)
+			break
+		}
+		fallthrough
+	default:
+		if !wasExceeded {
+			log.WithField("id", m.pipelineId).Warn(message)
+		}
+	}
+	return nil
+}
+
+// readProcessHeapAlloc returns the bytes of heap allocated by the whole process.
+func readProcessHeapAlloc() int64 {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return int64(memStats.HeapAlloc)
+}
+
+// initResourceLimits sets up the rate and memory limits of the pipeline and the metrics exposing their state.
+func (p *Pipeline) initResourceLimits(metricRegistry metrics.Registry) {
+	p.rateLimiter = newRateLimiter(p.pipelineBean.Config.RateLimit)
+	p.memoryMonitor = newMemoryMonitor(
+		p.pipelineConf.PipelineId,
+		p.pipelineBean.Config.MemoryLimit,
+		p.pipelineBean.Config.MemoryLimitExceeded,
+	)
+
+	p.throttledTimeCounter = util.CreateCounter(metricRegistry, PipelineThrottledTime)
+	util.CreateFunctionalGauge(metricRegistry, PipelineRateLimit, func() int64 {
+		return int64(p.pipelineBean.Config.RateLimit)
+	})
+	util.CreateFunctionalGauge(metricRegistry, PipelineThrottled, func() int64 {
+		return atomic.LoadInt64(&p.throttled)
+	})
+	util.CreateFunctionalGauge(metricRegistry, PipelineMemoryConsumed, func() int64 {
+		if p.memoryMonitor == nil {
+			return readProcessHeapAlloc()
+		}
+		return atomic.LoadInt64(&p.memoryMonitor.consumed)
+	})
+	util.CreateFunctionalGauge(metricRegistry, PipelineMemoryLimit, func() int64 {
+		if p.memoryMonitor == nil {
+			return 0
+		}
+		return p.memoryMonitor.limit
+	})
+	util.CreateFunctionalGauge(metricRegistry, PipelineMemoryLimitExceeded, func() int64 {
+		if p.memoryMonitor == nil {
+			return 0
+		}
+		return atomic.LoadInt64(&p.memoryMonitor.exceeded)
+	})
+}
+
+// throttle waits until the rate limit allows the next batch to start, or the pipeline is stopped.
+func (p *Pipeline) throttle() {
+	if p.rateLimiter == nil {
+		return
+	}
+	delay := p.rateLimiter.delay()
+	if delay <= 0 {
+		return
+	}
+
+	start := time.Now()
+	atomic.StoreInt64(&p.throttled, 1)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for delay > 0 {
+		select {
+		case <-p.stopped():
+			delay = 0
+		case <-timer.C:
+			if delay = p.rateLimiter.delay(); delay > 0 {
+				timer.Reset(delay)
+			}
+		}
+	}
+	atomic.StoreInt64(&p.throttled, 0)
+	p.throttledTimeCounter.Inc(int64(time.Since(start) / time.Millisecond))
+}
+
+// applyResourceLimits accounts for the records produced by the origin in the batch started at the given time and
+// checks the memory limit. An error is returned if the pipeline has to be stopped.
+func (p *Pipeline) applyResourceLimits(start time.Time, inputRecords int64) error {
+	if p.rateLimiter != nil {
+		p.rateLimiter.acquire(start, inputRecords)
+	}
+	if p.memoryMonitor != nil {
+		return p.memoryMonitor.check()
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"github.com/rcrowley/go-metrics"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	if newRateLimiter(0) != nil {
+		t.Error("Expected no rate limiter without a rate limit")
+	}
+
+	limiter := newRateLimiter(100)
+	start := time.Now()
+	if limiter.delay() > 0 {
+		t.Error("Expected the first batch to start right away")
+	}
+
+	limiter.acquire(start, 50)
+	limiter.acquire(start, 50)
+	delay := limiter.delay()
+	if delay <= 900*time.Millisecond || delay > time.Second {
+		t.Errorf("Expected delay of about a second for 100 records, but got %v", delay)
+	}
+
+	// time spent before the next batch starts counts against the delay
+	limiter = newRateLimiter(100)
+	limiter.acquire(start.Add(-2*time.Second), 100)
+	if limiter.delay() > 0 {
+		t.Errorf("Expected no delay, but got %v", limiter.delay())
+	}
+}
+
+func TestThrottleStopsWithPipeline(t *testing.T) {
+	p := &Pipeline{
+		rateLimiter:          newRateLimiter(1),
+		throttledTimeCounter: metrics.NewCounter(),
+	}
+	p.rateLimiter.acquire(time.Now(), 100)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		p.Stop()
+	}()
+
+	start := time.Now()
+	p.throttle()
+	if time.Since(start) > time.Second {
+		t.Error("Expected throttling to end when the pipeline is stopped")
+	}
+	if atomic.LoadInt64(&p.throttled) != 0 {
+		t.Error("Expected pipeline not to be throttled anymore")
+	}
+	if p.throttledTimeCounter.Count() < 50 {
+		t.Errorf("Expected throttled time of at least 50 ms, but got %d", p.throttledTimeCounter.Count())
+	}
+}
+
+func TestMemoryMonitor(t *testing.T) {
+	if newMemoryMonitor("test", "${jvm:maxMemoryMB() * 0.65}", MemoryLimitExceededStopPipeline) != nil {
+		t.Error("Expected expression memory limit to be ignored")
+	}
+	if newMemoryMonitor("test", "abc", MemoryLimitExceededStopPipeline) != nil {
+		t.Error("Expected invalid memory limit to be ignored")
+	}
+
+	heap := int64(0)
+	newMonitor := func(action string) *memoryMonitor {
+		monitor := newMemoryMonitor("test", "1", action)
+		monitor.readHeap = func() int64 {
+			return heap
+		}
+		return monitor
+	}
+
+	monitor := newMonitor(MemoryLimitExceededStopPipeline)
+	if monitor.limit != 1024*1024 {
+		t.Errorf("Expected limit of 1 MB, but got %d", monitor.limit)
+	}
+	heap = 512 * 1024
+	if err := monitor.check(); err != nil || monitor.exceeded != 0 {
+		t.Errorf("Expected memory limit not to be exceeded: %v", err)
+	}
+	heap = 2 * 1024 * 1024
+	if err := monitor.check(); err != nil {
+		t.Error("Expected memory to be checked at most once a second")
+	}
+	monitor.lastCheck = time.Time{}
+	if err := monitor.check(); err == nil || monitor.exceeded != 1 || monitor.consumed != heap {
+		t.Error("Expected error when memory limit is exceeded")
+	}
+
+	monitor = newMonitor(MemoryLimitExceededLog)
+	if err := monitor.check(); err != nil || monitor.exceeded != 1 {
+		t.Errorf("Expected memory limit to be exceeded without error: %v", err)
+	}
+
+	// without alert manager the alert is logged
+	monitor = newMonitor(MemoryLimitExceededAlert)
+	if err := monitor.check(); err != nil || monitor.exceeded != 1 {
+		t.Errorf("Expected memory limit to be exceeded without error: %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"context"
+	"github.com/rcrowley/go-metrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cast"
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/el"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+)
+
+const (
+	UserMetricPrefix = "user."
+	conditionConfig  = "condition"
+)
+
+type dataRuleCount struct {
+	evaluatedRecords int64
+	matchedRecords   int64
+}
+
+// RulesEvaluator evaluates the metric and data rules of a pipeline after every batch and raises the alerts
+// through the alert manager. Data rule counts are kept from the time the rules were last set.
+type RulesEvaluator struct {
+	ruleDefinitions common.RuleDefinitions
+	alertManager    *AlertManager
+	metricRegistry  metrics.Registry
+	stageContext    api.StageContext
+	dataRuleCounts  map[string]*dataRuleCount
+	mutex           sync.Mutex
+}
+
+func (r *RulesEvaluator) SetRuleDefinitions(ruleDefinitions common.RuleDefinitions) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.ruleDefinitions = ruleDefinitions
+	r.dataRuleCounts = make(map[string]*dataRuleCount)
+}
+
+func (r *RulesEvaluator) Evaluate(pipeBatch PipeBatch) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, dataRuleDefinition := range r.ruleDefinitions.DataRuleDefinitions {
+		if dataRuleDefinition.Enabled {
+			r.evaluateDataRule(dataRuleDefinition, pipeBatch.GetLaneRecords(dataRuleDefinition.Lane))
+		}
+	}
+
+	for _, metricsRuleDefinition := range r.ruleDefinitions.MetricsRuleDefinitions {
+		if metricsRuleDefinition.Enabled {
+			r.evaluateMetricsRule(metricsRuleDefinition)
+		}
+	}
+}
+
+func (r *RulesEvaluator) evaluateDataRule(dataRuleDefinition *common.DataRuleDefinition, records []api.Record) {
+	count := r.dataRuleCounts[dataRuleDefinition.Id]
+	if count == nil {
+		count = &dataRuleCount{}
+		r.dataRuleCounts[dataRuleDefinition.Id] = count
+	}
+
+	var matchedRecords int64
+	for _, record := range sampleRecords(records, dataRuleDefinition.SamplingPercentage) {
+		recordContext := context.WithValue(context.Background(), el.RecordContextVar, record)
+		result, err := r.stageContext.Evaluate(dataRuleDefinition.Condition, conditionConfig, recordContext)
+		if err != nil {
+			log.WithError(err).WithField("rule", dataRuleDefinition.Id).Warn("Failed to evaluate data rule")
+			return
+		}
+		count.evaluatedRecords++
+		if cast.ToBool(result) {
+			matchedRecords++
+		}
+	}
+	count.matchedRecords += matchedRecords
+
+	if dataRuleDefinition.MeterEnabled {
+		util.CreateMeter(r.metricRegistry, UserMetricPrefix+dataRuleDefinition.Id).Mark(matchedRecords)
+	}
+
+	if !dataRuleDefinition.AlertEnabled {
+		return
+	}
+
+	threshold := cast.ToFloat64(strings.TrimSpace(dataRuleDefinition.ThresholdValue))
+	switch dataRuleDefinition.ThresholdType {
+	case common.ThresholdTypePercentage:
+		if count.evaluatedRecords == 0 || count.evaluatedRecords < dataRuleDefinition.MinVolume {
+			return
+		}
+		percentage := float64(count.matchedRecords) * 100 / float64(count.evaluatedRecords)
+		if percentage > threshold {
+			r.alertManager.Alert(dataRuleDefinition.Id, dataRuleDefinition, dataRuleDefinition.AlertText, percentage)
+		}
+	default:
+		if float64(count.matchedRecords) > threshold {
+			r.alertManager.Alert(
+				dataRuleDefinition.Id,
+				dataRuleDefinition,
+				dataRuleDefinition.AlertText,
+				count.matchedRecords,
+			)
+		}
+	}
+}
+
+func (r *RulesEvaluator) evaluateMetricsRule(metricsRuleDefinition *common.MetricsRuleDefinition) {
+	metric := r.metricRegistry.Get(metricsRuleDefinition.MetricId)
+	if metric == nil {
+		return
+	}
+	value, ok := getMetricValue(metric, metricsRuleDefinition.MetricElement)
+	if !ok {
+		log.WithField("rule", metricsRuleDefinition.Id).
+			WithField("element", metricsRuleDefinition.MetricElement).
+			Warn("Unsupported metric element")
+		return
+	}
+
+	evaluator, _ := el.NewEvaluator(
+		conditionConfig,
+		nil,
+		[]el.Definitions{
+			&el.StringEL{},
+			&el.MathEL{},
+			&el.MetricRuleEL{Value: value},
+		},
+	)
+	result, err := evaluator.Evaluate(metricsRuleDefinition.Condition)
+	if err != nil {
+		log.WithError(err).WithField("rule", metricsRuleDefinition.Id).Warn("Failed to evaluate metric rule")
+		return
+	}
+	if cast.ToBool(result) {
+		r.alertManager.Alert(metricsRuleDefinition.Id, metricsRuleDefinition, metricsRuleDefinition.AlertText, value)
+	}
+}
+
+// sampleRecords returns a random sample of the given percentage of the records, rounded up.
+func sampleRecords(records []api.Record, samplingPercentage float64) []api.Record {
+	if samplingPercentage >= 100 || len(records) == 0 {
+		return records
+	}
+	sampleSize := int(math.Ceil(float64(len(records)) * samplingPercentage / 100))
+	if sampleSize <= 0 {
+		return nil
+	}
+	sample := make([]api.Record, sampleSize)
+	for i, index := range rand.Perm(len(records))[:sampleSize] {
+		sample[i] = records[index]
+	}
+	return sample
+}
+
+// getMetricValue returns the value of the given element of the metric, timer durations are in seconds as in
+// the pipeline metrics.
+func getMetricValue(metric interface{}, metricElement string) (interface{}, bool) {
+	switch m := metric.(type) {
+	case metrics.Counter:
+		if metricElement == "COUNTER_COUNT" {
+			return m.Count(), true
+		}
+	case metrics.Gauge:
+		return m.Value(), true
+	case metrics.Meter:
+		s := m.Snapshot()
+		switch metricElement {
+		case "METER_COUNT":
+			return s.Count(), true
+		case "METER_M1_RATE":
+			return s.Rate1(), true
+		case "METER_M5_RATE":
+			return s.Rate5(), true
+		case "METER_M15_RATE":
+			return s.Rate15(), true
+		case "METER_MEAN_RATE":
+			return s.RateMean(), true
+		}
+	case metrics.Timer:
+		s := m.Snapshot()
+		switch metricElement {
+		case "TIMER_COUNT":
+			return s.Count(), true
+		case "TIMER_M1_RATE":
+			return s.Rate1(), true
+		case "TIMER_M5_RATE":
+			return s.Rate5(), true
+		case "TIMER_M15_RATE":
+			return s.Rate15(), true
+		case "TIMER_MEAN_RATE":
+			return s.RateMean(), true
+		case "TIMER_MIN":
+			return util.ConvertNanoToSecondsFloat(float64(s.Min())), true
+		case "TIMER_MAX":
+			return util.ConvertNanoToSecondsFloat(float64(s.Max())), true
+		case "TIMER_MEAN":
+			return util.ConvertNanoToSecondsFloat(s.Mean()), true
+		case "TIMER_STD_DEV":
+			return util.ConvertNanoToSecondsFloat(s.StdDev()), true
+		}
+		if percentile, ok := getPercentile(metricElement, "TIMER_"); ok {
+			return util.ConvertNanoToSecondsFloat(s.Percentile(percentile)), true
+		}
+	case metrics.Histogram:
+		s := m.Snapshot()
+		switch metricElement {
+		case "HISTOGRAM_COUNT":
+			return s.Count(), true
+		case "HISTOGRAM_MIN":
+			return s.Min(), true
+		case "HISTOGRAM_MAX":
+			return s.Max(), true
+		case "HISTOGRAM_MEAN":
+			return s.Mean(), true
+		case "HISTOGRAM_MEDIAN":
+			return s.Percentile(0.5), true
+		case "HISTOGRAM_STD_DEV":
+			return s.StdDev(), true
+		}
+		if percentile, ok := getPercentile(metricElement, "HISTOGRAM_"); ok {
+			return s.Percentile(percentile), true
+		}
+	}
+	return nil, false
+}
+
+func getPercentile(metricElement string, prefix string) (float64, bool) {
+	switch strings.TrimPrefix(metricElement, prefix) {
+	case "P50":
+		return 0.5, true
+	case "P75":
+		return 0.75, true
+	case "P95":
+		return 0.95, true
+	case "P98":
+		return 0.98, true
+	case "P99":
+		return 0.99, true
+	case "P999":
+		return 0.999, true
+	}
+	return 0, false
+}
+
+func NewRulesEvaluator(
+	ruleDefinitions common.RuleDefinitions,
+	alertManager *AlertManager,
+	metricRegistry metrics.Registry,
+	stageContext api.StageContext,
+) *RulesEvaluator {
+	rulesEvaluator := &RulesEvaluator{
+		alertManager:   alertManager,
+		metricRegistry: metricRegistry,
+		stageContext:   stageContext,
+	}
+	rulesEvaluator.SetRuleDefinitions(ruleDefinitions)
+	return rulesEvaluator
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"github.com/rcrowley/go-metrics"
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestGetMetricValue(t *testing.T) {
+	metricRegistry := metrics.NewRegistry()
+	util.CreateCounter(metricRegistry, PipelineBatchCount).Inc(5)
+	util.CreateMeter(metricRegistry, PipelineBatchInputRecords).Mark(10)
+	util.CreateHistogram5Min(metricRegistry, PipelineInputRecordsPerBatch).Update(20)
+
+	value, ok := getMetricValue(metricRegistry.Get(PipelineBatchCount+util.COUNTER_SUFFIX), "COUNTER_COUNT")
+	if !ok || value != int64(5) {
+		t.Errorf("Expected counter value 5, but got %v", value)
+	}
+
+	value, ok = getMetricValue(metricRegistry.Get(PipelineBatchInputRecords+util.METER_SUFFIX), "METER_COUNT")
+	if !ok || value != int64(10) {
+		t.Errorf("Expected meter count 10, but got %v", value)
+	}
+
+	value, ok = getMetricValue(
+		metricRegistry.Get(PipelineInputRecordsPerBatch+util.HISTOGRAM_M5_SUFFIX),
+		"HISTOGRAM_P99",
+	)
+	if !ok || value != float64(20) {
+		t.Errorf("Expected histogram 99th percentile 20, but got %v", value)
+	}
+
+	if _, ok = getMetricValue(metricRegistry.Get(PipelineBatchCount+util.COUNTER_SUFFIX), "METER_COUNT"); ok {
+		t.Error("Expected meter element to be unsupported for counters")
+	}
+}
+
+func TestSampleRecords(t *testing.T) {
+	records := make([]api.Record, 10)
+	if sample := sampleRecords(records, 100); len(sample) != 10 {
+		t.Errorf("Expected all 10 records to be sampled, but got %d", len(sample))
+	}
+	if sample := sampleRecords(records, 25); len(sample) != 3 {
+		t.Errorf("Expected 3 records to be sampled, but got %d", len(sample))
+	}
+	if sample := sampleRecords(records, 0); len(sample) != 0 {
+		t.Errorf("Expected no records to be sampled, but got %d", len(sample))
+	}
+}
+
+func TestAlertManager(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestAlertManager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	store.BaseDir = baseDir
+
+	alertManager, err := NewAlertManager("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alertManager.Alert("rule1", nil, "High batch count", int64(101))
+	alertManager.Alert("rule1", nil, "High batch count", int64(102))
+	alertManager.Alert("rule2", nil, "Bad records", int64(1))
+
+	// alerts are persisted with the pipeline
+	alertManager, err = NewAlertManager("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerts := alertManager.GetAlerts()
+	if len(alerts) != 2 {
+		t.Fatalf("Expected 2 alerts, but got %d", len(alerts))
+	}
+	if alerts[0].RuleId != "rule1" || alerts[0].CurrentValue != float64(102) {
+		t.Errorf("Expected alert for rule1 with current value 102, but got %v", alerts[0])
+	}
+
+	if err = alertManager.DeleteAlert("rule1"); err != nil {
+		t.Fatal(err)
+	}
+	if err = alertManager.DeleteAlert("rule1"); err == nil {
+		t.Error("Expected error deleting an alert that does not exist")
+	}
+	if alerts = alertManager.GetAlerts(); len(alerts) != 1 || alerts[0].RuleId != "rule2" {
+		t.Errorf("Expected only the alert for rule2, but got %v", alerts)
+	}
+
+	// the returned alerts are not changed when the alert fires again
+	alertManager.Alert("rule2", nil, "More bad records", int64(2))
+	if alerts[0].AlertText != "Bad records" || alerts[0].CurrentValue != float64(1) {
+		t.Errorf("Expected the returned alert to keep its values, but got %v", alerts[0])
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/container/execution"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"time"
+)
+
+// snapshotCapture collects the stage outputs of consecutive batches until the requested number of batches is
+// captured, at which point the snapshot is saved.
+type snapshotCapture struct {
+	pipelineId      string
+	snapshotInfo    store.SnapshotInfo
+	snapshotBatches [][]execution.StageOutputJson
+}
+
+// CaptureSnapshot starts capturing the stage outputs of the next batches of the pipeline into a snapshot with the
+// given name.
+func (p *Pipeline) CaptureSnapshot(snapshotName string, snapshotLabel string, batches int) (*store.SnapshotInfo, error) {
+	if batches < 1 {
+		return nil, errors.New(fmt.Sprintf("invalid number of batches to capture: %d", batches))
+	}
+
+	p.snapshotMutex.Lock()
+	defer p.snapshotMutex.Unlock()
+
+	if p.snapshotCapture != nil {
+		return nil, errors.New(fmt.Sprintf(
+			"snapshot '%s' is being captured",
+			p.snapshotCapture.snapshotInfo.Id,
+		))
+	}
+
+	pipelineId := p.pipelineConf.PipelineId
+	if _, err := store.GetSnapshotInfo(pipelineId, snapshotName); err == nil {
+		return nil, errors.New(fmt.Sprintf("snapshot '%s' already exists", snapshotName))
+	}
+
+	capture := &snapshotCapture{
+		pipelineId: pipelineId,
+		snapshotInfo: store.SnapshotInfo{
+			Id:          snapshotName,
+			Label:       snapshotLabel,
+			PipelineId:  pipelineId,
+			Timestamp:   util.ConvertTimeToLong(time.Now()),
+			BatchNumber: batches,
+			InProgress:  true,
+		},
+		snapshotBatches: make([][]execution.StageOutputJson, 0, batches),
+	}
+	if err := store.SaveSnapshotInfo(pipelineId, capture.snapshotInfo); err != nil {
+		return nil, err
+	}
+	p.snapshotCapture = capture
+	return &capture.snapshotInfo, nil
+}
+
+func (p *Pipeline) isCapturingSnapshot() bool {
+	p.snapshotMutex.Lock()
+	defer p.snapshotMutex.Unlock()
+	return p.snapshotCapture != nil
+}
+
+func (p *Pipeline) getCapturingSnapshotId() string {
+	p.snapshotMutex.Lock()
+	defer p.snapshotMutex.Unlock()
+	if p.snapshotCapture == nil {
+		return ""
+	}
+	return p.snapshotCapture.snapshotInfo.Id
+}
+
+// addSnapshotBatch adds the stage outputs of a processed batch to the snapshot being captured.
+func (p *Pipeline) addSnapshotBatch(stageOutputs []execution.StageOutput) {
+	p.snapshotMutex.Lock()
+	defer p.snapshotMutex.Unlock()
+
+	if p.snapshotCapture == nil {
+		return
+	}
+
+	batchOutput := make([]execution.StageOutputJson, len(stageOutputs))
+	for i, stageOutput := range stageOutputs {
+		stageOutputJson, err := execution.NewStageOutputJson(stageOutput)
+		if err != nil {
+			log.WithError(err).WithField("snapshot", p.snapshotCapture.snapshotInfo.Id).
+				Error("Failed to capture snapshot batch")
+			return
+		}
+		batchOutput[i] = *stageOutputJson
+	}
+	p.snapshotCapture.snapshotBatches = append(p.snapshotCapture.snapshotBatches, batchOutput)
+
+	if len(p.snapshotCapture.snapshotBatches) >= p.snapshotCapture.snapshotInfo.BatchNumber {
+		p.saveSnapshot()
+	}
+}
+
+// finishSnapshotCapture saves the batches captured so far when the pipeline stops during a capture.
+func (p *Pipeline) finishSnapshotCapture() {
+	p.snapshotMutex.Lock()
+	defer p.snapshotMutex.Unlock()
+
+	if p.snapshotCapture != nil {
+		p.saveSnapshot()
+	}
+}
+
+func (p *Pipeline) saveSnapshot() {
+	capture := p.snapshotCapture
+	p.snapshotCapture = nil
+
+	capture.snapshotInfo.BatchCount = len(capture.snapshotBatches)
+	capture.snapshotInfo.InProgress = false
+	snapshot := execution.Snapshot{
+		Info:            &capture.snapshotInfo,
+		SnapshotBatches: capture.snapshotBatches,
+	}
+	if err := store.SaveSnapshotData(capture.pipelineId, capture.snapshotInfo.Id, snapshot); err != nil {
+		log.WithError(err).WithField("snapshot", capture.snapshotInfo.Id).Error("Failed to save snapshot")
+		return
+	}
+	if err := store.SaveSnapshotInfo(capture.pipelineId, capture.snapshotInfo); err != nil {
+		log.WithError(err).WithField("snapshot", capture.snapshotInfo.Id).Error("Failed to save snapshot info")
+	}
+}
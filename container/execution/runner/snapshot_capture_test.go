@@ -0,0 +1,100 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/execution"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestSnapshotCapture(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestSnapshotCapture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	store.BaseDir = baseDir
+
+	p := &Pipeline{pipelineConf: common.PipelineConfiguration{PipelineId: "testPipeline"}}
+
+	if _, err := p.CaptureSnapshot("../snapshot", "", 1); err == nil {
+		t.Error("Expected error for invalid snapshot name")
+	}
+
+	snapshotInfo, err := p.CaptureSnapshot("snapshot1", "label1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snapshotInfo.InProgress || !p.isCapturingSnapshot() {
+		t.Fatal("Expected snapshot capture to be in progress")
+	}
+	if _, err := p.CaptureSnapshot("snapshot2", "", 1); err == nil {
+		t.Error("Expected error when capturing a snapshot while another one is being captured")
+	}
+
+	stageContext := &common.StageContextImpl{StageConfig: &common.StageConfiguration{InstanceName: "origin1"}}
+	record, _ := stageContext.CreateRecord("record1", map[string]interface{}{"a": "value"})
+	stageOutputs := []execution.StageOutput{{
+		InstanceName: "origin1",
+		Output:       map[string][]api.Record{"lane1": {record}},
+	}}
+	p.addSnapshotBatch(stageOutputs)
+	p.addSnapshotBatch(stageOutputs)
+	if p.isCapturingSnapshot() {
+		t.Fatal("Expected snapshot capture to be complete")
+	}
+
+	snapshotsInfo, err := store.GetSnapshotsInfo("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshotsInfo) != 1 || snapshotsInfo[0].InProgress || snapshotsInfo[0].BatchCount != 2 ||
+		snapshotsInfo[0].Label != "label1" {
+		t.Fatalf("Unexpected snapshots info: %v", snapshotsInfo)
+	}
+
+	snapshot := &execution.Snapshot{}
+	if err := store.GetSnapshotData("testPipeline", "snapshot1", snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.SnapshotBatches) != 2 || snapshot.SnapshotBatches[1][0].InstanceName != "origin1" ||
+		len(snapshot.SnapshotBatches[1][0].Output["lane1"]) != 1 {
+		t.Errorf("Unexpected snapshot batches: %v", snapshot.SnapshotBatches)
+	}
+
+	// a snapshot stopped before all batches are captured keeps the captured batches
+	if _, err := p.CaptureSnapshot("snapshot2", "", 5); err != nil {
+		t.Fatal(err)
+	}
+	p.addSnapshotBatch(stageOutputs)
+	p.finishSnapshotCapture()
+	snapshotInfo, err = store.GetSnapshotInfo("testPipeline", "snapshot2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshotInfo.InProgress || snapshotInfo.BatchCount != 1 {
+		t.Errorf("Unexpected snapshot info: %v", snapshotInfo)
+	}
+
+	if err := store.DeleteSnapshot("testPipeline", "snapshot1"); err != nil {
+		t.Fatal(err)
+	}
+	if snapshotsInfo, _ = store.GetSnapshotsInfo("testPipeline"); len(snapshotsInfo) != 1 {
+		t.Errorf("Expected 1 snapshot after delete, but got %d", len(snapshotsInfo))
+	}
+}
@@ -13,21 +13,42 @@
 package runner
 
 import (
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
 	"github.com/streamsets/datacollector-edge/api"
 	"github.com/streamsets/datacollector-edge/api/validation"
 	"github.com/streamsets/datacollector-edge/container/common"
 	"github.com/streamsets/datacollector-edge/container/creation"
+	"github.com/streamsets/datacollector-edge/container/execution/buffer"
+	"runtime/debug"
+	"time"
 )
 
 type StageRuntime struct {
-	pipelineBean creation.PipelineBean
-	config       *common.StageConfiguration
-	stageBean    creation.StageBean
-	stageContext api.StageContext
+	pipelineBean          creation.PipelineBean
+	config                *common.StageConfiguration
+	stageBean             creation.StageBean
+	stageContext          api.StageContext
+	storeAndForwardBuffer *buffer.DiskBuffer
+	executionTimeout      time.Duration
+	recordParallelism     int
 }
 
-func (s *StageRuntime) Init() []validation.Issue {
-	issues := make([]validation.Issue, 0)
+type stageExecuteResult struct {
+	newOffset *string
+	err       error
+}
+
+func (s *StageRuntime) Init() (issues []validation.Issue) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := s.newPanicError(r)
+			issues = append(issues, s.stageContext.CreateConfigIssue(err.Error()))
+		}
+	}()
+
+	issues = make([]validation.Issue, 0)
 	if s.stageBean.Services != nil {
 		for _, serviceBean := range s.stageBean.Services {
 			serviceIssues := serviceBean.Service.Init(s.stageContext)
@@ -38,25 +59,120 @@ func (s *StageRuntime) Init() []validation.Issue {
 	return append(issues, stageIssues...)
 }
 
+// Execute runs the batch through the stage. A panic in the stage is returned as an error along with the stack trace,
+// and when the stage has an execution timeout, an error is returned if the stage doesn't finish in time. The stuck
+// call is left running in the background until the stage is destroyed.
 func (s *StageRuntime) Execute(
 	previousOffset *string,
 	batchSize int,
 	batch *BatchImpl,
 	batchMaker *BatchMakerImpl,
 ) (*string, error) {
-	var newOffset *string
-	var err error
+	if s.executionTimeout <= 0 {
+		return s.execute(previousOffset, batchSize, batch, batchMaker)
+	}
+
+	resultChan := make(chan stageExecuteResult, 1)
+	go func() {
+		newOffset, err := s.execute(previousOffset, batchSize, batch, batchMaker)
+		resultChan <- stageExecuteResult{newOffset: newOffset, err: err}
+	}()
+
+	timeout := time.NewTimer(s.executionTimeout)
+	defer timeout.Stop()
+	select {
+	case result := <-resultChan:
+		return result.newOffset, result.err
+	case <-timeout.C:
+		log.WithField("stage", s.config.InstanceName).
+			WithField("timeout", s.executionTimeout).
+			Error("Stage did not finish the batch in time")
+		return nil, errors.New(fmt.Sprintf(
+			"Stage '%s' did not finish the batch within %v",
+			s.config.InstanceName,
+			s.executionTimeout,
+		))
+	}
+}
+
+func (s *StageRuntime) execute(
+	previousOffset *string,
+	batchSize int,
+	batch *BatchImpl,
+	batchMaker *BatchMakerImpl,
+) (newOffset *string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = s.newPanicError(r)
+		}
+	}()
+
 	if s.stageBean.IsSource() {
 		newOffset, err = s.stageBean.Stage.(api.Origin).Produce(previousOffset, batchSize, batchMaker)
 	} else if s.stageBean.IsProcessor() {
-		err = s.stageBean.Stage.(api.Processor).Process(batch, batchMaker)
+		recordProcessor, ok := s.stageBean.Stage.(api.RecordProcessor)
+		if ok && s.recordParallelism > 1 && recordProcessor.IsRecordParallel() {
+			err = s.processRecordsInParallel(recordProcessor, batch, batchMaker)
+		} else {
+			err = s.stageBean.Stage.(api.Processor).Process(batch, batchMaker)
+		}
 	} else if s.stageBean.IsTarget() {
-		err = s.stageBean.Stage.(api.Destination).Write(batch)
+		if s.storeAndForwardBuffer != nil {
+			err = s.writeWithStoreAndForward(batch)
+		} else {
+			err = s.stageBean.Stage.(api.Destination).Write(batch)
+		}
 	}
 	return newOffset, err
 }
 
+// newPanicError logs the recovered panic of the stage and returns it as an error including the stack trace.
+func (s *StageRuntime) newPanicError(recovered interface{}) error {
+	stackTrace := string(debug.Stack())
+	log.WithField("stage", s.GetInstanceName()).
+		WithField("stackTrace", stackTrace).
+		Errorf("Stage panicked: %v", recovered)
+	return errors.New(fmt.Sprintf("Stage '%s' panicked: %v\n%s", s.GetInstanceName(), recovered, stackTrace))
+}
+
+// writeWithStoreAndForward writes the buffered batches to the destination in order before writing the given batch.
+// Batches that can't be written are added to the buffer instead of failing the pipeline.
+func (s *StageRuntime) writeWithStoreAndForward(batch *BatchImpl) error {
+	destination := s.stageBean.Stage.(api.Destination)
+	s.storeAndForwardBuffer.LockForwarding()
+	defer s.storeAndForwardBuffer.UnlockForwarding()
+
+	for !s.storeAndForwardBuffer.IsEmpty() {
+		bufferedRecords, err := s.storeAndForwardBuffer.Peek(s.stageContext)
+		if err != nil {
+			return err
+		}
+		err = destination.Write(NewBatchImpl(s.config.InstanceName, bufferedRecords, batch.GetSourceOffset()))
+		if err != nil {
+			log.WithError(err).WithField("stage", s.config.InstanceName).
+				Warn("Destination unavailable, adding batch to store and forward buffer")
+			return s.storeAndForwardBuffer.Enqueue(s.stageContext, batch.GetRecords())
+		}
+		if err = s.storeAndForwardBuffer.Remove(); err != nil {
+			return err
+		}
+	}
+
+	if err := destination.Write(batch); err != nil {
+		log.WithError(err).WithField("stage", s.config.InstanceName).
+			Warn("Destination unavailable, adding batch to store and forward buffer")
+		return s.storeAndForwardBuffer.Enqueue(s.stageContext, batch.GetRecords())
+	}
+	return nil
+}
+
 func (s *StageRuntime) Destroy() {
+	defer func() {
+		if r := recover(); r != nil {
+			_ = s.newPanicError(r)
+		}
+	}()
+
 	if s.stageBean.Services != nil {
 		for _, serviceBean := range s.stageBean.Services {
 			_ = serviceBean.Service.Destroy()
@@ -66,6 +182,9 @@ func (s *StageRuntime) Destroy() {
 }
 
 func (s *StageRuntime) GetInstanceName() string {
+	if s.config == nil {
+		return ""
+	}
 	return s.config.InstanceName
 }
 
@@ -75,9 +194,11 @@ func NewStageRuntime(
 	stageContext api.StageContext,
 ) StageRuntime {
 	return StageRuntime{
-		pipelineBean: pipelineBean,
-		config:       stageBean.Config,
-		stageBean:    stageBean,
-		stageContext: stageContext,
+		pipelineBean:      pipelineBean,
+		config:            stageBean.Config,
+		stageBean:         stageBean,
+		stageContext:      stageContext,
+		executionTimeout:  time.Duration(stageBean.SystemConfigs.StageExecutionTimeout) * time.Millisecond,
+		recordParallelism: int(stageBean.SystemConfigs.StageRecordParallelism),
 	}
 }
@@ -0,0 +1,80 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	"strings"
+	"testing"
+	"time"
+)
+
+type panickingProcessor struct {
+	*common.BaseStage
+}
+
+func (p *panickingProcessor) Process(batch api.Batch, batchMaker api.BatchMaker) error {
+	var records map[string]api.Record
+	records["record"] = nil
+	return nil
+}
+
+type blockingDestination struct {
+	*common.BaseStage
+	release chan struct{}
+}
+
+func (d *blockingDestination) Write(batch api.Batch) error {
+	<-d.release
+	return nil
+}
+
+func newTestStageRuntime(stage api.Stage, stageType string, executionTimeout float64) StageRuntime {
+	stageConfig := &common.StageConfiguration{
+		InstanceName: "stage1",
+		UiInfo:       map[string]interface{}{creation.STAGE_TYPE: stageType},
+	}
+	return NewStageRuntime(creation.PipelineBean{}, creation.StageBean{
+		Config:        stageConfig,
+		Stage:         stage,
+		SystemConfigs: creation.StageConfigBean{StageExecutionTimeout: executionTimeout},
+	}, nil)
+}
+
+func TestStageRuntimeRecoversPanic(t *testing.T) {
+	stageRuntime := newTestStageRuntime(&panickingProcessor{BaseStage: &common.BaseStage{}}, creation.PROCESSOR, 0)
+
+	_, err := stageRuntime.Execute(nil, 1, NewBatchImpl("stage1", nil, nil), nil)
+	if err == nil {
+		t.Fatal("Expected panic to be returned as an error")
+	}
+	if !strings.Contains(err.Error(), "Stage 'stage1' panicked") || !strings.Contains(err.Error(), "goroutine") {
+		t.Errorf("Expected error with stage name and stack trace, but got: %s", err.Error())
+	}
+}
+
+func TestStageRuntimeExecutionTimeout(t *testing.T) {
+	destination := &blockingDestination{BaseStage: &common.BaseStage{}, release: make(chan struct{})}
+	defer close(destination.release)
+	stageRuntime := newTestStageRuntime(destination, creation.TARGET, 10)
+
+	_, err := stageRuntime.Execute(nil, 1, NewBatchImpl("stage1", nil, nil), nil)
+	if err == nil {
+		t.Fatal("Expected error for stage exceeding the execution timeout")
+	}
+	if !strings.Contains(err.Error(), "did not finish the batch within "+(10*time.Millisecond).String()) {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+}
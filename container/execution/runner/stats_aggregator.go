@@ -0,0 +1,203 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"encoding/json"
+	"github.com/rcrowley/go-metrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/api/validation"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	"github.com/streamsets/datacollector-edge/container/execution"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"strconv"
+	"time"
+)
+
+const (
+	STATS_NULL_TARGET         = "com_streamsets_pipeline_stage_destination_devnull_StatsNullDTarget"
+	MetricRecordTypeAttribute = "sdc.record.type"
+	MetricRecordType          = "metricRegistry"
+)
+
+// StatsAggregator periodically turns the pipeline metrics into a metric record and writes it to the stats
+// aggregator stage of the pipeline, so metrics can be shipped through any destination.
+type StatsAggregator struct {
+	pipelineConfig common.PipelineConfiguration
+	stageRuntime   StageRuntime
+	errorSink      *common.ErrorSink
+	metricRegistry metrics.Registry
+	interval       time.Duration
+	quit           chan bool
+	done           chan bool
+}
+
+func (s *StatsAggregator) Init() []validation.Issue {
+	return s.stageRuntime.Init()
+}
+
+func (s *StatsAggregator) Run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	defer close(s.done)
+	for {
+		select {
+		case <-ticker.C:
+			s.writeMetrics()
+		case <-s.quit:
+			// write the latest metrics before stopping
+			s.writeMetrics()
+			return
+		}
+	}
+}
+
+// Stop stops writing metrics after writing the latest metrics, and destroys the stats aggregator stage.
+func (s *StatsAggregator) Stop() {
+	close(s.quit)
+	<-s.done
+	s.stageRuntime.Destroy()
+}
+
+func (s *StatsAggregator) writeMetrics() {
+	record, err := s.createMetricRecord()
+	if err != nil {
+		log.WithError(err).Error("Failed to create metric record")
+		return
+	}
+
+	batch := NewBatchImpl(s.stageRuntime.GetInstanceName(), []api.Record{record}, nil)
+	if _, err = s.stageRuntime.Execute(nil, -1, batch, nil); err != nil {
+		log.WithError(err).WithField("stage", s.stageRuntime.GetInstanceName()).Error("Failed to write metrics")
+	}
+	for _, errorMessages := range s.errorSink.GetErrorMessages() {
+		for _, errorMessage := range errorMessages {
+			log.WithField("stage", s.stageRuntime.GetInstanceName()).Error(errorMessage.LocalizableMessage)
+		}
+	}
+	if errorRecords := s.errorSink.GetTotalErrorRecords(); errorRecords > 0 {
+		log.WithField("stage", s.stageRuntime.GetInstanceName()).
+			WithField("errorRecords", errorRecords).
+			Error("Failed to write metric records")
+	}
+	s.errorSink.ClearErrorRecordsAndMessages()
+}
+
+func (s *StatsAggregator) createMetricRecord() (api.Record, error) {
+	// convert to generic values the record fields can be created from
+	metricsJson, err := json.Marshal(util.FormatMetricsRegistry(s.metricRegistry))
+	if err != nil {
+		return nil, err
+	}
+	var metricsValue map[string]interface{}
+	if err = json.Unmarshal(metricsJson, &metricsValue); err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]interface{})
+	for key, value := range s.pipelineConfig.Metadata {
+		if stringValue, ok := value.(string); ok {
+			metadata[key] = stringValue
+		}
+	}
+
+	timestamp := util.ConvertTimeToLong(time.Now())
+	record, err := s.stageRuntime.stageContext.CreateRecord(
+		s.pipelineConfig.PipelineId+"::"+strconv.FormatInt(timestamp, 10),
+		map[string]interface{}{
+			"timestamp":     timestamp,
+			"pipelineId":    s.pipelineConfig.PipelineId,
+			"pipelineTitle": s.pipelineConfig.Title,
+			"metadata":      metadata,
+			"aggregated":    false,
+			"metrics":       metricsValue,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	record.GetHeader().SetAttribute(MetricRecordTypeAttribute, MetricRecordType)
+	return record, nil
+}
+
+// isStatsAggregatorEnabled returns true when the pipeline has a stats aggregator stage that writes metric records,
+// writing to Control Hub directly is done by the MetricsEventRunnable.
+func isStatsAggregatorEnabled(pipelineBean creation.PipelineBean) bool {
+	statsAggregatorStage := pipelineBean.StatsAggregatorStage
+	return statsAggregatorStage.Config != nil &&
+		statsAggregatorStage.Stage != nil &&
+		statsAggregatorStage.IsTarget() &&
+		statsAggregatorStage.Config.StageName != STATS_NULL_TARGET &&
+		statsAggregatorStage.Config.StageName != STATS_DPM_DIRECTLY_TARGET
+}
+
+func NewStatsAggregator(
+	config execution.Config,
+	pipelineConfig common.PipelineConfiguration,
+	pipelineBean creation.PipelineBean,
+	resolvedParameters map[string]interface{},
+	metricRegistry metrics.Registry,
+) (*StatsAggregator, []validation.Issue) {
+	issues := make([]validation.Issue, 0)
+	var services map[string]api.Service
+	if len(pipelineBean.StatsAggregatorStage.Services) > 0 {
+		services = make(map[string]api.Service)
+		for _, serviceBean := range pipelineBean.StatsAggregatorStage.Services {
+			services[serviceBean.Config.Service] = serviceBean.Service
+		}
+	}
+
+	errorSink := common.NewErrorSink()
+	stageContext, err := common.NewStageContext(
+		pipelineBean.StatsAggregatorStage.Config,
+		resolvedParameters,
+		metricRegistry,
+		errorSink,
+		false,
+		pipelineBean.Config.ErrorRecordPolicy,
+		services,
+		pipelineBean.ElContext,
+		common.NewEventSink(),
+		false,
+	)
+	if err != nil {
+		issues = append(issues, validation.Issue{
+			InstanceName: pipelineBean.StatsAggregatorStage.Config.InstanceName,
+			Level:        common.StageConfig,
+			Count:        1,
+			Message:      err.Error(),
+		})
+		return nil, issues
+	}
+
+	interval := config.StatsAggregatorInterval
+	if interval <= 0 {
+		log.WithField("interval", interval).Warnf(
+			"Invalid stats aggregator interval, using the default of %d ms",
+			execution.DefaultStatsAggregatorInterval,
+		)
+		interval = execution.DefaultStatsAggregatorInterval
+	}
+
+	return &StatsAggregator{
+		pipelineConfig: pipelineConfig,
+		stageRuntime:   NewStageRuntime(pipelineBean, pipelineBean.StatsAggregatorStage, stageContext),
+		errorSink:      errorSink,
+		metricRegistry: metricRegistry,
+		interval:       time.Duration(interval) * time.Millisecond,
+		quit:           make(chan bool),
+		done:           make(chan bool),
+	}, issues
+}
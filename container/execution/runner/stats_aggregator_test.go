@@ -0,0 +1,141 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"github.com/rcrowley/go-metrics"
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	"github.com/streamsets/datacollector-edge/container/execution"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"testing"
+	"time"
+)
+
+type recordsCollector struct {
+	*common.BaseStage
+	records []api.Record
+}
+
+func (d *recordsCollector) Write(batch api.Batch) error {
+	d.records = append(d.records, batch.GetRecords()...)
+	return nil
+}
+
+func TestStatsAggregator(t *testing.T) {
+	destination := &recordsCollector{BaseStage: &common.BaseStage{}}
+	pipelineBean := creation.PipelineBean{
+		StatsAggregatorStage: creation.StageBean{
+			Config: &common.StageConfiguration{
+				InstanceName: "StatsAggregator_01",
+				StageName:    "statsTarget",
+				UiInfo:       map[string]interface{}{creation.STAGE_TYPE: creation.TARGET},
+			},
+			Stage: destination,
+		},
+	}
+	if !isStatsAggregatorEnabled(pipelineBean) {
+		t.Fatal("Expected stats aggregator to be enabled")
+	}
+
+	metricRegistry := metrics.NewRegistry()
+	util.CreateCounter(metricRegistry, PipelineBatchCount).Inc(5)
+
+	config := execution.NewConfig()
+	config.StatsAggregatorInterval = 60000
+	statsAggregator, issues := NewStatsAggregator(
+		config,
+		common.PipelineConfiguration{PipelineId: "testPipeline"},
+		pipelineBean,
+		map[string]interface{}{},
+		metricRegistry,
+	)
+	if len(issues) > 0 {
+		t.Fatal(issues[0].Message)
+	}
+	if issues = statsAggregator.Init(); len(issues) > 0 {
+		t.Fatal(issues[0].Message)
+	}
+
+	go statsAggregator.Run()
+	statsAggregator.Stop()
+
+	if len(destination.records) != 1 {
+		t.Fatalf("Expected 1 metric record on stop, got %d", len(destination.records))
+	}
+	record := destination.records[0]
+	if record.GetHeader().GetAttribute(MetricRecordTypeAttribute) != MetricRecordType {
+		t.Errorf("Expected record type attribute %s", MetricRecordType)
+	}
+	pipelineIdField, err := record.Get("/pipelineId")
+	if err != nil || pipelineIdField.Value != "testPipeline" {
+		t.Errorf("Unexpected pipelineId field: %v, %v", pipelineIdField, err)
+	}
+	countField, err := record.Get("/metrics/counters/" + PipelineBatchCount + ".counter/count")
+	if err != nil || countField == nil || countField.Value != float64(5) {
+		t.Errorf("Unexpected batch count field: %v, %v", countField, err)
+	}
+}
+
+func TestStatsAggregatorInvalidInterval(t *testing.T) {
+	pipelineBean := creation.PipelineBean{
+		StatsAggregatorStage: creation.StageBean{
+			Config: &common.StageConfiguration{
+				InstanceName: "StatsAggregator_01",
+				StageName:    "statsTarget",
+				UiInfo:       map[string]interface{}{creation.STAGE_TYPE: creation.TARGET},
+			},
+			Stage: &recordsCollector{BaseStage: &common.BaseStage{}},
+		},
+	}
+
+	for _, interval := range []int{0, -1000} {
+		config := execution.NewConfig()
+		config.StatsAggregatorInterval = interval
+		statsAggregator, issues := NewStatsAggregator(
+			config,
+			common.PipelineConfiguration{PipelineId: "testPipeline"},
+			pipelineBean,
+			map[string]interface{}{},
+			metrics.NewRegistry(),
+		)
+		if len(issues) > 0 {
+			t.Fatal(issues[0].Message)
+		}
+		expectedInterval := execution.DefaultStatsAggregatorInterval * time.Millisecond
+		if statsAggregator.interval != expectedInterval {
+			t.Errorf("Expected interval %v for %d, but got %v", expectedInterval, interval, statsAggregator.interval)
+		}
+	}
+}
+
+func TestStatsAggregatorDisabled(t *testing.T) {
+	for _, stageName := range []string{STATS_NULL_TARGET, STATS_DPM_DIRECTLY_TARGET} {
+		pipelineBean := creation.PipelineBean{
+			StatsAggregatorStage: creation.StageBean{
+				Config: &common.StageConfiguration{
+					StageName: stageName,
+					UiInfo:    map[string]interface{}{creation.STAGE_TYPE: creation.TARGET},
+				},
+				Stage: &recordsCollector{BaseStage: &common.BaseStage{}},
+			},
+		}
+		if isStatsAggregatorEnabled(pipelineBean) {
+			t.Errorf("Expected stats aggregator to be disabled for %s", stageName)
+		}
+	}
+	if isStatsAggregatorEnabled(creation.PipelineBean{}) {
+		t.Error("Expected stats aggregator to be disabled without stage")
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cast"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	"github.com/streamsets/datacollector-edge/container/el"
+	"github.com/streamsets/datacollector-edge/container/execution"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"net/http"
+	"time"
+)
+
+const (
+	webhookUrlConfig     = "webhookUrl"
+	webhookHeadersConfig = "headers"
+	webhookPayloadConfig = "payload"
+)
+
+// WebhookNotifier sends the webhooks configured in a pipeline when the pipeline moves into one of its
+// notifyOnStates. Failed requests are retried with exponential backoff and every delivery is logged in the
+// pipeline run info.
+type WebhookNotifier struct {
+	pipelineId     string
+	maxRetries     int
+	retryBaseDelay time.Duration
+	httpClient     *http.Client
+}
+
+func (n *WebhookNotifier) Notify(
+	pipelineConfig common.PipelineConfiguration,
+	runtimeParameters map[string]interface{},
+	pipelineState common.PipelineState,
+) {
+	pipelineConfigBean := creation.NewPipelineConfigBean(pipelineConfig)
+	if len(pipelineConfigBean.WebhookConfigs) == 0 ||
+		!util.Contains(cast.ToStringSlice(pipelineConfigBean.NotifyOnStates), pipelineState.Status) {
+		return
+	}
+
+	parameters := make(map[string]interface{})
+	for key, value := range pipelineConfigBean.Constants {
+		parameters[key] = value
+	}
+	for key, value := range runtimeParameters {
+		parameters[key] = value
+	}
+
+	elContext := context.WithValue(context.Background(), el.PipelineElContextVar, map[string]interface{}{
+		el.PipelineIdContextVar:      pipelineConfig.PipelineId,
+		el.PipelineTitleContextVar:   pipelineConfig.Title,
+		el.PipelineUserContextVar:    pipelineConfig.Info.LastModifier,
+		el.PipelineStateContextVar:   pipelineState.Status,
+		el.PipelineMessageContextVar: pipelineState.Message,
+	})
+
+	for _, webhookConfig := range pipelineConfigBean.WebhookConfigs {
+		request, err := n.newRequest(webhookConfig, parameters, elContext)
+		if err != nil {
+			log.WithError(err).WithField("id", n.pipelineId).Error("Failed to create webhook request")
+			n.saveDelivery(webhookConfig, pipelineState, 0, 0, err)
+			continue
+		}
+		go n.send(webhookConfig, pipelineState, request)
+	}
+}
+
+func (n *WebhookNotifier) newRequest(
+	webhookConfig creation.PipelineWebhookConfig,
+	parameters map[string]interface{},
+	elContext context.Context,
+) (*webhookRequest, error) {
+	evaluate := func(template string, configName string) (string, error) {
+		evaluator, _ := el.NewEvaluator(
+			configName,
+			parameters,
+			[]el.Definitions{
+				&el.StringEL{},
+				&el.MathEL{},
+				&el.PipelineEL{Context: elContext},
+				&el.JobEL{Context: elContext},
+				&el.SdcEL{},
+			},
+		)
+		return evaluator.EvaluateTemplate(template)
+	}
+
+	var err error
+	request := &webhookRequest{
+		method:  webhookConfig.HttpMethod,
+		headers: make(map[string]string),
+	}
+	if request.method == "" {
+		request.method = http.MethodPost
+	}
+	if request.url, err = evaluate(webhookConfig.WebhookUrl, webhookUrlConfig); err != nil {
+		return nil, err
+	}
+	for key, value := range webhookConfig.Headers {
+		if request.headers[key], err = evaluate(value, webhookHeadersConfig); err != nil {
+			return nil, err
+		}
+	}
+	if request.payload, err = evaluate(webhookConfig.Payload, webhookPayloadConfig); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (n *WebhookNotifier) send(
+	webhookConfig creation.PipelineWebhookConfig,
+	pipelineState common.PipelineState,
+	request *webhookRequest,
+) {
+	var statusCode int
+	var err error
+	attempts := 0
+	for {
+		attempts++
+		statusCode, err = n.sendRequest(webhookConfig, request)
+		if err == nil || attempts > n.maxRetries {
+			break
+		}
+		retryDelay := n.retryBaseDelay * time.Duration(1<<uint(attempts-1))
+		log.WithError(err).
+			WithField("id", n.pipelineId).
+			WithField("url", request.url).
+			WithField("delay", retryDelay).
+			Warn("Webhook request failed, retrying")
+		time.Sleep(retryDelay)
+	}
+
+	if err != nil {
+		log.WithError(err).WithField("id", n.pipelineId).WithField("url", request.url).Error("Webhook request failed")
+	}
+	n.saveDelivery(webhookConfig, pipelineState, attempts, statusCode, err)
+}
+
+func (n *WebhookNotifier) sendRequest(
+	webhookConfig creation.PipelineWebhookConfig,
+	request *webhookRequest,
+) (int, error) {
+	httpRequest, err := http.NewRequest(request.method, request.url, bytes.NewBufferString(request.payload))
+	if err != nil {
+		return 0, err
+	}
+	if webhookConfig.ContentType != "" {
+		httpRequest.Header.Set(common.HeaderContentType, webhookConfig.ContentType)
+	}
+	for key, value := range request.headers {
+		httpRequest.Header.Set(key, value)
+	}
+	if webhookConfig.AuthType == creation.WebhookAuthTypeBasic {
+		httpRequest.SetBasicAuth(webhookConfig.Username, webhookConfig.Password)
+	}
+
+	response, err := n.httpClient.Do(httpRequest)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			log.WithError(err).Error("Error while closing the response body")
+		}
+	}()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, errors.New(fmt.Sprintf("webhook returned status: %s", response.Status))
+	}
+	return response.StatusCode, nil
+}
+
+func (n *WebhookNotifier) saveDelivery(
+	webhookConfig creation.PipelineWebhookConfig,
+	pipelineState common.PipelineState,
+	attempts int,
+	statusCode int,
+	deliveryError error,
+) {
+	webhookDelivery := store.WebhookDelivery{
+		WebhookUrl: webhookConfig.WebhookUrl,
+		HttpMethod: webhookConfig.HttpMethod,
+		State:      pipelineState.Status,
+		Timestamp:  util.ConvertTimeToLong(time.Now()),
+		Attempts:   attempts,
+		StatusCode: statusCode,
+		Success:    deliveryError == nil,
+	}
+	if deliveryError != nil {
+		webhookDelivery.Error = deliveryError.Error()
+	}
+	if err := store.SaveWebhookDelivery(n.pipelineId, webhookDelivery); err != nil {
+		log.WithError(err).Error("Failed to save webhook delivery")
+	}
+}
+
+type webhookRequest struct {
+	method  string
+	url     string
+	headers map[string]string
+	payload string
+}
+
+func NewWebhookNotifier(pipelineId string, config execution.Config) *WebhookNotifier {
+	return &WebhookNotifier{
+		pipelineId:     pipelineId,
+		maxRetries:     config.WebhookMaxRetries,
+		retryBaseDelay: time.Duration(config.WebhookRetryBaseDelay) * time.Millisecond,
+		httpClient: &http.Client{
+			Timeout: time.Duration(config.WebhookTimeout) * time.Millisecond,
+		},
+	}
+}
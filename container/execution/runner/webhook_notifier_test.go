@@ -0,0 +1,99 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package runner
+
+import (
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	"github.com/streamsets/datacollector-edge/container/execution"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestWebhookNotifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	store.BaseDir = baseDir
+
+	var mutex sync.Mutex
+	requestPaths := make([]string, 0)
+	requestBodies := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		requestPaths = append(requestPaths, r.URL.Path)
+		requestBodies = append(requestBodies, string(body))
+		if len(requestPaths) == 1 {
+			// fail the first attempt to exercise the retry
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	pipelineConfig := common.PipelineConfiguration{
+		PipelineId: "testPipeline",
+		Configuration: []common.Config{
+			{Name: creation.NotifyOnStates, Value: []interface{}{common.RUN_ERROR}},
+			{Name: creation.Constants, Value: []interface{}{
+				map[string]interface{}{"key": "ALERT_PATH", "value": "alerts"},
+			}},
+			{Name: creation.WebHookConfigs, Value: []interface{}{
+				map[string]interface{}{
+					"webhookUrl": server.URL + "/${ALERT_PATH}",
+					"httpMethod": "POST",
+					"payload":    "pipeline failed",
+				},
+			}},
+		},
+	}
+
+	config := execution.NewConfig()
+	config.WebhookRetryBaseDelay = 1
+	webhookNotifier := NewWebhookNotifier("testPipeline", config)
+
+	// state not listed in notifyOnStates
+	webhookNotifier.Notify(pipelineConfig, nil, common.PipelineState{Status: common.STOPPED})
+	webhookNotifier.Notify(pipelineConfig, nil, common.PipelineState{Status: common.RUN_ERROR})
+
+	var webhookDeliveries []store.WebhookDelivery
+	for i := 0; i < 100 && len(webhookDeliveries) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if webhookDeliveries, err = store.GetWebhookDeliveries("testPipeline"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(webhookDeliveries) != 1 {
+		t.Fatalf("Expected 1 webhook delivery, but got %d", len(webhookDeliveries))
+	}
+	if !webhookDeliveries[0].Success || webhookDeliveries[0].Attempts != 2 ||
+		webhookDeliveries[0].State != common.RUN_ERROR {
+		t.Errorf("Expected successful delivery after 2 attempts, but got %v", webhookDeliveries[0])
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(requestPaths) != 2 || requestPaths[1] != "/alerts" || requestBodies[1] != "pipeline failed" {
+		t.Errorf("Unexpected webhook requests: %v %v", requestPaths, requestBodies)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxNextYears = 5
+
+var (
+	monthNames = map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}
+	dayOfWeekNames = map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// CronExpression is a standard five field cron expression: minute, hour, day of month, month and day of week.
+// Fields support '*', lists, ranges, steps and month and day names, and the @yearly, @monthly, @weekly, @daily and
+// @hourly descriptors are accepted. As in cron, when both day fields are restricted a day matching either matches.
+type CronExpression struct {
+	minutes     []bool
+	hours       []bool
+	daysOfMonth []bool
+	months      []bool
+	daysOfWeek  []bool
+	anyDay      bool
+	anyWeekday  bool
+	location    *time.Location
+}
+
+// Matches returns true if the expression matches the minute of the given time in the expression's time zone.
+func (c *CronExpression) Matches(t time.Time) bool {
+	t = t.In(c.location)
+	return c.minutes[t.Minute()] && c.hours[t.Hour()] && c.months[int(t.Month())] && c.matchesDay(t)
+}
+
+// Next returns the first time after the given time the expression matches, or the zero time if it doesn't match
+// within the next years, e.g. for the 30th of February.
+func (c *CronExpression) Next(after time.Time) time.Time {
+	t := after.In(c.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxNextYears, 0, 0)
+	for t.Before(limit) {
+		if !c.months[int(t.Month())] {
+			t = advance(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.location))
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.location))
+			continue
+		}
+		if !c.hours[t.Hour()] {
+			t = nextHour(t)
+			continue
+		}
+		if !c.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// advance returns next unless the wall clock time doesn't exist, as on the day daylight saving time starts, and
+// time.Date normalized it to a time that isn't after t. The start of the next hour is returned then.
+func advance(t time.Time, next time.Time) time.Time {
+	if !next.After(t) || (next.Day() == t.Day() && next.Month() == t.Month()) {
+		return nextHour(t)
+	}
+	return next
+}
+
+// nextHour returns the start of the next hour, also in time zones with offsets that aren't whole hours.
+func nextHour(t time.Time) time.Time {
+	return t.Add(time.Duration(60-t.Minute()) * time.Minute)
+}
+
+func (c *CronExpression) matchesDay(t time.Time) bool {
+	dayOfMonth := c.daysOfMonth[t.Day()]
+	dayOfWeek := c.daysOfWeek[int(t.Weekday())]
+	if c.anyDay || c.anyWeekday {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+// ParseCronExpression parses the expression, evaluated in the given time zone. An empty time zone is the local time
+// zone of the edge.
+func ParseCronExpression(expression string, timeZone string) (*CronExpression, error) {
+	location := time.Local
+	if timeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(timeZone); err != nil {
+			return nil, fmt.Errorf("invalid time zone '%s': %s", timeZone, err)
+		}
+	}
+
+	expression = strings.TrimSpace(expression)
+	if descriptor, ok := cronDescriptors[strings.ToLower(expression)]; ok {
+		expression = descriptor
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression '%s': expected 5 fields but found %d", expression, len(fields))
+	}
+
+	var err error
+	cronExpression := &CronExpression{
+		location:   location,
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+	if cronExpression.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid minute field in cron expression '%s': %s", expression, err)
+	}
+	if cronExpression.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid hour field in cron expression '%s': %s", expression, err)
+	}
+	if cronExpression.daysOfMonth, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid day of month field in cron expression '%s': %s", expression, err)
+	}
+	if cronExpression.months, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("invalid month field in cron expression '%s': %s", expression, err)
+	}
+	// 7 is accepted for Sunday as well
+	if cronExpression.daysOfWeek, err = parseCronField(fields[4], 0, 7, dayOfWeekNames); err != nil {
+		return nil, fmt.Errorf("invalid day of week field in cron expression '%s': %s", expression, err)
+	}
+	if cronExpression.daysOfWeek[7] {
+		cronExpression.daysOfWeek[0] = true
+	}
+	return cronExpression, nil
+}
+
+// parseCronField returns the values matched by the field, indexed by value.
+func parseCronField(field string, min int, max int, names map[string]int) ([]bool, error) {
+	values := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if index := strings.Index(part, "/"); index >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[index+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step '%s'", part[index+1:])
+			}
+			part = part[:index]
+		}
+
+		start, end := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = parseCronValue(bounds[0], min, max, names); err != nil {
+				return nil, err
+			}
+			if end, err = parseCronValue(bounds[1], min, max, names); err != nil {
+				return nil, err
+			}
+			if start > end {
+				return nil, fmt.Errorf("invalid range '%s'", part)
+			}
+		default:
+			value, err := parseCronValue(part, min, max, names)
+			if err != nil {
+				return nil, err
+			}
+			start = value
+			if step == 1 {
+				end = value
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+func parseCronValue(value string, min int, max int, names map[string]int) (int, error) {
+	if number, ok := names[strings.ToUpper(value)]; ok {
+		return number, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s'", value)
+	}
+	if number < min || number > max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", number, min, max)
+	}
+	return number, nil
+}
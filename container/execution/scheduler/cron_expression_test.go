@@ -0,0 +1,140 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronExpression(t *testing.T) {
+	validExpressions := []string{
+		"* * * * *",
+		"*/15 0-6,18-23 1,15 * MON-FRI",
+		"0 12 * JAN-MAR/2 7",
+		"@daily",
+		"@Hourly",
+	}
+	for _, expression := range validExpressions {
+		if _, err := ParseCronExpression(expression, "UTC"); err != nil {
+			t.Errorf("Expected '%s' to be valid but got: %s", expression, err)
+		}
+	}
+
+	invalidExpressions := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * FOO *",
+		"@reboot",
+	}
+	for _, expression := range invalidExpressions {
+		if _, err := ParseCronExpression(expression, "UTC"); err == nil {
+			t.Errorf("Expected '%s' to be invalid", expression)
+		}
+	}
+
+	if _, err := ParseCronExpression("* * * * *", "Invalid/TimeZone"); err == nil {
+		t.Error("Expected an error for an invalid time zone")
+	}
+}
+
+func TestCronExpression_Next(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("Time zone database not available: ", err)
+	}
+
+	testCases := []struct {
+		expression string
+		timeZone   string
+		after      time.Time
+		expected   time.Time
+	}{
+		{
+			expression: "* * * * *",
+			timeZone:   "UTC",
+			after:      time.Date(2018, 3, 1, 10, 15, 30, 0, time.UTC),
+			expected:   time.Date(2018, 3, 1, 10, 16, 0, 0, time.UTC),
+		},
+		{
+			expression: "30 9 * * MON-FRI",
+			timeZone:   "UTC",
+			after:      time.Date(2018, 3, 2, 9, 30, 0, 0, time.UTC), // Friday
+			expected:   time.Date(2018, 3, 5, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			expression: "0 0 31 * *",
+			timeZone:   "UTC",
+			after:      time.Date(2018, 3, 31, 12, 0, 0, 0, time.UTC),
+			expected:   time.Date(2018, 5, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// either day field matches when both are restricted
+			expression: "0 0 13 * FRI",
+			timeZone:   "UTC",
+			after:      time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC),
+			expected:   time.Date(2018, 3, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			expression: "0 8 * * *",
+			timeZone:   "America/New_York",
+			after:      time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC),
+			expected:   time.Date(2018, 3, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			// 02:30 doesn't exist on the day daylight saving time starts
+			expression: "30 2 * * *",
+			timeZone:   "America/New_York",
+			after:      time.Date(2018, 3, 10, 12, 0, 0, 0, newYork),
+			expected:   time.Date(2018, 3, 12, 2, 30, 0, 0, newYork),
+		},
+		{
+			expression: "0 0 1 1 *",
+			timeZone:   "Asia/Kolkata",
+			after:      time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC),
+			expected:   time.Date(2018, 12, 31, 18, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, testCase := range testCases {
+		cronExpression, err := ParseCronExpression(testCase.expression, testCase.timeZone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := cronExpression.Next(testCase.after)
+		if !next.Equal(testCase.expected) {
+			t.Errorf(
+				"Expected next time of '%s' after %s to be %s but got %s",
+				testCase.expression,
+				testCase.after,
+				testCase.expected,
+				next,
+			)
+		}
+		if !cronExpression.Matches(next) {
+			t.Errorf("Expected '%s' to match %s", testCase.expression, next)
+		}
+	}
+
+	cronExpression, _ := ParseCronExpression("0 0 30 FEB *", "UTC")
+	if next := cronExpression.Next(time.Now()); !next.IsZero() {
+		t.Errorf("Expected no next time for an expression that never matches but got %s", next)
+	}
+}
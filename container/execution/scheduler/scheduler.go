@@ -0,0 +1,300 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/execution/manager"
+	pipelineStateStore "github.com/streamsets/datacollector-edge/container/execution/store"
+	"github.com/streamsets/datacollector-edge/container/store"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"sync"
+	"time"
+)
+
+const (
+	SCHEDULE_ID   = "SCHEDULE_ID"
+	SCHEDULE_NAME = "SCHEDULE_NAME"
+)
+
+// Scheduler starts and stops pipelines on the cron schedules saved with the pipelines. Schedules are checked at
+// every minute, a schedule whose fire time passed since the last check fires once, and every fired schedule is
+// recorded in the pipeline history.
+type Scheduler struct {
+	manager           manager.Manager
+	pipelineStoreTask store.PipelineStoreTask
+	schedules         map[string][]*common.PipelineSchedule
+	lastCheck         time.Time
+	mutex             sync.Mutex
+	quit              chan bool
+	done              chan bool
+	now               func() time.Time
+}
+
+// Init loads the schedules of all pipelines.
+func (s *Scheduler) Init() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pipelines, err := s.pipelineStoreTask.GetPipelines()
+	if err != nil {
+		return err
+	}
+	for _, pipelineInfo := range pipelines {
+		schedules, err := s.pipelineStoreTask.RetrieveSchedules(pipelineInfo.PipelineId)
+		if err != nil {
+			log.WithError(err).WithField("id", pipelineInfo.PipelineId).Error("Failed to load pipeline schedules")
+			continue
+		}
+		if len(schedules) > 0 {
+			s.schedules[pipelineInfo.PipelineId] = schedules
+		}
+	}
+	s.lastCheck = s.now()
+	return nil
+}
+
+func (s *Scheduler) Run() {
+	defer close(s.done)
+	for {
+		now := s.now()
+		select {
+		case <-time.After(now.Truncate(time.Minute).Add(time.Minute).Sub(now)):
+			s.fireSchedules(s.now())
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) Stop() {
+	close(s.quit)
+	<-s.done
+}
+
+// GetSchedules returns the schedules of the pipeline along with their next fire time.
+func (s *Scheduler) GetSchedules(pipelineId string) ([]*common.PipelineSchedule, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	schedules, err := s.pipelineStoreTask.RetrieveSchedules(pipelineId)
+	if err != nil {
+		return schedules, err
+	}
+	now := s.now()
+	for _, schedule := range schedules {
+		if cronExpression, err := ParseCronExpression(schedule.CronExpression, schedule.TimeZone); err == nil {
+			if next := cronExpression.Next(now); schedule.Enabled && !next.IsZero() {
+				schedule.NextFireTimestamp = util.ConvertTimeToLong(next)
+			}
+		}
+	}
+	return schedules, nil
+}
+
+// SaveSchedule adds the schedule to the pipeline if it has no id, or replaces the schedule with the same id.
+func (s *Scheduler) SaveSchedule(
+	pipelineId string,
+	schedule common.PipelineSchedule,
+) (*common.PipelineSchedule, error) {
+	if err := validateSchedule(schedule); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	schedules, err := s.pipelineStoreTask.RetrieveSchedules(pipelineId)
+	if err != nil {
+		return nil, err
+	}
+	schedule.NextFireTimestamp = 0
+	if schedule.Id == "" {
+		schedule.Id = uuid.NewV4().String()
+		schedules = append(schedules, &schedule)
+	} else {
+		index := findSchedule(schedules, schedule.Id)
+		if index < 0 {
+			return nil, fmt.Errorf("schedule '%s' does not exist for pipeline '%s'", schedule.Id, pipelineId)
+		}
+		schedule.LastFiredTimestamp = schedules[index].LastFiredTimestamp
+		schedules[index] = &schedule
+	}
+
+	if err = s.pipelineStoreTask.SaveSchedules(pipelineId, schedules); err != nil {
+		return nil, err
+	}
+	s.schedules[pipelineId] = schedules
+	log.WithField("id", pipelineId).WithField("schedule", schedule.Name).Info("Saved pipeline schedule")
+	return &schedule, nil
+}
+
+func (s *Scheduler) DeleteSchedule(pipelineId string, scheduleId string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	schedules, err := s.pipelineStoreTask.RetrieveSchedules(pipelineId)
+	if err != nil {
+		return err
+	}
+	index := findSchedule(schedules, scheduleId)
+	if index < 0 {
+		return fmt.Errorf("schedule '%s' does not exist for pipeline '%s'", scheduleId, pipelineId)
+	}
+	schedules = append(schedules[:index], schedules[index+1:]...)
+
+	if err = s.pipelineStoreTask.SaveSchedules(pipelineId, schedules); err != nil {
+		return err
+	}
+	s.schedules[pipelineId] = schedules
+	log.WithField("id", pipelineId).WithField("schedule", scheduleId).Info("Deleted pipeline schedule")
+	return nil
+}
+
+// RemovePipeline drops the schedules of a deleted pipeline.
+func (s *Scheduler) RemovePipeline(pipelineId string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.schedules, pipelineId)
+}
+
+// fireSchedules fires the enabled schedules with a fire time between the last check and now.
+func (s *Scheduler) fireSchedules(now time.Time) {
+	type dueSchedule struct {
+		pipelineId string
+		schedule   common.PipelineSchedule
+	}
+	dueSchedules := make([]dueSchedule, 0)
+
+	s.mutex.Lock()
+	for pipelineId, schedules := range s.schedules {
+		fired := false
+		for _, schedule := range schedules {
+			if !schedule.Enabled {
+				continue
+			}
+			cronExpression, err := ParseCronExpression(schedule.CronExpression, schedule.TimeZone)
+			if err != nil {
+				log.WithError(err).WithField("id", pipelineId).Error("Invalid pipeline schedule")
+				continue
+			}
+			if next := cronExpression.Next(s.lastCheck); !next.IsZero() && !next.After(now) {
+				schedule.LastFiredTimestamp = util.ConvertTimeToLong(now)
+				dueSchedules = append(dueSchedules, dueSchedule{pipelineId: pipelineId, schedule: *schedule})
+				fired = true
+			}
+		}
+		if fired {
+			if err := s.pipelineStoreTask.SaveSchedules(pipelineId, schedules); err != nil {
+				log.WithError(err).WithField("id", pipelineId).Error("Failed to save pipeline schedules")
+			}
+		}
+	}
+	s.lastCheck = now
+	s.mutex.Unlock()
+
+	for _, due := range dueSchedules {
+		s.fire(due.pipelineId, due.schedule)
+	}
+}
+
+// fire starts or stops the pipeline and records the outcome in the pipeline history.
+func (s *Scheduler) fire(pipelineId string, schedule common.PipelineSchedule) {
+	log.WithField("id", pipelineId).
+		WithField("schedule", schedule.Name).
+		WithField("action", schedule.Action).
+		Info("Pipeline schedule fired")
+
+	verb, pastTense := "start", "started"
+	if schedule.Action == common.ScheduleActionStop {
+		verb, pastTense = "stop", "stopped"
+	}
+
+	pipelineState, err := s.runAction(pipelineId, schedule)
+	historyEntry := &common.PipelineState{
+		PipelineId: pipelineId,
+		TimeStamp:  util.ConvertTimeToLong(time.Now()),
+		Attributes: map[string]interface{}{
+			SCHEDULE_ID:   schedule.Id,
+			SCHEDULE_NAME: schedule.Name,
+		},
+	}
+	if err != nil {
+		log.WithError(err).WithField("id", pipelineId).WithField("schedule", schedule.Name).
+			Error("Pipeline schedule failed")
+		historyEntry.Message = fmt.Sprintf("Schedule '%s' failed to %s the pipeline: %s", schedule.Name, verb, err)
+		if currentState, err := pipelineStateStore.GetState(pipelineId); err == nil {
+			historyEntry.Status = currentState.Status
+		}
+	} else {
+		historyEntry.Status = pipelineState.Status
+		historyEntry.Message = fmt.Sprintf("Pipeline %s by schedule '%s'", pastTense, schedule.Name)
+	}
+
+	if err = pipelineStateStore.AddHistory(pipelineId, historyEntry); err != nil {
+		log.WithError(err).WithField("id", pipelineId).Error("Failed to record pipeline schedule in history")
+	}
+}
+
+func (s *Scheduler) runAction(
+	pipelineId string,
+	schedule common.PipelineSchedule,
+) (pipelineState *common.PipelineState, err error) {
+	// the manager panics if the runner of the pipeline can't be created
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	if schedule.Action == common.ScheduleActionStop {
+		return s.manager.StopPipeline(pipelineId)
+	}
+	return s.manager.StartPipeline(pipelineId, schedule.RuntimeParameters)
+}
+
+func validateSchedule(schedule common.PipelineSchedule) error {
+	if schedule.Action != common.ScheduleActionStart && schedule.Action != common.ScheduleActionStop {
+		return errors.New(fmt.Sprintf(
+			"invalid schedule action '%s', expected %s or %s",
+			schedule.Action,
+			common.ScheduleActionStart,
+			common.ScheduleActionStop,
+		))
+	}
+	_, err := ParseCronExpression(schedule.CronExpression, schedule.TimeZone)
+	return err
+}
+
+func findSchedule(schedules []*common.PipelineSchedule, scheduleId string) int {
+	for i, schedule := range schedules {
+		if schedule.Id == scheduleId {
+			return i
+		}
+	}
+	return -1
+}
+
+func NewScheduler(manager manager.Manager, pipelineStoreTask store.PipelineStoreTask) *Scheduler {
+	return &Scheduler{
+		manager:           manager,
+		pipelineStoreTask: pipelineStoreTask,
+		schedules:         make(map[string][]*common.PipelineSchedule),
+		quit:              make(chan bool),
+		done:              make(chan bool),
+		now:               time.Now,
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/execution"
+	pipelineStateStore "github.com/streamsets/datacollector-edge/container/execution/store"
+	"github.com/streamsets/datacollector-edge/container/store"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+type fakeManager struct {
+	started           []string
+	stopped           []string
+	runtimeParameters map[string]interface{}
+}
+
+func (m *fakeManager) CreatePreviewer(pipelineId string) (execution.Previewer, error) {
+	return nil, nil
+}
+
+func (m *fakeManager) GetPreviewer(previewerId string) (execution.Previewer, error) {
+	return nil, nil
+}
+
+func (m *fakeManager) GetRunner(pipelineId string) execution.Runner {
+	return nil
+}
+
+func (m *fakeManager) StartPipeline(
+	pipelineId string,
+	runtimeParameters map[string]interface{},
+) (*common.PipelineState, error) {
+	m.started = append(m.started, pipelineId)
+	m.runtimeParameters = runtimeParameters
+	return &common.PipelineState{PipelineId: pipelineId, Status: common.RUNNING}, nil
+}
+
+func (m *fakeManager) StopPipeline(pipelineId string) (*common.PipelineState, error) {
+	m.stopped = append(m.stopped, pipelineId)
+	return &common.PipelineState{PipelineId: pipelineId, Status: common.STOPPED}, nil
+}
+
+func (m *fakeManager) ResetOffset(pipelineId string) error {
+	return nil
+}
+
+func (m *fakeManager) DeletePipeline(pipelineId string) error {
+	return nil
+}
+
+func TestScheduler(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestScheduler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+
+	pipelineStoreTask := store.NewFilePipelineStoreTask(common.RuntimeInfo{BaseDir: baseDir})
+	if _, err = pipelineStoreTask.Create("testPipeline", "testPipeline", "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := &fakeManager{}
+	scheduler := NewScheduler(manager, pipelineStoreTask)
+	now := time.Date(2018, 3, 1, 8, 59, 30, 0, time.UTC)
+	scheduler.now = func() time.Time { return now }
+	if err = scheduler.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = scheduler.SaveSchedule("testPipeline", common.PipelineSchedule{
+		Action:         "RESTART",
+		CronExpression: "0 9 * * *",
+	}); err == nil {
+		t.Error("Expected an error for an invalid schedule action")
+	}
+	if _, err = scheduler.SaveSchedule("testPipeline", common.PipelineSchedule{
+		Action:         common.ScheduleActionStart,
+		CronExpression: "0 25 * * *",
+	}); err == nil {
+		t.Error("Expected an error for an invalid cron expression")
+	}
+
+	startSchedule, err := scheduler.SaveSchedule("testPipeline", common.PipelineSchedule{
+		Name:              "start",
+		Action:            common.ScheduleActionStart,
+		CronExpression:    "0 9 * * *",
+		TimeZone:          "UTC",
+		RuntimeParameters: map[string]interface{}{"param": "value"},
+		Enabled:           true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if startSchedule.Id == "" {
+		t.Error("Expected an id to be generated for the new schedule")
+	}
+	stopSchedule, err := scheduler.SaveSchedule("testPipeline", common.PipelineSchedule{
+		Name:           "stop",
+		Action:         common.ScheduleActionStop,
+		CronExpression: "0 17 * * *",
+		TimeZone:       "UTC",
+		Enabled:        true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schedules, err := scheduler.GetSchedules("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schedules) != 2 {
+		t.Fatalf("Expected 2 schedules but got %d", len(schedules))
+	}
+	expectedNextFire := time.Date(2018, 3, 1, 9, 0, 0, 0, time.UTC)
+	if schedules[0].NextFireTimestamp != expectedNextFire.UnixNano()/int64(time.Millisecond) {
+		t.Errorf("Expected next fire time %s but got %d", expectedNextFire, schedules[0].NextFireTimestamp)
+	}
+
+	// the schedules are also loaded from the store
+	scheduler = NewScheduler(manager, pipelineStoreTask)
+	scheduler.now = func() time.Time { return now }
+	if err = scheduler.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	scheduler.fireSchedules(now.Add(10 * time.Second))
+	if len(manager.started) != 0 || len(manager.stopped) != 0 {
+		t.Error("Expected no schedule to fire before its fire time")
+	}
+
+	// a check delayed by several minutes fires the schedule once
+	scheduler.fireSchedules(now.Add(5 * time.Minute))
+	if len(manager.started) != 1 || len(manager.stopped) != 0 {
+		t.Fatalf("Expected the pipeline to be started once but got %v", manager.started)
+	}
+	if manager.runtimeParameters["param"] != "value" {
+		t.Errorf("Expected the runtime parameters of the schedule but got %v", manager.runtimeParameters)
+	}
+	scheduler.fireSchedules(now.Add(6 * time.Minute))
+	if len(manager.started) != 1 {
+		t.Error("Expected the schedule to fire only once")
+	}
+
+	scheduler.fireSchedules(time.Date(2018, 3, 1, 17, 0, 0, 0, time.UTC))
+	if len(manager.stopped) != 1 {
+		t.Error("Expected the pipeline to be stopped")
+	}
+
+	history, err := pipelineStateStore.GetHistory("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduledEntries := make([]*common.PipelineState, 0)
+	for _, pipelineState := range history {
+		if pipelineState.Attributes[SCHEDULE_ID] != nil {
+			scheduledEntries = append(scheduledEntries, pipelineState)
+		}
+	}
+	if len(scheduledEntries) != 2 {
+		t.Fatalf("Expected 2 schedule entries in the history but got %d", len(scheduledEntries))
+	}
+	if scheduledEntries[0].Status != common.RUNNING || scheduledEntries[0].Attributes[SCHEDULE_ID] != startSchedule.Id {
+		t.Errorf("Unexpected history entry for the start schedule: %v", scheduledEntries[0])
+	}
+	if scheduledEntries[1].Status != common.STOPPED || scheduledEntries[1].Attributes[SCHEDULE_ID] != stopSchedule.Id {
+		t.Errorf("Unexpected history entry for the stop schedule: %v", scheduledEntries[1])
+	}
+
+	if err = scheduler.DeleteSchedule("testPipeline", stopSchedule.Id); err != nil {
+		t.Fatal(err)
+	}
+	if err = scheduler.DeleteSchedule("testPipeline", stopSchedule.Id); err == nil {
+		t.Error("Expected an error deleting a schedule that does not exist")
+	}
+	schedules, _ = scheduler.GetSchedules("testPipeline")
+	if len(schedules) != 1 || schedules[0].LastFiredTimestamp == 0 {
+		t.Errorf("Expected the fired start schedule to remain but got %v", schedules)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"encoding/json"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	ALERTS_FILE = "alerts.json"
+)
+
+func GetAlerts(pipelineId string) ([]*common.AlertInfo, error) {
+	alerts := make([]*common.AlertInfo, 0)
+	fileExists, err := checkFileExists(getPipelineAlertsFile(pipelineId))
+	if err != nil || !fileExists {
+		return alerts, err
+	}
+
+	file, err := ioutil.ReadFile(getPipelineAlertsFile(pipelineId))
+	if err != nil {
+		return alerts, err
+	}
+	err = json.Unmarshal(file, &alerts)
+	return alerts, err
+}
+
+func SaveAlerts(pipelineId string, alerts []*common.AlertInfo) error {
+	if err := os.MkdirAll(GetRunInfoDir(pipelineId), os.ModePerm); err != nil {
+		return err
+	}
+	alertsJson, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(getPipelineAlertsFile(pipelineId), alertsJson, 0644)
+}
+
+func getPipelineAlertsFile(pipelineId string) string {
+	return GetRunInfoDir(pipelineId) + ALERTS_FILE
+}
@@ -0,0 +1,120 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	TMP_FILE_SUFFIX    = ".tmp"
+	BACKUP_FILE_SUFFIX = ".bak"
+	checksumPrefix     = "\n#crc32:"
+)
+
+var ErrCorruptedFile = errors.New("file is corrupted")
+
+// writeFileAtomic replaces the given file so that a crash at any point leaves either the previous or the new
+// content behind. The content is written with a checksum to a temporary file and synced before it is renamed over
+// the file, and the previous generation, if valid, is kept as a backup to fall back to.
+func writeFileAtomic(fileName string, data []byte) error {
+	content := make([]byte, 0, len(data)+len(checksumPrefix)+8)
+	content = append(content, data...)
+	content = append(content, fmt.Sprintf("%s%08x", checksumPrefix, crc32.ChecksumIEEE(data))...)
+
+	tmpFileName := fileName + TMP_FILE_SUFFIX
+	tmpFile, err := os.OpenFile(tmpFileName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if _, err = readVerifiedFile(fileName); err == nil {
+		if err = os.Rename(fileName, fileName+BACKUP_FILE_SUFFIX); err != nil {
+			return err
+		}
+	}
+	if err = os.Rename(tmpFileName, fileName); err != nil {
+		return err
+	}
+	return util.SyncDir(filepath.Dir(fileName))
+}
+
+// readFileAtomic returns the content of a file written by writeFileAtomic, falling back to the previous generation
+// when the file is missing or fails the checksum. An error satisfying os.IsNotExist is returned if neither exists.
+func readFileAtomic(fileName string) ([]byte, error) {
+	data, err := readVerifiedFile(fileName)
+	if err == nil {
+		return data, nil
+	}
+
+	backupData, backupErr := readVerifiedFile(fileName + BACKUP_FILE_SUFFIX)
+	if backupErr == nil {
+		log.WithError(err).WithField("file", fileName).Warn("Falling back to the last good generation of file")
+		return backupData, nil
+	}
+
+	if os.IsNotExist(err) && os.IsNotExist(backupErr) {
+		return nil, err
+	}
+	if os.IsNotExist(err) {
+		err = backupErr
+	}
+	return nil, fmt.Errorf("failed to read '%s': %s", fileName, err)
+}
+
+// readVerifiedFile reads the file and verifies its checksum. Files written before checksums were added have no
+// checksum and are only accepted if they hold valid JSON.
+func readVerifiedFile(fileName string) ([]byte, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 0 {
+		return nil, ErrCorruptedFile
+	}
+
+	index := bytes.LastIndex(content, []byte(checksumPrefix))
+	if index < 0 {
+		if !json.Valid(content) {
+			return nil, ErrCorruptedFile
+		}
+		return content, nil
+	}
+	data := content[:index]
+	checksum, err := strconv.ParseUint(string(content[index+len(checksumPrefix):]), 16, 32)
+	if err != nil || uint32(checksum) != crc32.ChecksumIEEE(data) {
+		return nil, ErrCorruptedFile
+	}
+	return data, nil
+}
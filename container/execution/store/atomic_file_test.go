@@ -0,0 +1,144 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"github.com/streamsets/datacollector-edge/container/common"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestAtomicFile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "test.json")
+
+	if _, err = readFileAtomic(fileName); !os.IsNotExist(err) {
+		t.Fatalf("Expected not exist error, got: %v", err)
+	}
+
+	if err = writeFileAtomic(fileName, []byte(`{"gen":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = writeFileAtomic(fileName, []byte(`{"gen":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := readFileAtomic(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"gen":2}` {
+		t.Errorf("Expected latest generation, got: %s", data)
+	}
+
+	// simulate a torn write of the current generation
+	if err = ioutil.WriteFile(fileName, []byte(`{"ge`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, err = readFileAtomic(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"gen":1}` {
+		t.Errorf("Expected fallback to previous generation, got: %s", data)
+	}
+
+	// a corrupted file must not replace the last good generation
+	if err = writeFileAtomic(fileName, []byte(`{"gen":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = readVerifiedFile(fileName + BACKUP_FILE_SUFFIX); err != nil || string(data) != `{"gen":1}` {
+		t.Errorf("Expected backup to keep last good generation, got: %s, %v", data, err)
+	}
+
+	if err = ioutil.WriteFile(fileName, []byte(`{"ge`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(fileName+BACKUP_FILE_SUFFIX, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = readFileAtomic(fileName); err == nil || os.IsNotExist(err) {
+		t.Errorf("Expected corrupted file error, got: %v", err)
+	}
+}
+
+func TestOffsetStoreCorruptedFile(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestOffsetStoreCorruptedFile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	BaseDir = baseDir
+	if err = os.MkdirAll(GetRunInfoDir("testPipeline"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(getPipelineOffsetFile("testPipeline"), []byte(`{"version":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GetOffset("testPipeline"); err == nil {
+		t.Error("Expected error for corrupted offset file")
+	}
+}
+
+func TestStateHistoryCompaction(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestStateHistoryCompaction")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	BaseDir = baseDir
+
+	maxSize, maxEntries := StateHistoryMaxSize, StateHistoryMaxEntries
+	defer func() {
+		StateHistoryMaxSize, StateHistoryMaxEntries = maxSize, maxEntries
+	}()
+	StateHistoryMaxSize = 2048
+	StateHistoryMaxEntries = 5
+
+	if _, err = GetState("testPipeline"); err != nil {
+		t.Fatal(err)
+	}
+	statuses := []string{common.STARTING, common.RUNNING, common.RETRY, common.RETRY, common.RETRY, common.RUNNING}
+	for i := 0; i < 10; i++ {
+		for _, status := range statuses {
+			if err = SaveState("testPipeline", &common.PipelineState{PipelineId: "testPipeline", Status: status}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	history, err := GetHistory("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) == 0 || len(history) >= 1+len(statuses)*10 {
+		t.Errorf("Expected history to be compacted, got %d entries", len(history))
+	}
+	if _, err = os.Stat(getPipelineStateHistoryFile("testPipeline") + ROTATED_HISTORY_FILE_SUFFIX); err != nil {
+		t.Errorf("Expected rotated history file: %v", err)
+	}
+
+	state, err := GetState("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != common.RUNNING {
+		t.Errorf("Expected status RUNNING, got: %s", state.Status)
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/streamsets/datacollector-edge/container/common"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"time"
+)
+
+var (
+	pipelinesBucket = []byte("pipelines")
+	runInfoBucket   = []byte("runInfo")
+	historyBucket   = []byte("history")
+	offsetKey       = []byte("offset")
+	stateKey        = []byte("state")
+)
+
+// BoltRuntimeStore keeps pipelines in an embedded transactional key-value database. Every pipeline has a bucket
+// with its documents in the pipelines bucket, and a bucket with its offset, state and state history in the runInfo
+// bucket.
+type BoltRuntimeStore struct {
+	db *bolt.DB
+}
+
+func (s *BoltRuntimeStore) GetOffset(pipelineId string) (common.SourceOffset, error) {
+	sourceOffset := common.GetDefaultOffset()
+	err := s.db.View(func(tx *bolt.Tx) error {
+		offsetJson := getValue(tx, runInfoBucket, pipelineId, offsetKey)
+		if offsetJson == nil {
+			return nil
+		}
+		if err := json.Unmarshal(offsetJson, &sourceOffset); err != nil {
+			return fmt.Errorf("failed to parse offset of pipeline '%s': %s", pipelineId, err)
+		}
+		return nil
+	})
+	return sourceOffset, err
+}
+
+func (s *BoltRuntimeStore) SaveOffset(pipelineId string, sourceOffset common.SourceOffset) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putOffset(tx, pipelineId, sourceOffset)
+	})
+}
+
+func (s *BoltRuntimeStore) GetState(pipelineId string) (*common.PipelineState, error) {
+	var pipelineState *common.PipelineState
+	err := s.db.View(func(tx *bolt.Tx) error {
+		stateJson := getValue(tx, runInfoBucket, pipelineId, stateKey)
+		if stateJson == nil {
+			return nil
+		}
+		pipelineState = &common.PipelineState{}
+		if err := json.Unmarshal(stateJson, pipelineState); err != nil {
+			return fmt.Errorf("failed to parse state of pipeline '%s': %s", pipelineId, err)
+		}
+		return nil
+	})
+	return pipelineState, err
+}
+
+func (s *BoltRuntimeStore) SaveState(pipelineId string, pipelineState *common.PipelineState) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putState(tx, pipelineId, pipelineState)
+	})
+}
+
+func (s *BoltRuntimeStore) AddHistory(pipelineId string, pipelineState *common.PipelineState) error {
+	pipelineStateJson, err := json.Marshal(pipelineState)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := createBucket(tx, runInfoBucket, pipelineId)
+		if err != nil {
+			return err
+		}
+		return putHistory(bucket, pipelineStateJson)
+	})
+}
+
+func (s *BoltRuntimeStore) SaveOffsetAndState(
+	pipelineId string,
+	sourceOffset common.SourceOffset,
+	pipelineState *common.PipelineState,
+) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := putOffset(tx, pipelineId, sourceOffset); err != nil {
+			return err
+		}
+		return putState(tx, pipelineId, pipelineState)
+	})
+}
+
+func (s *BoltRuntimeStore) GetHistory(pipelineId string) ([]*common.PipelineState, error) {
+	history_of_states := []*common.PipelineState{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := getBucket(tx, runInfoBucket, pipelineId)
+		if bucket == nil || bucket.Bucket(historyBucket) == nil {
+			return nil
+		}
+		return bucket.Bucket(historyBucket).ForEach(func(k, v []byte) error {
+			var pipelineState common.PipelineState
+			if err := json.Unmarshal(v, &pipelineState); err != nil {
+				return err
+			}
+			history_of_states = append(history_of_states, &pipelineState)
+			return nil
+		})
+	})
+	return history_of_states, err
+}
+
+func (s *BoltRuntimeStore) GetPipelineIds() ([]string, error) {
+	pipelineIds := make([]string, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(pipelinesBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if v == nil {
+				pipelineIds = append(pipelineIds, string(k))
+			}
+			return nil
+		})
+	})
+	return pipelineIds, err
+}
+
+func (s *BoltRuntimeStore) GetPipelineDocument(pipelineId string, name string) ([]byte, error) {
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if value := getValue(tx, pipelinesBucket, pipelineId, []byte(name)); value != nil {
+			// values are only valid during the transaction
+			data = append([]byte{}, value...)
+		}
+		return nil
+	})
+	if err == nil && data == nil {
+		err = &os.PathError{Op: "get", Path: pipelineId + "/" + name, Err: os.ErrNotExist}
+	}
+	return data, err
+}
+
+func (s *BoltRuntimeStore) SavePipelineDocument(pipelineId string, name string, data []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := createBucket(tx, pipelinesBucket, pipelineId)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(name), data)
+	})
+}
+
+func (s *BoltRuntimeStore) DeletePipeline(pipelineId string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{pipelinesBucket, runInfoBucket} {
+			if bucket := tx.Bucket(name); bucket != nil && bucket.Bucket([]byte(pipelineId)) != nil {
+				if err := bucket.DeleteBucket([]byte(pipelineId)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltRuntimeStore) Close() error {
+	return s.db.Close()
+}
+
+func putOffset(tx *bolt.Tx, pipelineId string, sourceOffset common.SourceOffset) error {
+	offsetJson, err := json.Marshal(sourceOffset)
+	if err != nil {
+		return err
+	}
+	bucket, err := createBucket(tx, runInfoBucket, pipelineId)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(offsetKey, offsetJson)
+}
+
+// putState saves the state and appends it to the history.
+func putState(tx *bolt.Tx, pipelineId string, pipelineState *common.PipelineState) error {
+	pipelineStateJson, err := json.Marshal(pipelineState)
+	if err != nil {
+		return err
+	}
+	bucket, err := createBucket(tx, runInfoBucket, pipelineId)
+	if err != nil {
+		return err
+	}
+	if err = bucket.Put(stateKey, pipelineStateJson); err != nil {
+		return err
+	}
+	return putHistory(bucket, pipelineStateJson)
+}
+
+// putHistory appends the state to the history, which keeps the most recent StateHistoryMaxEntries states.
+func putHistory(bucket *bolt.Bucket, pipelineStateJson []byte) error {
+	history, err := bucket.CreateBucketIfNotExists(historyBucket)
+	if err != nil {
+		return err
+	}
+	sequence, err := history.NextSequence()
+	if err != nil {
+		return err
+	}
+	if err = history.Put(sequenceKey(sequence), pipelineStateJson); err != nil {
+		return err
+	}
+	if sequence > uint64(StateHistoryMaxEntries) {
+		return history.Delete(sequenceKey(sequence - uint64(StateHistoryMaxEntries)))
+	}
+	return nil
+}
+
+func getBucket(tx *bolt.Tx, name []byte, pipelineId string) *bolt.Bucket {
+	bucket := tx.Bucket(name)
+	if bucket == nil {
+		return nil
+	}
+	return bucket.Bucket([]byte(pipelineId))
+}
+
+func getValue(tx *bolt.Tx, name []byte, pipelineId string, key []byte) []byte {
+	bucket := getBucket(tx, name, pipelineId)
+	if bucket == nil {
+		return nil
+	}
+	return bucket.Get(key)
+}
+
+func createBucket(tx *bolt.Tx, name []byte, pipelineId string) (*bolt.Bucket, error) {
+	bucket, err := tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return bucket.CreateBucketIfNotExists([]byte(pipelineId))
+}
+
+// sequenceKey encodes the sequence big endian, so history entries are iterated in the order they were added.
+func sequenceKey(sequence uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, sequence)
+	return key
+}
+
+func NewBoltRuntimeStore(dbFile string) (RuntimeStore, error) {
+	db, err := bolt.Open(dbFile, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &BoltRuntimeStore{db: db}, nil
+}
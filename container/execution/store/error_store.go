@@ -0,0 +1,400 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/recordio/sdcrecord"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	ERRORS_FOLDER              = "errors"
+	ERROR_RECORDS_FILE_PREFIX  = "errorRecords"
+	ERROR_MESSAGES_FILE_PREFIX = "errorMessages"
+	ERROR_FILE_SUFFIX          = ".json"
+)
+
+// StageErrorMessage is an error message reported by a stage, as kept in the error store.
+type StageErrorMessage struct {
+	StageInstanceName string `json:"stageInstanceName"`
+	api.ErrorMessage
+}
+
+// ErrorQuery filters and pages the entries of the error store. Entries are returned newest first, Offset entries are
+// skipped before returning at most Size entries. Zero values disable the corresponding filter.
+type ErrorQuery struct {
+	StageInstanceName string
+	StartTime         int64
+	EndTime           int64
+	SourceIds         []string
+	Offset            int
+	Size              int
+}
+
+func (q ErrorQuery) matches(stageInstanceName string, timestamp int64, sourceId string) bool {
+	if q.StageInstanceName != "" && q.StageInstanceName != stageInstanceName {
+		return false
+	}
+	if q.StartTime > 0 && timestamp < q.StartTime {
+		return false
+	}
+	if q.EndTime > 0 && timestamp > q.EndTime {
+		return false
+	}
+	if len(q.SourceIds) > 0 {
+		for _, id := range q.SourceIds {
+			if id == sourceId {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (q ErrorQuery) page(count int) (int, int) {
+	start := q.Offset
+	if start > count {
+		start = count
+	}
+	end := count
+	if q.Size > 0 && start+q.Size < count {
+		end = start + q.Size
+	}
+	return start, end
+}
+
+// rotatingFile appends JSON lines to numbered files, starting a new file when the current one reaches maxFileSize
+// and deleting the oldest files so that at most maxFiles are kept.
+type rotatingFile struct {
+	dir         string
+	prefix      string
+	maxFileSize int64
+	maxFiles    int
+	index       int
+	size        int64
+}
+
+func (f *rotatingFile) fileName(index int) string {
+	return filepath.Join(f.dir, fmt.Sprintf("%s.%d%s", f.prefix, index, ERROR_FILE_SUFFIX))
+}
+
+// indexes returns the indexes of the existing files, oldest first.
+func (f *rotatingFile) indexes() ([]int, error) {
+	fileInfos, err := ioutil.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+	indexes := make([]int, 0)
+	for _, fileInfo := range fileInfos {
+		name := fileInfo.Name()
+		if !strings.HasPrefix(name, f.prefix+".") || !strings.HasSuffix(name, ERROR_FILE_SUFFIX) {
+			continue
+		}
+		indexString := strings.TrimSuffix(strings.TrimPrefix(name, f.prefix+"."), ERROR_FILE_SUFFIX)
+		if index, err := strconv.Atoi(indexString); err == nil {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+func (f *rotatingFile) init() error {
+	indexes, err := f.indexes()
+	if err != nil {
+		return err
+	}
+	if len(indexes) > 0 {
+		f.index = indexes[len(indexes)-1]
+		if fileInfo, err := os.Stat(f.fileName(f.index)); err == nil {
+			f.size = fileInfo.Size()
+		}
+	}
+	return nil
+}
+
+func (f *rotatingFile) write(lines [][]byte) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(f.fileName(f.index), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		if f.maxFileSize > 0 && f.size > 0 && f.size+int64(len(line)) > f.maxFileSize {
+			if err = file.Close(); err != nil {
+				return err
+			}
+			if err = f.rotate(); err != nil {
+				return err
+			}
+			if file, err = os.OpenFile(f.fileName(f.index), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+				return err
+			}
+		}
+		if _, err = file.Write(line); err != nil {
+			file.Close()
+			return err
+		}
+		f.size += int64(len(line))
+	}
+	return file.Close()
+}
+
+func (f *rotatingFile) rotate() error {
+	f.index++
+	f.size = 0
+	indexes, err := f.indexes()
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if f.maxFiles > 0 && index <= f.index-f.maxFiles {
+			if err = os.Remove(f.fileName(index)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readLines calls the given function with every line of every file, oldest first.
+func (f *rotatingFile) readLines(lineFunc func(line []byte) error) error {
+	indexes, err := f.indexes()
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		file, err := os.Open(f.fileName(index))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			if err = lineFunc(scanner.Bytes()); err != nil {
+				file.Close()
+				return err
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *rotatingFile) clear() error {
+	indexes, err := f.indexes()
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if err = os.Remove(f.fileName(index)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	f.index = 0
+	f.size = 0
+	return nil
+}
+
+// ErrorStore persists the error records and error messages of a pipeline in bounded, rotating files under the
+// pipeline run info directory, so they can be queried after the pipeline is stopped or the edge process restarts.
+type ErrorStore struct {
+	pipelineId        string
+	errorRecordsFile  *rotatingFile
+	errorMessagesFile *rotatingFile
+	mutex             sync.Mutex
+}
+
+func (s *ErrorStore) SaveErrorRecords(stageErrorRecords map[string][]api.Record) error {
+	lines := make([][]byte, 0)
+	sdcRecords := make([]*sdcrecord.SDCRecord, 0)
+	for _, errorRecords := range stageErrorRecords {
+		for _, errorRecord := range errorRecords {
+			sdcRecord, err := sdcrecord.NewSdcRecordFromRecord(errorRecord)
+			if err != nil {
+				log.WithError(err).Error("Failed to convert error record")
+				continue
+			}
+			line, err := json.Marshal(sdcRecord)
+			if err != nil {
+				log.WithError(err).Error("Failed to serialize error record")
+				continue
+			}
+			lines = append(lines, append(line, '\n'))
+			sdcRecords = append(sdcRecords, sdcRecord)
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.errorRecordsFile.write(lines); err != nil {
+		return err
+	}
+	for _, sdcRecord := range sdcRecords {
+		PublishEvent(EVENT_ERROR_RECORD, s.pipelineId, sdcRecord)
+	}
+	return nil
+}
+
+func (s *ErrorStore) SaveErrorMessages(stageErrorMessages map[string][]api.ErrorMessage) error {
+	lines := make([][]byte, 0)
+	for stageInstanceName, errorMessages := range stageErrorMessages {
+		for _, errorMessage := range errorMessages {
+			line, err := json.Marshal(StageErrorMessage{
+				StageInstanceName: stageInstanceName,
+				ErrorMessage:      errorMessage,
+			})
+			if err != nil {
+				log.WithError(err).Error("Failed to serialize error message")
+				continue
+			}
+			lines = append(lines, append(line, '\n'))
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.errorMessagesFile.write(lines)
+}
+
+func (s *ErrorStore) GetErrorRecords(query ErrorQuery) ([]sdcrecord.SDCRecord, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	errorRecords := make([]sdcrecord.SDCRecord, 0)
+	err := s.errorRecordsFile.readLines(func(line []byte) error {
+		var errorRecord sdcrecord.SDCRecord
+		if err := json.Unmarshal(line, &errorRecord); err != nil {
+			log.WithError(err).Warn("Skipping invalid entry in error store")
+			return nil
+		}
+		if errorRecord.Header == nil {
+			errorRecord.Header = &common.HeaderImpl{}
+		}
+		if query.matches(
+			errorRecord.Header.ErrorStageInstance,
+			errorRecord.Header.ErrorTimestamp,
+			errorRecord.Header.SourceId,
+		) {
+			errorRecords = append(errorRecords, errorRecord)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// newest first
+	for i, j := 0, len(errorRecords)-1; i < j; i, j = i+1, j-1 {
+		errorRecords[i], errorRecords[j] = errorRecords[j], errorRecords[i]
+	}
+	start, end := query.page(len(errorRecords))
+	return errorRecords[start:end], nil
+}
+
+func (s *ErrorStore) GetErrorMessages(query ErrorQuery) ([]StageErrorMessage, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	errorMessages := make([]StageErrorMessage, 0)
+	err := s.errorMessagesFile.readLines(func(line []byte) error {
+		var errorMessage StageErrorMessage
+		if err := json.Unmarshal(line, &errorMessage); err != nil {
+			log.WithError(err).Warn("Skipping invalid entry in error store")
+			return nil
+		}
+		if query.matches(errorMessage.StageInstanceName, errorMessage.Timestamp, "") {
+			errorMessages = append(errorMessages, errorMessage)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// newest first
+	for i, j := 0, len(errorMessages)-1; i < j; i, j = i+1, j-1 {
+		errorMessages[i], errorMessages[j] = errorMessages[j], errorMessages[i]
+	}
+	start, end := query.page(len(errorMessages))
+	return errorMessages[start:end], nil
+}
+
+func (s *ErrorStore) Clear() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.errorRecordsFile.clear(); err != nil {
+		return err
+	}
+	return s.errorMessagesFile.clear()
+}
+
+// NewErrorStore returns the error store of the given pipeline, keeping at most maxFiles files of maxFileSize bytes
+// each for error records and for error messages.
+func NewErrorStore(pipelineId string, maxFileSize int64, maxFiles int) (*ErrorStore, error) {
+	dir := filepath.Join(GetRunInfoDir(pipelineId), ERRORS_FOLDER)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	errorStore := &ErrorStore{
+		pipelineId: pipelineId,
+		errorRecordsFile: &rotatingFile{
+			dir:         dir,
+			prefix:      ERROR_RECORDS_FILE_PREFIX,
+			maxFileSize: maxFileSize,
+			maxFiles:    maxFiles,
+		},
+		errorMessagesFile: &rotatingFile{
+			dir:         dir,
+			prefix:      ERROR_MESSAGES_FILE_PREFIX,
+			maxFileSize: maxFileSize,
+			maxFiles:    maxFiles,
+		},
+	}
+
+	if err := errorStore.errorRecordsFile.init(); err != nil {
+		return nil, err
+	}
+	if err := errorStore.errorMessagesFile.init(); err != nil {
+		return nil, err
+	}
+	return errorStore, nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"errors"
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func createErrorRecord(t *testing.T, stageInstanceName string, sourceId string) api.Record {
+	stageContext := &common.StageContextImpl{
+		StageConfig:       &common.StageConfiguration{InstanceName: stageInstanceName},
+		ErrorSink:         common.NewErrorSink(),
+		ErrorRecordPolicy: common.ErrorRecordPolicyStage,
+	}
+	record, err := stageContext.CreateRecord(sourceId, "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stageContext.ToError(errors.New("sample error"), record)
+	return stageContext.ErrorSink.GetStageErrorRecords(stageInstanceName)[0]
+}
+
+func TestErrorStore(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestErrorStore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	BaseDir = baseDir
+
+	errorStore, err := NewErrorStore("testPipeline", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = errorStore.SaveErrorRecords(map[string][]api.Record{
+		"stage1": {createErrorRecord(t, "stage1", "record1"), createErrorRecord(t, "stage1", "record2")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = errorStore.SaveErrorRecords(map[string][]api.Record{
+		"stage2": {createErrorRecord(t, "stage2", "record3")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errorRecords, err := errorStore.GetErrorRecords(ErrorQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errorRecords) != 3 || errorRecords[0].Header.SourceId != "record3" {
+		t.Errorf("Expected 3 error records, newest first, but got %v", errorRecords)
+	}
+
+	errorRecords, err = errorStore.GetErrorRecords(ErrorQuery{StageInstanceName: "stage1", Offset: 1, Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errorRecords) != 1 || errorRecords[0].Header.SourceId != "record1" {
+		t.Errorf("Expected error record 'record1', but got %v", errorRecords)
+	}
+
+	err = errorStore.SaveErrorMessages(map[string][]api.ErrorMessage{
+		"stage1": {{LocalizableMessage: "message1", Timestamp: 10}, {LocalizableMessage: "message2", Timestamp: 20}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errorMessages, err := errorStore.GetErrorMessages(ErrorQuery{StartTime: 15})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errorMessages) != 1 || errorMessages[0].LocalizableMessage != "message2" ||
+		errorMessages[0].StageInstanceName != "stage1" {
+		t.Errorf("Expected error message 'message2', but got %v", errorMessages)
+	}
+}
+
+func TestErrorStoreRotation(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestErrorStoreRotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	BaseDir = baseDir
+
+	// every message goes to its own file and only two files are kept
+	errorStore, err := NewErrorStore("testPipeline", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, message := range []string{"message1", "message2", "message3"} {
+		err = errorStore.SaveErrorMessages(map[string][]api.ErrorMessage{
+			"stage1": {{LocalizableMessage: message}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a new store picks up the existing files
+	errorStore, err = NewErrorStore("testPipeline", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errorMessages, err := errorStore.GetErrorMessages(ErrorQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errorMessages) != 2 || errorMessages[0].LocalizableMessage != "message3" ||
+		errorMessages[1].LocalizableMessage != "message2" {
+		t.Errorf("Expected error messages 'message3' and 'message2', but got %v", errorMessages)
+	}
+}
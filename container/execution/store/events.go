@@ -0,0 +1,115 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"sync"
+	"time"
+)
+
+const (
+	EVENT_STATE        = "state"
+	EVENT_ALERT        = "alert"
+	EVENT_ERROR_RECORD = "errorRecord"
+	EVENT_METRICS      = "metrics"
+)
+
+var (
+	eventSubscribers      = make(map[*EventSubscription]bool)
+	eventSubscribersMutex sync.RWMutex
+)
+
+// PipelineEvent is a change of a pipeline pushed to the subscribers of pipeline events.
+type PipelineEvent struct {
+	Type       string      `json:"type"`
+	PipelineId string      `json:"pipelineId"`
+	Timestamp  int64       `json:"timestamp"`
+	Data       interface{} `json:"data"`
+}
+
+// EventSubscription receives the events of the subscribed pipelines, or of all pipelines if it was subscribed
+// without pipeline ids. Events are dropped while its buffer is full so that slow subscribers never block pipelines.
+type EventSubscription struct {
+	pipelineIds map[string]bool
+	events      chan PipelineEvent
+}
+
+func (s *EventSubscription) Events() <-chan PipelineEvent {
+	return s.events
+}
+
+func (s *EventSubscription) IsSubscribed(pipelineId string) bool {
+	return len(s.pipelineIds) == 0 || s.pipelineIds[pipelineId]
+}
+
+// SubscribeEvents subscribes to the events of the given pipelines, or of all pipelines if none are given.
+func SubscribeEvents(pipelineIds []string, bufferSize int) *EventSubscription {
+	subscription := &EventSubscription{
+		pipelineIds: make(map[string]bool),
+		events:      make(chan PipelineEvent, bufferSize),
+	}
+	for _, pipelineId := range pipelineIds {
+		subscription.pipelineIds[pipelineId] = true
+	}
+
+	eventSubscribersMutex.Lock()
+	defer eventSubscribersMutex.Unlock()
+	eventSubscribers[subscription] = true
+	return subscription
+}
+
+func UnsubscribeEvents(subscription *EventSubscription) {
+	eventSubscribersMutex.Lock()
+	defer eventSubscribersMutex.Unlock()
+	delete(eventSubscribers, subscription)
+}
+
+// HasEventSubscribers returns true if a subscriber receives the events of the given pipeline.
+func HasEventSubscribers(pipelineId string) bool {
+	eventSubscribersMutex.RLock()
+	defer eventSubscribersMutex.RUnlock()
+	for subscription := range eventSubscribers {
+		if subscription.IsSubscribed(pipelineId) {
+			return true
+		}
+	}
+	return false
+}
+
+// PublishEvent sends an event to the subscribers of the pipeline.
+func PublishEvent(eventType string, pipelineId string, data interface{}) {
+	eventSubscribersMutex.RLock()
+	defer eventSubscribersMutex.RUnlock()
+	if len(eventSubscribers) == 0 {
+		return
+	}
+
+	event := PipelineEvent{
+		Type:       eventType,
+		PipelineId: pipelineId,
+		Timestamp:  util.ConvertTimeToLong(time.Now()),
+		Data:       data,
+	}
+	for subscription := range eventSubscribers {
+		if !subscription.IsSubscribed(pipelineId) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			log.WithField("id", pipelineId).WithField("type", eventType).Debug("Dropped event for slow subscriber")
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"encoding/json"
+	"github.com/streamsets/datacollector-edge/api"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestPublishEvent(t *testing.T) {
+	allSubscription := SubscribeEvents(nil, 10)
+	defer UnsubscribeEvents(allSubscription)
+	pipelineSubscription := SubscribeEvents([]string{"pipeline1"}, 1)
+
+	if !HasEventSubscribers("pipeline2") {
+		t.Error("Expected a subscriber for all pipelines")
+	}
+
+	PublishEvent(EVENT_ALERT, "pipeline1", "alert1")
+	PublishEvent(EVENT_ALERT, "pipeline2", "alert2")
+	// the buffer of the pipeline subscription is full, the event is dropped instead of blocking
+	PublishEvent(EVENT_ALERT, "pipeline1", "alert3")
+
+	if len(allSubscription.Events()) != 3 {
+		t.Errorf("Expected 3 events for all pipelines, got %d", len(allSubscription.Events()))
+	}
+	if len(pipelineSubscription.Events()) != 1 {
+		t.Fatalf("Expected 1 event for pipeline1, got %d", len(pipelineSubscription.Events()))
+	}
+	event := <-pipelineSubscription.Events()
+	if event.Type != EVENT_ALERT || event.PipelineId != "pipeline1" || event.Data != "alert1" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	UnsubscribeEvents(pipelineSubscription)
+	PublishEvent(EVENT_ALERT, "pipeline1", "alert4")
+	if len(pipelineSubscription.Events()) != 0 {
+		t.Error("Expected no events after unsubscribing")
+	}
+}
+
+func TestStateAndErrorRecordEvents(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestStateAndErrorRecordEvents")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	BaseDir = baseDir
+
+	subscription := SubscribeEvents([]string{"testPipeline"}, 10)
+	defer UnsubscribeEvents(subscription)
+
+	pipelineState := &common.PipelineState{PipelineId: "testPipeline", Status: common.RUNNING}
+	if err = SaveState("testPipeline", pipelineState); err != nil {
+		t.Fatal(err)
+	}
+	// changes after saving are not visible to subscribers
+	pipelineState.Status = common.STOPPED
+
+	event := <-subscription.Events()
+	if event.Type != EVENT_STATE || event.Data.(*common.PipelineState).Status != common.RUNNING {
+		t.Errorf("Unexpected state event: %+v", event)
+	}
+
+	errorStore, err := NewErrorStore("testPipeline", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = errorStore.SaveErrorRecords(map[string][]api.Record{
+		"stage1": {createErrorRecord(t, "stage1", "record1"), createErrorRecord(t, "stage1", "record2")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subscription.Events()) != 2 {
+		t.Fatalf("Expected 2 error record events, got %d", len(subscription.Events()))
+	}
+	if event = <-subscription.Events(); event.Type != EVENT_ERROR_RECORD {
+		t.Errorf("Unexpected error record event: %+v", event)
+	}
+}
+
+func TestStateEventAttributesNotShared(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestStateEventAttributesNotShared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	BaseDir = baseDir
+
+	subscription := SubscribeEvents([]string{"testPipeline"}, 10)
+	defer UnsubscribeEvents(subscription)
+
+	pipelineState := &common.PipelineState{
+		PipelineId: "testPipeline",
+		Status:     common.RETRY,
+		Attributes: map[string]interface{}{RETRY_ATTEMPT: 1},
+	}
+	if err = SaveState("testPipeline", pipelineState); err != nil {
+		t.Fatal(err)
+	}
+	event := <-subscription.Events()
+
+	// the runner keeps changing the attributes of its state while the event is encoded for a subscriber
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			pipelineState.Attributes[RETRY_ATTEMPT] = i
+			pipelineState.Attributes[NEXT_RETRY_TIMESTAMP] = int64(i)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := json.Marshal(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+
+	attributes := event.Data.(*common.PipelineState).Attributes
+	if attributes[RETRY_ATTEMPT] != 1 || attributes[NEXT_RETRY_TIMESTAMP] != nil {
+		t.Errorf("Expected the attributes of the event not to change, but got %v", attributes)
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	PIPELINE_FILE           = "pipeline.json"
+	PIPELINE_INFO_FILE      = "info.json"
+	PIPELINE_RULES_FILE     = "rules.json"
+	PIPELINE_SCHEDULES_FILE = "schedules.json"
+	PIPELINES_FOLDER        = "/data/pipelines/"
+)
+
+var pipelineDocuments = []string{PIPELINE_INFO_FILE, PIPELINE_FILE, PIPELINE_RULES_FILE, PIPELINE_SCHEDULES_FILE}
+
+// FileRuntimeStore keeps every pipeline in its own directory under BaseDir, with the pipeline definitions in
+// data/pipelines and the runtime data in data/runInfo.
+type FileRuntimeStore struct {
+}
+
+func (s *FileRuntimeStore) GetOffset(pipelineId string) (common.SourceOffset, error) {
+	defaultSourceOffset := common.GetDefaultOffset()
+	file, err := readFileAtomic(getPipelineOffsetFile(pipelineId))
+	if os.IsNotExist(err) {
+		return defaultSourceOffset, nil
+	} else if err != nil {
+		return defaultSourceOffset, err
+	}
+
+	var sourceOffset common.SourceOffset
+	if err = json.Unmarshal(file, &sourceOffset); err != nil {
+		return defaultSourceOffset, fmt.Errorf("failed to parse offset of pipeline '%s': %s", pipelineId, err)
+	}
+	return sourceOffset, nil
+}
+
+func (s *FileRuntimeStore) SaveOffset(pipelineId string, sourceOffset common.SourceOffset) error {
+	var err error
+	var offsetJson []byte
+	if offsetJson, err = json.Marshal(sourceOffset); err == nil {
+		if err = os.MkdirAll(GetRunInfoDir(pipelineId), os.ModePerm); err == nil {
+			err = writeFileAtomic(getPipelineOffsetFile(pipelineId), offsetJson)
+		}
+	}
+	return err
+}
+
+func (s *FileRuntimeStore) GetState(pipelineId string) (*common.PipelineState, error) {
+	file, err := readFileAtomic(getPipelineStateFile(pipelineId))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var pipelineState common.PipelineState
+	if err = json.Unmarshal(file, &pipelineState); err != nil {
+		return nil, fmt.Errorf("failed to parse state of pipeline '%s': %s", pipelineId, err)
+	}
+	return &pipelineState, nil
+}
+
+func (s *FileRuntimeStore) SaveState(pipelineId string, pipelineState *common.PipelineState) error {
+	pipelineStateJson, err := json.Marshal(pipelineState)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(GetRunInfoDir(pipelineId), os.ModePerm); err != nil {
+		return err
+	}
+	if err = writeFileAtomic(getPipelineStateFile(pipelineId), pipelineStateJson); err != nil {
+		return err
+	}
+
+	//save in history file as well.
+	return s.appendStateHistory(pipelineId, pipelineStateJson)
+}
+
+func (s *FileRuntimeStore) AddHistory(pipelineId string, pipelineState *common.PipelineState) error {
+	pipelineStateJson, err := json.Marshal(pipelineState)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(GetRunInfoDir(pipelineId), os.ModePerm); err != nil {
+		return err
+	}
+	return s.appendStateHistory(pipelineId, pipelineStateJson)
+}
+
+// SaveOffsetAndState writes the offset before the state, files can't be replaced together.
+func (s *FileRuntimeStore) SaveOffsetAndState(
+	pipelineId string,
+	sourceOffset common.SourceOffset,
+	pipelineState *common.PipelineState,
+) error {
+	if err := s.SaveOffset(pipelineId, sourceOffset); err != nil {
+		return err
+	}
+	return s.SaveState(pipelineId, pipelineState)
+}
+
+func (s *FileRuntimeStore) appendStateHistory(pipelineId string, pipelineStateJson []byte) error {
+	historyFileName := getPipelineStateHistoryFile(pipelineId)
+
+	//open for append or create and open for write if it does not exist
+	historyFile, err := os.OpenFile(historyFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	_, err = historyFile.Write(append(pipelineStateJson, '\n'))
+	if err == nil {
+		err = historyFile.Sync()
+	}
+	util.CloseFile(historyFile)
+	if err != nil {
+		return err
+	}
+
+	if fileInfo, err := os.Stat(historyFileName); err == nil && fileInfo.Size() > StateHistoryMaxSize {
+		return s.compactStateHistory(pipelineId)
+	}
+	return nil
+}
+
+// compactStateHistory keeps the current history as the rotated history file and replaces it with its compacted
+// history.
+func (s *FileRuntimeStore) compactStateHistory(pipelineId string) error {
+	history, err := s.GetHistory(pipelineId)
+	if err != nil {
+		return err
+	}
+
+	var historyBuffer bytes.Buffer
+	for _, pipelineState := range compactHistory(history) {
+		pipelineStateJson, err := json.Marshal(pipelineState)
+		if err != nil {
+			return err
+		}
+		historyBuffer.Write(pipelineStateJson)
+		historyBuffer.WriteString("\n")
+	}
+
+	historyFileName := getPipelineStateHistoryFile(pipelineId)
+	tmpFileName := historyFileName + TMP_FILE_SUFFIX
+	if err = ioutil.WriteFile(tmpFileName, historyBuffer.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(historyFileName, historyFileName+ROTATED_HISTORY_FILE_SUFFIX); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpFileName, historyFileName); err != nil {
+		return err
+	}
+	return util.SyncDir(GetRunInfoDir(pipelineId))
+}
+
+// GetHistory skips entries that can't be parsed, such as a line torn by a crash.
+func (s *FileRuntimeStore) GetHistory(pipelineId string) ([]*common.PipelineState, error) {
+	history_of_states := []*common.PipelineState{}
+
+	fileBytes, err := ioutil.ReadFile(getPipelineStateHistoryFile(pipelineId))
+	if os.IsNotExist(err) {
+		return history_of_states, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, line := range bytes.Split(fileBytes, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var pipelineState common.PipelineState
+		if err = json.Unmarshal(line, &pipelineState); err != nil {
+			log.WithError(err).WithField("id", pipelineId).Warn("Skipping invalid entry in pipeline state history")
+			continue
+		}
+		history_of_states = append(history_of_states, &pipelineState)
+	}
+	return history_of_states, nil
+}
+
+func (s *FileRuntimeStore) GetPipelineIds() ([]string, error) {
+	pipelineIds := make([]string, 0)
+	files, err := ioutil.ReadDir(BaseDir + PIPELINES_FOLDER)
+	if os.IsNotExist(err) {
+		return pipelineIds, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			pipelineIds = append(pipelineIds, f.Name())
+		}
+	}
+	return pipelineIds, nil
+}
+
+func (s *FileRuntimeStore) GetPipelineDocument(pipelineId string, name string) ([]byte, error) {
+	return ioutil.ReadFile(getPipelineDir(pipelineId) + name)
+}
+
+func (s *FileRuntimeStore) SavePipelineDocument(pipelineId string, name string, data []byte) error {
+	if err := os.MkdirAll(getPipelineDir(pipelineId), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(getPipelineDir(pipelineId)+name, data, 0644)
+}
+
+func (s *FileRuntimeStore) DeletePipeline(pipelineId string) error {
+	if err := os.RemoveAll(getPipelineDir(pipelineId)); err != nil {
+		return err
+	}
+	return os.RemoveAll(GetRunInfoDir(pipelineId))
+}
+
+func (s *FileRuntimeStore) Close() error {
+	return nil
+}
+
+// compactHistory drops consecutive states with the same status and message, e.g. repeated retries, and keeps the
+// most recent StateHistoryMaxEntries states.
+func compactHistory(history []*common.PipelineState) []*common.PipelineState {
+	compacted := make([]*common.PipelineState, 0, len(history))
+	for _, pipelineState := range history {
+		if len(compacted) > 0 {
+			last := compacted[len(compacted)-1]
+			if last.Status == pipelineState.Status && last.Message == pipelineState.Message {
+				compacted[len(compacted)-1] = pipelineState
+				continue
+			}
+		}
+		compacted = append(compacted, pipelineState)
+	}
+	if len(compacted) > StateHistoryMaxEntries {
+		compacted = compacted[len(compacted)-StateHistoryMaxEntries:]
+	}
+	return compacted
+}
+
+func getPipelineDir(pipelineId string) string {
+	validPipelineId := strings.Replace(pipelineId, ":", "", -1)
+	return BaseDir + PIPELINES_FOLDER + validPipelineId + "/"
+}
+
+func NewFileRuntimeStore() RuntimeStore {
+	return &FileRuntimeStore{}
+}
@@ -13,10 +13,7 @@
 package store
 
 import (
-	"encoding/json"
 	"github.com/streamsets/datacollector-edge/container/common"
-	"io/ioutil"
-	"os"
 	"strings"
 )
 
@@ -28,29 +25,11 @@ const (
 )
 
 func GetOffset(pipelineId string) (common.SourceOffset, error) {
-	defaultSourceOffset := common.GetDefaultOffset()
-	if _, err := os.Stat(getPipelineOffsetFile(pipelineId)); os.IsNotExist(err) {
-		return defaultSourceOffset, nil
-	} else {
-		file, readError := ioutil.ReadFile(getPipelineOffsetFile(pipelineId))
-
-		if readError != nil {
-			return defaultSourceOffset, readError
-		}
-
-		var sourceOffset common.SourceOffset
-		json.Unmarshal(file, &sourceOffset)
-		return sourceOffset, nil
-	}
+	return runtimeStore.GetOffset(pipelineId)
 }
 
 func SaveOffset(pipelineId string, sourceOffset common.SourceOffset) error {
-	var err error
-	var offsetJson []byte
-	if offsetJson, err = json.Marshal(sourceOffset); err == nil {
-		err = ioutil.WriteFile(getPipelineOffsetFile(pipelineId), offsetJson, 0644)
-	}
-	return err
+	return runtimeStore.SaveOffset(pipelineId, sourceOffset)
 }
 
 func ResetOffset(pipelineId string) error {
@@ -58,10 +37,10 @@ func ResetOffset(pipelineId string) error {
 }
 
 func getPipelineOffsetFile(pipelineId string) string {
-	return getRunInfoDir(pipelineId) + OFFSET_FILE
+	return GetRunInfoDir(pipelineId) + OFFSET_FILE
 }
 
-func getRunInfoDir(pipelineId string) string {
+func GetRunInfoDir(pipelineId string) string {
 	validPipelineId := strings.Replace(pipelineId, ":", "", -1)
 	return BaseDir + PIPELINES_RUN_INFO_FOLDER + validPipelineId + "/"
 }
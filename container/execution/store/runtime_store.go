@@ -0,0 +1,167 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"os"
+	"path/filepath"
+)
+
+const (
+	RUNTIME_STORE_FILE = "file"
+	RUNTIME_STORE_BOLT = "bolt"
+	BOLT_DB_FILE       = "/data/runtime.db"
+)
+
+// RuntimeStore persists pipeline definitions and the runtime data of pipelines: source offsets, pipeline state and
+// the pipeline state history.
+type RuntimeStore interface {
+	GetOffset(pipelineId string) (common.SourceOffset, error)
+	SaveOffset(pipelineId string, sourceOffset common.SourceOffset) error
+	// GetState returns nil if no state has been saved for the pipeline yet.
+	GetState(pipelineId string) (*common.PipelineState, error)
+	// SaveState saves the state of the pipeline and appends it to the state history.
+	SaveState(pipelineId string, pipelineState *common.PipelineState) error
+	// SaveOffsetAndState saves the source offset and state of the pipeline together. Implementations that support
+	// transactions guarantee that either both or none are saved.
+	SaveOffsetAndState(pipelineId string, sourceOffset common.SourceOffset, pipelineState *common.PipelineState) error
+	GetHistory(pipelineId string) ([]*common.PipelineState, error)
+	// AddHistory appends an entry to the state history without changing the state of the pipeline.
+	AddHistory(pipelineId string, pipelineState *common.PipelineState) error
+
+	GetPipelineIds() ([]string, error)
+	// GetPipelineDocument returns an error satisfying os.IsNotExist if the document does not exist.
+	GetPipelineDocument(pipelineId string, name string) ([]byte, error)
+	SavePipelineDocument(pipelineId string, name string, data []byte) error
+	// DeletePipeline deletes the documents and runtime data of the pipeline.
+	DeletePipeline(pipelineId string) error
+
+	Close() error
+}
+
+var runtimeStore RuntimeStore = NewFileRuntimeStore()
+
+func GetRuntimeStore() RuntimeStore {
+	return runtimeStore
+}
+
+func SetRuntimeStore(store RuntimeStore) {
+	runtimeStore = store
+}
+
+// NewRuntimeStore creates the runtime store of the given type under the base directory. When the key-value store
+// is created for the first time, the pipelines stored in the file layout are migrated to it.
+func NewRuntimeStore(storeType string, baseDir string) (RuntimeStore, error) {
+	BaseDir = baseDir
+	switch storeType {
+	case "", RUNTIME_STORE_FILE:
+		return NewFileRuntimeStore(), nil
+	case RUNTIME_STORE_BOLT:
+		dbFile := baseDir + BOLT_DB_FILE
+		_, err := os.Stat(dbFile)
+		migrate := os.IsNotExist(err)
+		if err = os.MkdirAll(filepath.Dir(dbFile), os.ModePerm); err != nil {
+			return nil, err
+		}
+		boltStore, err := NewBoltRuntimeStore(dbFile)
+		if err != nil {
+			return nil, err
+		}
+		if migrate {
+			if err = MigrateRuntimeStore(NewFileRuntimeStore(), boltStore); err != nil {
+				_ = boltStore.Close()
+				_ = os.Remove(dbFile)
+				return nil, err
+			}
+		}
+		return boltStore, nil
+	default:
+		return nil, errors.New("Unsupported runtime store: " + storeType)
+	}
+}
+
+// MigrateRuntimeStore copies the pipeline definitions, source offsets, state and state history of all pipelines
+// from one runtime store to another.
+func MigrateRuntimeStore(from RuntimeStore, to RuntimeStore) error {
+	storedPipelineIds, err := from.GetPipelineIds()
+	if err != nil {
+		return err
+	}
+
+	for _, storedPipelineId := range storedPipelineIds {
+		pipelineId := getMigratedPipelineId(from, storedPipelineId)
+		for _, name := range pipelineDocuments {
+			data, err := from.GetPipelineDocument(storedPipelineId, name)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			if err = to.SavePipelineDocument(pipelineId, name, data); err != nil {
+				return err
+			}
+		}
+
+		sourceOffset, err := from.GetOffset(storedPipelineId)
+		if err != nil {
+			return err
+		}
+		pipelineState, err := from.GetState(storedPipelineId)
+		if err != nil {
+			return err
+		}
+		history, err := from.GetHistory(storedPipelineId)
+		if err != nil {
+			return err
+		}
+
+		// the last history entry is the current state, which is saved together with the offset
+		if pipelineState != nil && len(history) > 0 {
+			history = history[:len(history)-1]
+		}
+		for _, historyState := range history {
+			if err = to.SaveState(pipelineId, historyState); err != nil {
+				return err
+			}
+		}
+		if pipelineState != nil {
+			err = to.SaveOffsetAndState(pipelineId, sourceOffset, pipelineState)
+		} else {
+			err = to.SaveOffset(pipelineId, sourceOffset)
+		}
+		if err != nil {
+			return err
+		}
+		log.WithField("id", pipelineId).Info("Migrated pipeline to runtime store")
+	}
+	return nil
+}
+
+// getMigratedPipelineId returns the pipeline id from the pipeline info. The file runtime store returns the names of
+// the pipeline directories as ids, which lack the ':' of Control Hub pipeline ids.
+func getMigratedPipelineId(from RuntimeStore, storedPipelineId string) string {
+	data, err := from.GetPipelineDocument(storedPipelineId, PIPELINE_INFO_FILE)
+	if err != nil {
+		return storedPipelineId
+	}
+	var pipelineInfo common.PipelineInfo
+	if err = json.Unmarshal(data, &pipelineInfo); err != nil || pipelineInfo.PipelineId == "" {
+		log.WithField("id", storedPipelineId).Warn("Pipeline info is invalid, migrating pipeline with its stored id")
+		return storedPipelineId
+	}
+	return pipelineInfo.PipelineId
+}
@@ -0,0 +1,263 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"github.com/streamsets/datacollector-edge/container/common"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestBoltRuntimeStore(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestBoltRuntimeStore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+
+	maxEntries := StateHistoryMaxEntries
+	defer func() {
+		StateHistoryMaxEntries = maxEntries
+	}()
+	StateHistoryMaxEntries = 3
+
+	runtimeStore, err := NewBoltRuntimeStore(baseDir + "/runtime.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer runtimeStore.Close()
+
+	pipelineState, err := runtimeStore.GetState("testPipeline")
+	if err != nil || pipelineState != nil {
+		t.Fatalf("Expected no state, got: %v, %v", pipelineState, err)
+	}
+	if _, err = runtimeStore.GetPipelineDocument("testPipeline", PIPELINE_FILE); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got: %v", err)
+	}
+
+	offset := "offset1"
+	sourceOffset := common.GetDefaultOffset()
+	sourceOffset.Offset[common.PollSourceOffsetKey] = &offset
+	statuses := []string{common.STARTING, common.RUNNING, common.FINISHING, common.FINISHED}
+	for _, status := range statuses {
+		err = runtimeStore.SaveOffsetAndState(
+			"testPipeline",
+			sourceOffset,
+			&common.PipelineState{PipelineId: "testPipeline", Status: status},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	savedOffset, err := runtimeStore.GetOffset("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if savedOffset.Offset[common.PollSourceOffsetKey] == nil || *savedOffset.Offset[common.PollSourceOffsetKey] != offset {
+		t.Errorf("Expected offset %s, got: %v", offset, savedOffset.Offset)
+	}
+
+	pipelineState, err = runtimeStore.GetState("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pipelineState.Status != common.FINISHED {
+		t.Errorf("Expected status FINISHED, got: %s", pipelineState.Status)
+	}
+
+	history, err := runtimeStore.GetHistory("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 || history[0].Status != common.RUNNING || history[2].Status != common.FINISHED {
+		t.Errorf("Expected last 3 states in history, got %d entries", len(history))
+	}
+
+	if err = runtimeStore.DeletePipeline("testPipeline"); err != nil {
+		t.Fatal(err)
+	}
+	if pipelineState, err = runtimeStore.GetState("testPipeline"); err != nil || pipelineState != nil {
+		t.Errorf("Expected state to be deleted, got: %v, %v", pipelineState, err)
+	}
+}
+
+func TestMigrateRuntimeStore(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestMigrateRuntimeStore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	BaseDir = baseDir
+
+	fileStore := NewFileRuntimeStore()
+	pipelineInfoJson := []byte(`{"pipelineId":"testPipeline"}`)
+	if err = fileStore.SavePipelineDocument("testPipeline", PIPELINE_INFO_FILE, pipelineInfoJson); err != nil {
+		t.Fatal(err)
+	}
+	offset := "offset1"
+	sourceOffset := common.GetDefaultOffset()
+	sourceOffset.Offset[common.PollSourceOffsetKey] = &offset
+	if err = fileStore.SaveOffset("testPipeline", sourceOffset); err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range []string{common.STARTING, common.RUNNING, common.STOPPED} {
+		pipelineState := &common.PipelineState{PipelineId: "testPipeline", Status: status}
+		if err = fileStore.SaveState("testPipeline", pipelineState); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	boltStore, err := NewRuntimeStore(RUNTIME_STORE_BOLT, baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer boltStore.Close()
+
+	pipelineIds, err := boltStore.GetPipelineIds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pipelineIds) != 1 || pipelineIds[0] != "testPipeline" {
+		t.Fatalf("Expected migrated pipeline, got: %v", pipelineIds)
+	}
+	if _, err = boltStore.GetPipelineDocument("testPipeline", PIPELINE_INFO_FILE); err != nil {
+		t.Error(err)
+	}
+
+	savedOffset, err := boltStore.GetOffset("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if savedOffset.Offset[common.PollSourceOffsetKey] == nil || *savedOffset.Offset[common.PollSourceOffsetKey] != offset {
+		t.Errorf("Expected offset %s, got: %v", offset, savedOffset.Offset)
+	}
+
+	pipelineState, err := boltStore.GetState("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pipelineState == nil || pipelineState.Status != common.STOPPED {
+		t.Errorf("Expected status STOPPED, got: %v", pipelineState)
+	}
+
+	history, err := boltStore.GetHistory("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Errorf("Expected 3 states in history, got %d", len(history))
+	}
+}
+
+func TestMigrateRuntimeStoreControlHubPipeline(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestMigrateRuntimeStoreControlHubPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	BaseDir = baseDir
+
+	// the file runtime store strips the ':' of Control Hub pipeline ids from the directory names
+	pipelineId := "testPipeline:org"
+	fileStore := NewFileRuntimeStore()
+	pipelineInfoJson := []byte(`{"pipelineId":"` + pipelineId + `"}`)
+	if err = fileStore.SavePipelineDocument(pipelineId, PIPELINE_INFO_FILE, pipelineInfoJson); err != nil {
+		t.Fatal(err)
+	}
+	offset := "offset1"
+	sourceOffset := common.GetDefaultOffset()
+	sourceOffset.Offset[common.PollSourceOffsetKey] = &offset
+	pipelineState := &common.PipelineState{PipelineId: pipelineId, Status: common.STOPPED}
+	if err = fileStore.SaveOffsetAndState(pipelineId, sourceOffset, pipelineState); err != nil {
+		t.Fatal(err)
+	}
+
+	boltStore, err := NewRuntimeStore(RUNTIME_STORE_BOLT, baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer boltStore.Close()
+
+	pipelineIds, err := boltStore.GetPipelineIds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pipelineIds) != 1 || pipelineIds[0] != pipelineId {
+		t.Fatalf("Expected migrated pipeline %s, got: %v", pipelineId, pipelineIds)
+	}
+	savedOffset, err := boltStore.GetOffset(pipelineId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if savedOffset.Offset[common.PollSourceOffsetKey] == nil || *savedOffset.Offset[common.PollSourceOffsetKey] != offset {
+		t.Errorf("Expected offset %s, got: %v", offset, savedOffset.Offset)
+	}
+	savedState, err := boltStore.GetState(pipelineId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if savedState == nil || savedState.Status != common.STOPPED {
+		t.Errorf("Expected status STOPPED, got: %v", savedState)
+	}
+}
+
+func TestBoltRuntimeStore_SaveOffsetAndStateInOneTransaction(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "TestBoltRuntimeStore_SaveOffsetAndStateInOneTransaction")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+
+	runtimeStore, err := NewBoltRuntimeStore(baseDir + "/runtime.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer runtimeStore.Close()
+
+	offset1 := "offset1"
+	sourceOffset := common.GetDefaultOffset()
+	sourceOffset.Offset[common.PollSourceOffsetKey] = &offset1
+	runningState := &common.PipelineState{PipelineId: "testPipeline", Status: common.RUNNING}
+	if err = runtimeStore.SaveOffsetAndState("testPipeline", sourceOffset, runningState); err != nil {
+		t.Fatal(err)
+	}
+
+	// the state can't be serialized, so the offset written before it in the transaction must be rolled back
+	offset2 := "offset2"
+	sourceOffset.Offset[common.PollSourceOffsetKey] = &offset2
+	invalidState := &common.PipelineState{
+		PipelineId: "testPipeline",
+		Status:     common.STOPPED,
+		Attributes: map[string]interface{}{"invalid": make(chan int)},
+	}
+	if err = runtimeStore.SaveOffsetAndState("testPipeline", sourceOffset, invalidState); err == nil {
+		t.Fatal("Expected error when saving a state that can't be serialized")
+	}
+
+	savedOffset, err := runtimeStore.GetOffset("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *savedOffset.Offset[common.PollSourceOffsetKey] != offset1 {
+		t.Errorf("Expected offset %s, got: %s", offset1, *savedOffset.Offset[common.PollSourceOffsetKey])
+	}
+	pipelineState, err := runtimeStore.GetState("testPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pipelineState.Status != common.RUNNING {
+		t.Errorf("Expected status RUNNING, got: %s", pipelineState.Status)
+	}
+}
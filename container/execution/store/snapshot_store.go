@@ -0,0 +1,148 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	SNAPSHOTS_FOLDER     = "snapshots/"
+	SNAPSHOT_INFO_FILE   = "info.json"
+	SNAPSHOT_OUTPUT_FILE = "output.json"
+)
+
+// SnapshotInfo describes a snapshot of the stage outputs captured from a running pipeline.
+type SnapshotInfo struct {
+	Id          string `json:"id"`
+	Label       string `json:"label"`
+	PipelineId  string `json:"pipelineId"`
+	Timestamp   int64  `json:"timeStamp"`
+	BatchNumber int    `json:"batchNumber"`
+	BatchCount  int    `json:"batchCount"`
+	InProgress  bool   `json:"inProgress"`
+}
+
+func SaveSnapshotInfo(pipelineId string, snapshotInfo SnapshotInfo) error {
+	snapshotDir, err := getSnapshotDir(pipelineId, snapshotInfo.Id)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(snapshotDir, os.ModePerm); err != nil {
+		return err
+	}
+	snapshotInfoJson, err := json.Marshal(snapshotInfo)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(snapshotDir+SNAPSHOT_INFO_FILE, snapshotInfoJson)
+}
+
+func GetSnapshotInfo(pipelineId string, snapshotName string) (*SnapshotInfo, error) {
+	snapshotDir, err := getSnapshotDir(pipelineId, snapshotName)
+	if err != nil {
+		return nil, err
+	}
+	snapshotInfoJson, err := readFileAtomic(snapshotDir + SNAPSHOT_INFO_FILE)
+	if os.IsNotExist(err) {
+		return nil, errors.New(fmt.Sprintf("snapshot '%s' does not exist", snapshotName))
+	} else if err != nil {
+		return nil, err
+	}
+	var snapshotInfo SnapshotInfo
+	err = json.Unmarshal(snapshotInfoJson, &snapshotInfo)
+	return &snapshotInfo, err
+}
+
+// GetSnapshotsInfo returns the info of all snapshots of the pipeline, newest first.
+func GetSnapshotsInfo(pipelineId string) ([]*SnapshotInfo, error) {
+	snapshotsInfo := make([]*SnapshotInfo, 0)
+	fileInfos, err := ioutil.ReadDir(GetRunInfoDir(pipelineId) + SNAPSHOTS_FOLDER)
+	if os.IsNotExist(err) {
+		return snapshotsInfo, nil
+	} else if err != nil {
+		return snapshotsInfo, err
+	}
+	for _, fileInfo := range fileInfos {
+		if !fileInfo.IsDir() {
+			continue
+		}
+		snapshotInfo, err := GetSnapshotInfo(pipelineId, fileInfo.Name())
+		if err != nil {
+			return snapshotsInfo, err
+		}
+		snapshotsInfo = append(snapshotsInfo, snapshotInfo)
+	}
+	sort.SliceStable(snapshotsInfo, func(i, j int) bool {
+		return snapshotsInfo[i].Timestamp > snapshotsInfo[j].Timestamp
+	})
+	return snapshotsInfo, nil
+}
+
+// SaveSnapshotData stores the captured batches of the snapshot, snapshotData is serialized as JSON.
+func SaveSnapshotData(pipelineId string, snapshotName string, snapshotData interface{}) error {
+	snapshotDir, err := getSnapshotDir(pipelineId, snapshotName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(snapshotDir, os.ModePerm); err != nil {
+		return err
+	}
+	snapshotDataJson, err := json.Marshal(snapshotData)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(snapshotDir+SNAPSHOT_OUTPUT_FILE, snapshotDataJson)
+}
+
+// GetSnapshotData reads the captured batches of the snapshot into snapshotData.
+func GetSnapshotData(pipelineId string, snapshotName string, snapshotData interface{}) error {
+	snapshotDir, err := getSnapshotDir(pipelineId, snapshotName)
+	if err != nil {
+		return err
+	}
+	snapshotDataJson, err := readFileAtomic(snapshotDir + SNAPSHOT_OUTPUT_FILE)
+	if os.IsNotExist(err) {
+		return errors.New(fmt.Sprintf("snapshot '%s' has no data", snapshotName))
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(snapshotDataJson, snapshotData)
+}
+
+func DeleteSnapshot(pipelineId string, snapshotName string) error {
+	snapshotDir, err := getSnapshotDir(pipelineId, snapshotName)
+	if err != nil {
+		return err
+	}
+	if exists, err := checkFileExists(snapshotDir); err != nil {
+		return err
+	} else if !exists {
+		return errors.New(fmt.Sprintf("snapshot '%s' does not exist", snapshotName))
+	}
+	return os.RemoveAll(snapshotDir)
+}
+
+func getSnapshotDir(pipelineId string, snapshotName string) (string, error) {
+	if snapshotName == "" || snapshotName == "." || snapshotName == ".." ||
+		strings.ContainsAny(snapshotName, "/\\:") {
+		return "", errors.New(fmt.Sprintf("invalid snapshot name '%s'", snapshotName))
+	}
+	return GetRunInfoDir(pipelineId) + SNAPSHOTS_FOLDER + snapshotName + "/", nil
+}
@@ -13,12 +13,8 @@
 package store
 
 import (
-	"bytes"
-	"encoding/json"
 	"github.com/streamsets/datacollector-edge/container/common"
 	"github.com/streamsets/datacollector-edge/container/util"
-	"io"
-	"io/ioutil"
 	"os"
 	"time"
 )
@@ -28,6 +24,18 @@ const (
 	PIPELINE_STATE_HISTORY_FILE = "pipelineStateHistory.json"
 	IS_REMOTE_PIPELINE          = "IS_REMOTE_PIPELINE"
 	ISSUES                      = "issues"
+	RETRY_ATTEMPT               = "RETRY_ATTEMPT"
+	NEXT_RETRY_TIMESTAMP        = "NEXT_RETRY_TIMESTAMP"
+	ROTATED_HISTORY_FILE_SUFFIX = ".1"
+	TRIGGER_CHAIN               = "TRIGGER_CHAIN"
+	TRIGGERED_PIPELINE          = "TRIGGERED_PIPELINE"
+)
+
+var (
+	// StateHistoryMaxSize is the size in bytes above which the pipeline state history is rotated and compacted.
+	StateHistoryMaxSize int64 = 1024 * 1024
+	// StateHistoryMaxEntries is the number of states kept when the pipeline state history is compacted.
+	StateHistoryMaxEntries = 1000
 )
 
 func checkFileExists(filePath string) (bool, error) {
@@ -42,35 +50,20 @@ func checkFileExists(filePath string) (bool, error) {
 }
 
 func GetState(pipelineId string) (*common.PipelineState, error) {
-	fileExists, err := checkFileExists(getPipelineStateFile(pipelineId))
-	if err != nil {
-		return nil, err
-	}
-	if !fileExists {
-		pipelineState := &common.PipelineState{
-			PipelineId: pipelineId,
-			Status:     common.EDITED,
-			Message:    "",
-			TimeStamp:  util.ConvertTimeToLong(time.Now()),
-		}
-		pipelineState.Attributes = make(map[string]interface{})
-		pipelineState.Attributes[IS_REMOTE_PIPELINE] = false
-		err = os.MkdirAll(getRunInfoDir(pipelineId), os.ModePerm)
-		if err == nil {
-			err = SaveState(pipelineId, pipelineState)
-		}
+	pipelineState, err := runtimeStore.GetState(pipelineId)
+	if err != nil || pipelineState != nil {
 		return pipelineState, err
-	} else {
-		file, readError := ioutil.ReadFile(getPipelineStateFile(pipelineId))
-
-		if readError != nil {
-			return nil, readError
-		}
+	}
 
-		var pipelineState common.PipelineState
-		err := json.Unmarshal(file, &pipelineState)
-		return &pipelineState, err
+	pipelineState = &common.PipelineState{
+		PipelineId: pipelineId,
+		Status:     common.EDITED,
+		Message:    "",
+		TimeStamp:  util.ConvertTimeToLong(time.Now()),
 	}
+	pipelineState.Attributes = make(map[string]interface{})
+	pipelineState.Attributes[IS_REMOTE_PIPELINE] = false
+	return pipelineState, SaveState(pipelineId, pipelineState)
 }
 
 func Edited(pipelineId string, isRemote bool) error {
@@ -88,62 +81,44 @@ func Edited(pipelineId string, isRemote bool) error {
 }
 
 func SaveState(pipelineId string, pipelineState *common.PipelineState) error {
-	var err error
-	var pipelineStateJson []byte
-	if pipelineStateJson, err = json.Marshal(pipelineState); err == nil {
-		if err = ioutil.WriteFile(getPipelineStateFile(pipelineId), pipelineStateJson, 0644); err == nil {
-			//open for append or create and open for write if it does not exist
-			openFlag := os.O_APPEND | os.O_CREATE | os.O_WRONLY
-
-			var historyFile *os.File
-
-			//save in history file as well.
-			if historyFile, err = os.OpenFile(getPipelineStateHistoryFile(pipelineId), openFlag, 0666); err == nil {
-				defer util.CloseFile(historyFile)
-				_, err = historyFile.Write(pipelineStateJson)
-				if err == nil {
-					_, err = historyFile.WriteString("\n")
-				}
-			}
-		}
+	err := runtimeStore.SaveState(pipelineId, pipelineState)
+	if err == nil {
+		publishStateEvent(pipelineId, pipelineState)
 	}
 	return err
 }
 
-func GetHistory(pipelineId string) ([]*common.PipelineState, error) {
-	fileExists, err := checkFileExists(getPipelineStateHistoryFile(pipelineId))
-	if err != nil {
-		return nil, err
+func SaveOffsetAndState(
+	pipelineId string,
+	sourceOffset common.SourceOffset,
+	pipelineState *common.PipelineState,
+) error {
+	err := runtimeStore.SaveOffsetAndState(pipelineId, sourceOffset, pipelineState)
+	if err == nil {
+		publishStateEvent(pipelineId, pipelineState)
 	}
+	return err
+}
 
-	history_of_states := []*common.PipelineState{}
-
-	if fileExists {
-		fileBytes, readError := ioutil.ReadFile(getPipelineStateHistoryFile(pipelineId))
-
-		if readError != nil {
-			return nil, readError
-		}
-		var err error = nil
-		decoder := json.NewDecoder(bytes.NewReader(fileBytes))
-		for err == nil {
-			var pipelineState common.PipelineState
-			err = decoder.Decode(&pipelineState)
-			if err == nil {
-				history_of_states = append(history_of_states, &pipelineState)
-			}
-		}
-		if err != io.EOF {
-			return nil, err
-		}
-	}
-	return history_of_states, nil
+// publishStateEvent publishes a copy of the state, the runner keeps changing its state after saving it.
+func publishStateEvent(pipelineId string, pipelineState *common.PipelineState) {
+	PublishEvent(EVENT_STATE, pipelineId, common.CopyPipelineState(pipelineState))
+}
+
+// GetHistory returns the recorded states of the pipeline, oldest first.
+func GetHistory(pipelineId string) ([]*common.PipelineState, error) {
+	return runtimeStore.GetHistory(pipelineId)
+}
+
+// AddHistory records an event in the state history of the pipeline, e.g. a schedule that fired.
+func AddHistory(pipelineId string, pipelineState *common.PipelineState) error {
+	return runtimeStore.AddHistory(pipelineId, pipelineState)
 }
 
 func getPipelineStateFile(pipelineId string) string {
-	return getRunInfoDir(pipelineId) + PIPELINE_STATE_FILE
+	return GetRunInfoDir(pipelineId) + PIPELINE_STATE_FILE
 }
 
 func getPipelineStateHistoryFile(pipelineId string) string {
-	return getRunInfoDir(pipelineId) + PIPELINE_STATE_HISTORY_FILE
+	return GetRunInfoDir(pipelineId) + PIPELINE_STATE_HISTORY_FILE
 }
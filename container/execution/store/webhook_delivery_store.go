@@ -0,0 +1,94 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"sync"
+)
+
+const (
+	WEBHOOK_DELIVERIES_FILE_PREFIX = "webhookDeliveries"
+	WEBHOOK_DELIVERIES_MAX_FILE    = 1024 * 1024
+	WEBHOOK_DELIVERIES_MAX_FILES   = 2
+)
+
+var webhookDeliveriesMutex sync.Mutex
+
+// WebhookDelivery records the outcome of a webhook notification sent for a pipeline state change.
+type WebhookDelivery struct {
+	WebhookUrl string `json:"webhookUrl"`
+	HttpMethod string `json:"httpMethod"`
+	State      string `json:"state"`
+	Timestamp  int64  `json:"timestamp"`
+	Attempts   int    `json:"attempts"`
+	StatusCode int    `json:"statusCode"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error"`
+}
+
+func SaveWebhookDelivery(pipelineId string, webhookDelivery WebhookDelivery) error {
+	line, err := json.Marshal(webhookDelivery)
+	if err != nil {
+		return err
+	}
+
+	webhookDeliveriesMutex.Lock()
+	defer webhookDeliveriesMutex.Unlock()
+	deliveriesFile, err := getWebhookDeliveriesFile(pipelineId)
+	if err != nil {
+		return err
+	}
+	return deliveriesFile.write([][]byte{append(line, '\n')})
+}
+
+// GetWebhookDeliveries returns the logged webhook deliveries of the pipeline, newest first.
+func GetWebhookDeliveries(pipelineId string) ([]WebhookDelivery, error) {
+	webhookDeliveriesMutex.Lock()
+	defer webhookDeliveriesMutex.Unlock()
+
+	webhookDeliveries := make([]WebhookDelivery, 0)
+	deliveriesFile, err := getWebhookDeliveriesFile(pipelineId)
+	if err != nil {
+		return webhookDeliveries, err
+	}
+	err = deliveriesFile.readLines(func(line []byte) error {
+		var webhookDelivery WebhookDelivery
+		if err := json.Unmarshal(line, &webhookDelivery); err != nil {
+			log.WithError(err).Warn("Skipping invalid entry in webhook delivery log")
+			return nil
+		}
+		webhookDeliveries = append(webhookDeliveries, webhookDelivery)
+		return nil
+	})
+
+	for i, j := 0, len(webhookDeliveries)-1; i < j; i, j = i+1, j-1 {
+		webhookDeliveries[i], webhookDeliveries[j] = webhookDeliveries[j], webhookDeliveries[i]
+	}
+	return webhookDeliveries, err
+}
+
+func getWebhookDeliveriesFile(pipelineId string) (*rotatingFile, error) {
+	if err := os.MkdirAll(GetRunInfoDir(pipelineId), os.ModePerm); err != nil {
+		return nil, err
+	}
+	deliveriesFile := &rotatingFile{
+		dir:         GetRunInfoDir(pipelineId),
+		prefix:      WEBHOOK_DELIVERIES_FILE_PREFIX,
+		maxFileSize: WEBHOOK_DELIVERIES_MAX_FILE,
+		maxFiles:    WEBHOOK_DELIVERIES_MAX_FILES,
+	}
+	return deliveriesFile, deliveriesFile.init()
+}
@@ -0,0 +1,262 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package http
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+
+	Authorization   = "Authorization"
+	WwwAuthenticate = "WWW-Authenticate"
+	BasicScheme     = "Basic"
+	BearerScheme    = "Bearer"
+	AuthRealm       = "Data Collector Edge"
+)
+
+var roleLevels = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+type User struct {
+	Name string
+	Role string
+}
+
+// HasRole returns true if the role of the user includes the permissions of the given role, admins can do
+// everything operators can and operators everything viewers can.
+func (u *User) HasRole(role string) bool {
+	return roleLevels[u.Role] >= roleLevels[role]
+}
+
+// Authenticator authenticates requests carrying credentials of one scheme of the Authorization header.
+type Authenticator interface {
+	Scheme() string
+	Authenticate(credentials string) (*User, error)
+}
+
+// BasicAuthenticator authenticates users by the bcrypt hashed passwords of the users file.
+type BasicAuthenticator struct {
+	users map[string]*credential
+	// verified caches a hash of credentials that passed bcrypt, which is slow on purpose
+	verified sync.Map
+}
+
+type credential struct {
+	hash string
+	role string
+}
+
+func (b *BasicAuthenticator) Scheme() string {
+	return BasicScheme
+}
+
+func (b *BasicAuthenticator) Authenticate(credentials string) (*User, error) {
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return nil, errors.New("invalid basic authentication credentials")
+	}
+	userName, password, ok := splitPair(string(decoded), ":")
+	if !ok {
+		return nil, errors.New("invalid basic authentication credentials")
+	}
+
+	userCredential, ok := b.users[userName]
+	if !ok {
+		return nil, errors.New("invalid user name or password")
+	}
+	verifiedKey := sha256Hex(string(decoded))
+	if verifiedHash, ok := b.verified.Load(verifiedKey); !ok || verifiedHash != userCredential.hash {
+		if err := bcrypt.CompareHashAndPassword([]byte(userCredential.hash), []byte(password)); err != nil {
+			return nil, errors.New("invalid user name or password")
+		}
+		b.verified.Store(verifiedKey, userCredential.hash)
+	}
+	return &User{Name: userName, Role: userCredential.role}, nil
+}
+
+// TokenAuthenticator authenticates API tokens by their SHA-256 hashes in the tokens file.
+type TokenAuthenticator struct {
+	tokens map[string]*User
+}
+
+func (t *TokenAuthenticator) Scheme() string {
+	return BearerScheme
+}
+
+func (t *TokenAuthenticator) Authenticate(credentials string) (*User, error) {
+	if user, ok := t.tokens[sha256Hex(credentials)]; ok {
+		return user, nil
+	}
+	return nil, errors.New("invalid API token")
+}
+
+// NewBasicAuthenticator loads the users file, which has a line "<user name>: <bcrypt hash>, <role>" per user.
+func NewBasicAuthenticator(usersFile string) (*BasicAuthenticator, error) {
+	basicAuthenticator := &BasicAuthenticator{users: make(map[string]*credential)}
+	err := readCredentialsFile(usersFile, func(name string, hash string, role string) {
+		basicAuthenticator.users[name] = &credential{hash: hash, role: role}
+	})
+	return basicAuthenticator, err
+}
+
+// NewTokenAuthenticator loads the tokens file, which has a line "<token name>: <SHA-256 hex of token>, <role>"
+// per API token.
+func NewTokenAuthenticator(tokensFile string) (*TokenAuthenticator, error) {
+	tokenAuthenticator := &TokenAuthenticator{tokens: make(map[string]*User)}
+	err := readCredentialsFile(tokensFile, func(name string, hash string, role string) {
+		tokenAuthenticator.tokens[strings.ToLower(hash)] = &User{Name: name, Role: role}
+	})
+	return tokenAuthenticator, err
+}
+
+func readCredentialsFile(fileName string, addCredential func(name string, hash string, role string)) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := splitPair(line, ":")
+		hash, role, hasRole := splitPair(value, ",")
+		name, hash, role = strings.TrimSpace(name), strings.TrimSpace(hash), strings.TrimSpace(role)
+		if !ok || !hasRole || name == "" || hash == "" {
+			return fmt.Errorf("invalid entry in '%s' at line %d", fileName, lineNumber)
+		}
+		if _, ok := roleLevels[role]; !ok {
+			return fmt.Errorf("invalid role '%s' in '%s' at line %d", role, fileName, lineNumber)
+		}
+		addCredential(name, hash, role)
+	}
+	return scanner.Err()
+}
+
+// authenticate returns the user identified by the Authorization header of the request.
+func (webServerTask *WebServerTask) authenticate(r *http.Request) (*User, error) {
+	scheme, credentials, ok := splitPair(r.Header.Get(Authorization), " ")
+	if !ok {
+		return nil, errors.New("authentication required")
+	}
+	for _, authenticator := range webServerTask.authenticators {
+		if strings.EqualFold(authenticator.Scheme(), scheme) {
+			return authenticator.Authenticate(strings.TrimSpace(credentials))
+		}
+	}
+	return nil, fmt.Errorf("unsupported authentication scheme '%s'", scheme)
+}
+
+// authorize wraps the handler of a route so that it is only called for users with the given role, when
+// authentication is enabled.
+func (webServerTask *WebServerTask) authorize(role string, handle httprouter.Handle) httprouter.Handle {
+	if !webServerTask.config.Auth.Enabled {
+		return handle
+	}
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		user, err := webServerTask.authenticate(r)
+		if err != nil {
+			log.WithError(err).WithField("path", r.URL.Path).Debug("Authentication failed")
+			w.Header().Set(WwwAuthenticate, fmt.Sprintf("%s realm=%q", BasicScheme, AuthRealm))
+			errorReq(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		if !user.HasRole(role) {
+			log.WithField("user", user.Name).WithField("path", r.URL.Path).Warn("Access denied")
+			errorReq(w, http.StatusForbidden, fmt.Sprintf("User '%s' is not allowed to access this resource", user.Name))
+			return
+		}
+		handle(w, r, ps)
+	}
+}
+
+func (webServerTask *WebServerTask) authorizeRole(role string) func(handle httprouter.Handle) httprouter.Handle {
+	return func(handle httprouter.Handle) httprouter.Handle {
+		return webServerTask.authorize(role, handle)
+	}
+}
+
+// authorizeHandler is authorize for plain http handlers.
+func (webServerTask *WebServerTask) authorizeHandler(role string, handler http.Handler) httprouter.Handle {
+	return webServerTask.authorize(role, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (webServerTask *WebServerTask) initAuthenticators() error {
+	webServerTask.authenticators = make([]Authenticator, 0)
+	if !webServerTask.config.Auth.Enabled {
+		return nil
+	}
+	credentialCount := 0
+	if webServerTask.config.Auth.UsersFile != "" {
+		basicAuthenticator, err := NewBasicAuthenticator(webServerTask.config.Auth.UsersFile)
+		if err != nil {
+			return fmt.Errorf("authentication is enabled but the users file can't be loaded: %v", err)
+		}
+		webServerTask.authenticators = append(webServerTask.authenticators, basicAuthenticator)
+		credentialCount += len(basicAuthenticator.users)
+	}
+	if webServerTask.config.Auth.TokensFile != "" {
+		tokenAuthenticator, err := NewTokenAuthenticator(webServerTask.config.Auth.TokensFile)
+		if err != nil {
+			return fmt.Errorf("authentication is enabled but the tokens file can't be loaded: %v", err)
+		}
+		webServerTask.authenticators = append(webServerTask.authenticators, tokenAuthenticator)
+		credentialCount += len(tokenAuthenticator.tokens)
+	}
+	if len(webServerTask.authenticators) == 0 {
+		return errors.New("authentication is enabled but neither a users file nor a tokens file is configured")
+	}
+	if credentialCount == 0 {
+		return errors.New("authentication is enabled but the users and tokens files don't define any user or token")
+	}
+	return nil
+}
+
+func splitPair(value string, separator string) (string, string, bool) {
+	index := strings.Index(value, separator)
+	if index < 0 {
+		return value, "", false
+	}
+	return value[:index], value[index+len(separator):], true
+}
+
+func sha256Hex(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,155 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package http
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/crypto/bcrypt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestAuthWebServerTask(t *testing.T) (*WebServerTask, func()) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usersFile := filepath.Join(dir, "users.properties")
+	users := "# users\nviewer: " + string(hash) + ", viewer\noperator: " + string(hash) + ", operator\n"
+	if err := ioutil.WriteFile(usersFile, []byte(users), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tokensFile := filepath.Join(dir, "api-tokens.properties")
+	tokens := "automation: " + sha256Hex("token1") + ", admin\n"
+	if err := ioutil.WriteFile(tokensFile, []byte(tokens), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	webServerTask := &WebServerTask{
+		config: Config{Auth: AuthConfig{Enabled: true, UsersFile: usersFile, TokensFile: tokensFile}},
+	}
+	if err := webServerTask.initAuthenticators(); err != nil {
+		t.Fatal(err)
+	}
+	return webServerTask, func() { os.RemoveAll(dir) }
+}
+
+func TestAuthorize(t *testing.T) {
+	webServerTask, cleanup := newTestAuthWebServerTask(t)
+	defer cleanup()
+
+	handle := webServerTask.authorize(RoleOperator, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name          string
+		authorization string
+		statusCode    int
+	}{
+		{"no credentials", "", http.StatusUnauthorized},
+		{"wrong password", basicAuthorization("operator", "wrong"), http.StatusUnauthorized},
+		{"unknown user", basicAuthorization("unknown", "secret"), http.StatusUnauthorized},
+		{"unknown scheme", "Digest abc", http.StatusUnauthorized},
+		{"missing role", basicAuthorization("viewer", "secret"), http.StatusForbidden},
+		{"role", basicAuthorization("operator", "secret"), http.StatusOK},
+		{"cached credentials", basicAuthorization("operator", "secret"), http.StatusOK},
+		{"higher role", "Bearer token1", http.StatusOK},
+		{"wrong token", "Bearer token2", http.StatusUnauthorized},
+	}
+
+	for _, testCase := range testCases {
+		request := httptest.NewRequest("POST", "/rest/v1/pipeline/test/start", nil)
+		if testCase.authorization != "" {
+			request.Header.Set(Authorization, testCase.authorization)
+		}
+		recorder := httptest.NewRecorder()
+		handle(recorder, request, nil)
+
+		if recorder.Code != testCase.statusCode {
+			t.Errorf("%s: Expected status %d, but got %d", testCase.name, testCase.statusCode, recorder.Code)
+		}
+		if recorder.Code != http.StatusOK {
+			if recorder.Header().Get(ContentType) != ApplicationJson ||
+				!strings.Contains(recorder.Body.String(), `"error"`) {
+				t.Errorf("%s: Expected JSON error, but got %s", testCase.name, recorder.Body.String())
+			}
+		}
+		if recorder.Code == http.StatusUnauthorized && recorder.Header().Get(WwwAuthenticate) == "" {
+			t.Errorf("%s: Expected %s header", testCase.name, WwwAuthenticate)
+		}
+	}
+}
+
+func TestAuthorizeDisabled(t *testing.T) {
+	webServerTask := &WebServerTask{config: NewConfig()}
+	if err := webServerTask.initAuthenticators(); err != nil {
+		t.Fatal(err)
+	}
+	handle := webServerTask.authorize(RoleAdmin, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusOK)
+	})
+	recorder := httptest.NewRecorder()
+	handle(recorder, httptest.NewRequest("GET", "/rest/v1/pipelines", nil), nil)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status %d, but got %d", http.StatusOK, recorder.Code)
+	}
+}
+
+func TestInvalidCredentialsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	usersFile := filepath.Join(dir, "users.properties")
+	if err := ioutil.WriteFile(usersFile, []byte("admin: hash, superuser\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewBasicAuthenticator(usersFile); err == nil {
+		t.Error("Expected error for invalid role")
+	}
+
+	webServerTask := &WebServerTask{config: Config{Auth: AuthConfig{Enabled: true}}}
+	if err := webServerTask.initAuthenticators(); err == nil {
+		t.Error("Expected error when no users or tokens file is configured")
+	}
+
+	webServerTask.config.Auth.UsersFile = filepath.Join(dir, "missing.properties")
+	if err := webServerTask.initAuthenticators(); err == nil || !strings.Contains(err.Error(), "users file") {
+		t.Errorf("Expected error for missing users file, but got %v", err)
+	}
+
+	// the users file shipped with Data Collector Edge only has comments
+	webServerTask.config.Auth.UsersFile = filepath.Join("..", "..", "resources", "etc", "users.properties")
+	if err := webServerTask.initAuthenticators(); err == nil || !strings.Contains(err.Error(), "any user") {
+		t.Errorf("Expected error for users file without users, but got %v", err)
+	}
+}
+
+func basicAuthorization(userName string, password string) string {
+	request := httptest.NewRequest("GET", "/", nil)
+	request.SetBasicAuth(userName, password)
+	return request.Header.Get(Authorization)
+}
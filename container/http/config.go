@@ -12,20 +12,84 @@
 // limitations under the License.
 package http
 
+import (
+	"path/filepath"
+)
+
 const (
-	DefaultBindAddress = ":18633"
+	DefaultBindAddress           = ":18633"
+	DefaultTLSMinVersion         = "1.2"
+	DefaultTLSReloadInterval     = 10000
+	DefaultEventsMetricsInterval = 2000
 )
 
 type Config struct {
 	Enabled     bool   `toml:"enabled"`
 	BindAddress string `toml:"bind-address"`
 	BaseHttpUrl string `toml:"base-http-url"`
+	// EventsMetricsInterval is the default interval in milliseconds of the metrics pushed to event streams
+	EventsMetricsInterval int        `toml:"events-metrics-interval"`
+	Auth                  AuthConfig `toml:"auth"`
+	TLS                   TLSConfig  `toml:"tls"`
+}
+
+// AuthConfig configures the authentication of the REST API. Relative file paths are resolved against the base
+// directory of Data Collector Edge.
+type AuthConfig struct {
+	Enabled        bool   `toml:"enabled"`
+	UsersFile      string `toml:"users-file"`
+	TokensFile     string `toml:"tokens-file"`
+	PprofAdminOnly bool   `toml:"pprof-admin-only"`
+}
+
+// TLSConfig configures HTTPS for the REST API. The key and certificate are read either from PEM files or from a
+// PKCS12 keystore and are reloaded when the files change. Relative file paths are resolved against the base
+// directory of Data Collector Edge.
+type TLSConfig struct {
+	Enabled          bool     `toml:"enabled"`
+	KeyStoreType     string   `toml:"keystore-type"`
+	CertFile         string   `toml:"cert-file"`
+	KeyFile          string   `toml:"key-file"`
+	KeyStoreFile     string   `toml:"keystore-file"`
+	KeyStorePassword string   `toml:"keystore-password"`
+	ClientAuth       string   `toml:"client-auth"`
+	ClientCaFile     string   `toml:"client-ca-file"`
+	MinVersion       string   `toml:"min-version"`
+	CipherSuites     []string `toml:"cipher-suites"`
+	ReloadInterval   int      `toml:"reload-interval"`
 }
 
 // NewConfig returns a new Config with default settings.
 func NewConfig() Config {
 	return Config{
-		Enabled:     true,
-		BindAddress: DefaultBindAddress,
+		Enabled:               true,
+		BindAddress:           DefaultBindAddress,
+		EventsMetricsInterval: DefaultEventsMetricsInterval,
+		Auth: AuthConfig{
+			PprofAdminOnly: true,
+		},
+		TLS: TLSConfig{
+			KeyStoreType:   KeyStoreTypePem,
+			ClientAuth:     ClientAuthNone,
+			MinVersion:     DefaultTLSMinVersion,
+			ReloadInterval: DefaultTLSReloadInterval,
+		},
+	}
+}
+
+// ResolvePaths makes the relative file paths of the configuration relative to the given base directory.
+func (c *Config) ResolvePaths(baseDir string) {
+	c.Auth.UsersFile = resolvePath(baseDir, c.Auth.UsersFile)
+	c.Auth.TokensFile = resolvePath(baseDir, c.Auth.TokensFile)
+	c.TLS.CertFile = resolvePath(baseDir, c.TLS.CertFile)
+	c.TLS.KeyFile = resolvePath(baseDir, c.TLS.KeyFile)
+	c.TLS.KeyStoreFile = resolvePath(baseDir, c.TLS.KeyStoreFile)
+	c.TLS.ClientCaFile = resolvePath(baseDir, c.TLS.ClientCaFile)
+}
+
+func resolvePath(baseDir string, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
 	}
+	return filepath.Join(baseDir, path)
 }
@@ -0,0 +1,146 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	TextEventStream          = "text/event-stream"
+	EventsBufferSize         = 1000
+	EventsHeartbeatInterval  = 15 * time.Second
+	MinEventsMetricsInterval = 250
+)
+
+// Path - GET /rest/v1/events?pipelineId=<id>&metricsInterval=<milliseconds>
+// Path - GET /rest/v1/pipeline/:pipelineId/events?metricsInterval=<milliseconds>
+// Streams the state transitions, alerts and error records of the given pipelines, or of all pipelines if no
+// pipelineId is given, and snapshots of their metrics while they run as Server-Sent Events. The stream starts with
+// the current state of the pipelines, a metricsInterval of 0 disables the metrics.
+func (webServerTask *WebServerTask) streamEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pipelineIds := r.URL.Query()["pipelineId"]
+	if pipelineId := ps.ByName("pipelineId"); pipelineId != "" {
+		pipelineIds = []string{pipelineId}
+	}
+	for _, pipelineId := range pipelineIds {
+		if _, err := webServerTask.pipelineStoreTask.GetInfo(pipelineId); err != nil {
+			serverErrorReq(w, fmt.Sprintf("Failed to stream events:  %s! ", err))
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		serverErrorReq(w, "Failed to stream events: streaming is not supported")
+		return
+	}
+
+	metricsInterval := webServerTask.config.EventsMetricsInterval
+	if i, err := strconv.ParseInt(r.URL.Query().Get("metricsInterval"), 10, 64); err == nil {
+		metricsInterval = int(i)
+	}
+	if metricsInterval > 0 && metricsInterval < MinEventsMetricsInterval {
+		metricsInterval = MinEventsMetricsInterval
+	}
+
+	// subscribe before reading the current states so that no transition is missed
+	subscription := store.SubscribeEvents(pipelineIds, EventsBufferSize)
+	defer store.UnsubscribeEvents(subscription)
+
+	w.Header().Set(ContentType, TextEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, pipelineId := range webServerTask.getEventPipelineIds(pipelineIds) {
+		if pipelineState, err := webServerTask.manager.GetRunner(pipelineId).GetStatus(); err == nil {
+			writeServerSentEvent(w, newPipelineEvent(store.EVENT_STATE, pipelineId, pipelineState))
+		}
+	}
+	flusher.Flush()
+
+	heartbeatTicker := time.NewTicker(EventsHeartbeatInterval)
+	defer heartbeatTicker.Stop()
+	var metricsTicks <-chan time.Time
+	if metricsInterval > 0 {
+		metricsTicker := time.NewTicker(time.Duration(metricsInterval) * time.Millisecond)
+		defer metricsTicker.Stop()
+		metricsTicks = metricsTicker.C
+	}
+
+	for {
+		var err error
+		select {
+		case event := <-subscription.Events():
+			err = writeServerSentEvent(w, event)
+		case <-metricsTicks:
+			for _, pipelineId := range webServerTask.getEventPipelineIds(pipelineIds) {
+				if metricRegistry, ok := webServerTask.getRunningPipelineMetrics(pipelineId); ok {
+					metricsJson := util.FormatMetricsRegistry(metricRegistry)
+					if err = writeServerSentEvent(w, newPipelineEvent(store.EVENT_METRICS, pipelineId, metricsJson)); err != nil {
+						break
+					}
+				}
+			}
+		case <-heartbeatTicker.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		case <-webServerTask.shutdownChan:
+			return
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// getEventPipelineIds returns the given pipeline ids, or the ids of all pipelines if none are given.
+func (webServerTask *WebServerTask) getEventPipelineIds(pipelineIds []string) []string {
+	if len(pipelineIds) > 0 {
+		return pipelineIds
+	}
+	allPipelineIds := make([]string, 0)
+	pipelineInfoList, _ := webServerTask.pipelineStoreTask.GetPipelines()
+	for _, pipelineInfo := range pipelineInfoList {
+		allPipelineIds = append(allPipelineIds, pipelineInfo.PipelineId)
+	}
+	return allPipelineIds
+}
+
+func newPipelineEvent(eventType string, pipelineId string, data interface{}) store.PipelineEvent {
+	return store.PipelineEvent{
+		Type:       eventType,
+		PipelineId: pipelineId,
+		Timestamp:  util.ConvertTimeToLong(time.Now()),
+		Data:       data,
+	}
+}
+
+func writeServerSentEvent(w http.ResponseWriter, event store.PipelineEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
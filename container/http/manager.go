@@ -16,9 +16,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/julienschmidt/httprouter"
-	"github.com/sirupsen/logrus"
 	"github.com/streamsets/datacollector-edge/container/common"
-	"github.com/streamsets/datacollector-edge/container/recordio/sdcrecord"
+	"github.com/streamsets/datacollector-edge/container/execution/store"
 	"github.com/streamsets/datacollector-edge/container/util"
 	"io"
 	"net/http"
@@ -151,27 +150,12 @@ func (webServerTask *WebServerTask) metricsHandler(w http.ResponseWriter, r *htt
 // Path - GET /rest/v1/pipeline/{pipelineId}/errorRecords
 func (webServerTask *WebServerTask) getErrorRecords(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	pipelineId := ps.ByName("pipelineId")
-	stageInstanceName := r.URL.Query().Get("stageInstanceName")
-	size := 10
-	if i, err := strconv.Atoi(r.URL.Query().Get("size")); err == nil {
-		size = i
-	}
-
-	errorRecords, err := webServerTask.manager.GetRunner(pipelineId).GetErrorRecords(stageInstanceName, size)
+	errorRecords, err := webServerTask.manager.GetRunner(pipelineId).GetErrorRecords(getErrorQuery(r))
 	w.Header().Set(ContentType, ApplicationJson)
 	if err == nil {
-		sdcRecords := make([]sdcrecord.SDCRecord, len(errorRecords))
-		for i, record := range errorRecords {
-			sdcRecord, err := sdcrecord.NewSdcRecordFromRecord(record)
-			if err != nil {
-				logrus.WithError(err).Error("failed to create sdc record")
-			} else {
-				sdcRecords[i] = *sdcRecord
-			}
-		}
 		encoder := json.NewEncoder(w)
 		encoder.SetIndent("", "\t")
-		encoder.Encode(sdcRecords)
+		encoder.Encode(errorRecords)
 	} else {
 		serverErrorReq(w, fmt.Sprintf("Failed to get error records:  %s! ", err))
 	}
@@ -180,12 +164,7 @@ func (webServerTask *WebServerTask) getErrorRecords(w http.ResponseWriter, r *ht
 // Path - GET /rest/v1/pipeline/{pipelineId}/errorMessages
 func (webServerTask *WebServerTask) getErrorMessages(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	pipelineId := ps.ByName("pipelineId")
-	stageInstanceName := r.URL.Query().Get("stageInstanceName")
-	size := 10
-	if i, err := strconv.Atoi(r.URL.Query().Get("size")); err == nil {
-		size = i
-	}
-	errorMessages, err := webServerTask.manager.GetRunner(pipelineId).GetErrorMessages(stageInstanceName, size)
+	errorMessages, err := webServerTask.manager.GetRunner(pipelineId).GetErrorMessages(getErrorQuery(r))
 	w.Header().Set(ContentType, ApplicationJson)
 	if err == nil {
 		encoder := json.NewEncoder(w)
@@ -195,3 +174,87 @@ func (webServerTask *WebServerTask) getErrorMessages(w http.ResponseWriter, r *h
 		serverErrorReq(w, fmt.Sprintf("Failed to get error messages:  %s! ", err))
 	}
 }
+
+// Path - POST /rest/v1/pipeline/{pipelineId}/errorRecords/replay
+// Replays the error records selected by the same query parameters as GET errorRecords.
+func (webServerTask *WebServerTask) replayErrorRecords(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pipelineId := ps.ByName("pipelineId")
+	replayedRecords, err := webServerTask.manager.GetRunner(pipelineId).ReplayErrorRecords(getErrorQuery(r))
+	w.Header().Set(ContentType, ApplicationJson)
+	if err == nil {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		encoder.Encode(map[string]int{"replayedRecords": replayedRecords})
+	} else {
+		serverErrorReq(w, fmt.Sprintf("Failed to replay error records:  %s! ", err))
+	}
+}
+
+// Path - GET /rest/v1/pipeline/{pipelineId}/alerts
+func (webServerTask *WebServerTask) getAlerts(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pipelineId := ps.ByName("pipelineId")
+	alerts, err := webServerTask.manager.GetRunner(pipelineId).GetAlerts()
+	w.Header().Set(ContentType, ApplicationJson)
+	if err == nil {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		encoder.Encode(alerts)
+	} else {
+		serverErrorReq(w, fmt.Sprintf("Failed to get alerts:  %s! ", err))
+	}
+}
+
+// Path - DELETE /rest/v1/pipeline/{pipelineId}/alerts?alertId=<ruleId>
+func (webServerTask *WebServerTask) deleteAlert(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pipelineId := ps.ByName("pipelineId")
+	err := webServerTask.manager.GetRunner(pipelineId).DeleteAlert(r.URL.Query().Get("alertId"))
+	w.Header().Set(ContentType, ApplicationJson)
+	if err == nil {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		encoder.Encode(true)
+	} else {
+		serverErrorReq(w, fmt.Sprintf("Failed to delete alert:  %s! ", err))
+	}
+}
+
+// Path - GET /rest/v1/pipeline/{pipelineId}/webhookDeliveries
+func (webServerTask *WebServerTask) getWebhookDeliveries(
+	w http.ResponseWriter,
+	r *http.Request,
+	ps httprouter.Params,
+) {
+	pipelineId := ps.ByName("pipelineId")
+	webhookDeliveries, err := webServerTask.manager.GetRunner(pipelineId).GetWebhookDeliveries()
+	w.Header().Set(ContentType, ApplicationJson)
+	if err == nil {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		encoder.Encode(webhookDeliveries)
+	} else {
+		serverErrorReq(w, fmt.Sprintf("Failed to get webhook deliveries:  %s! ", err))
+	}
+}
+
+// getErrorQuery reads the error store query from the request parameters: stageInstanceName, startTime and endTime
+// (in milliseconds), sourceId (repeatable), offset and size.
+func getErrorQuery(r *http.Request) store.ErrorQuery {
+	query := store.ErrorQuery{
+		StageInstanceName: r.URL.Query().Get("stageInstanceName"),
+		SourceIds:         r.URL.Query()["sourceId"],
+		Size:              10,
+	}
+	if i, err := strconv.Atoi(r.URL.Query().Get("size")); err == nil {
+		query.Size = i
+	}
+	if i, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil {
+		query.Offset = i
+	}
+	if i, err := strconv.ParseInt(r.URL.Query().Get("startTime"), 10, 64); err == nil {
+		query.StartTime = i
+	}
+	if i, err := strconv.ParseInt(r.URL.Query().Get("endTime"), 10, 64); err == nil {
+		query.EndTime = i
+	}
+	return query
+}
@@ -13,12 +13,21 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/julienschmidt/httprouter"
 	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/store"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
+)
+
+const (
+	ApplicationZip         = "application/zip"
+	MaxPipelineArchiveSize = 64 * 1024 * 1024
 )
 
 // Path - GET /rest/v1/pipelines
@@ -48,10 +57,11 @@ func (webServerTask *WebServerTask) getPipeline(w http.ResponseWriter, r *http.R
 	}
 }
 
-// Path - PUT /rest/v1/pipeline/:pipelineId?description=<desc>
+// Path - PUT /rest/v1/pipeline/:pipelineTitle?description=<desc>
 func (webServerTask *WebServerTask) createPipeline(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set(ContentType, ApplicationJson)
-	pipelineTitle := ps.ByName("pipelineTitle")
+	// the router requires the same wildcard name for all routes of a method, the path holds the title
+	pipelineTitle := ps.ByName("pipelineId")
 	description := r.URL.Query().Get("description")
 	pipelineConfig, err := webServerTask.pipelineStoreTask.Create(pipelineTitle, pipelineTitle, description, false)
 	if err == nil {
@@ -92,3 +102,125 @@ func (webServerTask *WebServerTask) savePipeline(w http.ResponseWriter, r *http.
 		serverErrorReq(w, fmt.Sprintf("Failed to save pipeline:  %s! ", err))
 	}
 }
+
+// Path - DELETE /rest/v1/pipeline/:pipelineId
+func (webServerTask *WebServerTask) deletePipeline(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set(ContentType, ApplicationJson)
+	pipelineId := ps.ByName("pipelineId")
+	err := webServerTask.manager.DeletePipeline(pipelineId)
+	if err == nil {
+		webServerTask.scheduler.RemovePipeline(pipelineId)
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		encoder.Encode(true)
+	} else {
+		serverErrorReq(w, fmt.Sprintf("Failed to delete pipeline:  %s! ", err))
+	}
+}
+
+// Path - GET /rest/v1/pipeline/:pipelineId/export?includeOffset=true&includeHistory=true
+func (webServerTask *WebServerTask) exportPipeline(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pipelineId := ps.ByName("pipelineId")
+	includeOffset, _ := strconv.ParseBool(r.URL.Query().Get("includeOffset"))
+	includeHistory, _ := strconv.ParseBool(r.URL.Query().Get("includeHistory"))
+
+	// the archive is written to a buffer first to be able to report errors
+	var archiveBuffer bytes.Buffer
+	archive, err := webServerTask.pipelineStoreTask.Export(pipelineId, includeOffset, includeHistory)
+	if err == nil {
+		err = archive.Write(&archiveBuffer)
+	}
+	if err == nil {
+		w.Header().Set(ContentType, ApplicationZip)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", pipelineId))
+		w.Write(archiveBuffer.Bytes())
+	} else {
+		w.Header().Set(ContentType, ApplicationJson)
+		serverErrorReq(w, fmt.Sprintf("Failed to export pipeline:  %s! ", err))
+	}
+}
+
+// Path - POST /rest/v1/pipelines/import?regenerateIds=true&overwrite=true
+func (webServerTask *WebServerTask) importPipeline(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set(ContentType, ApplicationJson)
+	regenerateIds, _ := strconv.ParseBool(r.URL.Query().Get("regenerateIds"))
+	overwrite, _ := strconv.ParseBool(r.URL.Query().Get("overwrite"))
+
+	defer r.Body.Close()
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxPipelineArchiveSize))
+	if err != nil {
+		serverErrorReq(w, fmt.Sprintf("Failed to import pipeline:  %s! ", err))
+		return
+	}
+	archive, err := store.ReadPipelineArchive(data)
+	if err != nil {
+		serverErrorReq(w, fmt.Sprintf("Failed to import pipeline:  %s! ", err))
+		return
+	}
+
+	// an existing pipeline is only replaced if it is not running
+	pipelineId := archive.Pipeline.PipelineId
+	if _, err := webServerTask.pipelineStoreTask.GetInfo(pipelineId); err == nil && overwrite && !regenerateIds {
+		if err = webServerTask.manager.DeletePipeline(pipelineId); err != nil {
+			serverErrorReq(w, fmt.Sprintf("Failed to import pipeline:  %s! ", err))
+			return
+		}
+		webServerTask.scheduler.RemovePipeline(pipelineId)
+	}
+
+	pipelineInfo, err := webServerTask.pipelineStoreTask.Import(archive, regenerateIds)
+	if err == nil {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		encoder.Encode(pipelineInfo)
+	} else {
+		serverErrorReq(w, fmt.Sprintf("Failed to import pipeline:  %s! ", err))
+	}
+}
+
+// Path - GET /rest/v1/pipeline/:pipelineId/rules
+func (webServerTask *WebServerTask) getPipelineRules(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set(ContentType, ApplicationJson)
+	pipelineId := ps.ByName("pipelineId")
+	ruleDefinitions, err := webServerTask.pipelineStoreTask.RetrieveRules(pipelineId)
+	if err == nil {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		encoder.Encode(ruleDefinitions)
+	} else {
+		serverErrorReq(w, fmt.Sprintf("Failed to get pipeline rules:  %s! ", err))
+	}
+}
+
+// Path - POST /rest/v1/pipeline/:pipelineId/rules
+func (webServerTask *WebServerTask) savePipelineRules(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set(ContentType, ApplicationJson)
+	pipelineId := ps.ByName("pipelineId")
+
+	decoder := json.NewDecoder(r.Body)
+	var ruleDefinitions common.RuleDefinitions
+	err := decoder.Decode(&ruleDefinitions)
+	if err != nil {
+		switch {
+		case err == io.EOF:
+			// empty body
+		case err != nil:
+			// other error
+			serverErrorReq(w, fmt.Sprintf("Failed to save pipeline rules:  %s! ", err))
+			return
+		}
+	}
+	defer r.Body.Close()
+
+	ruleDefinitions, err = webServerTask.pipelineStoreTask.SaveRules(pipelineId, ruleDefinitions)
+	if err == nil {
+		err = webServerTask.manager.GetRunner(pipelineId).ReloadRules()
+	}
+	if err == nil {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		encoder.Encode(ruleDefinitions)
+	} else {
+		serverErrorReq(w, fmt.Sprintf("Failed to save pipeline rules:  %s! ", err))
+	}
+}
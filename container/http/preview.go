@@ -120,6 +120,7 @@ func (webServerTask *WebServerTask) getPreviewData(w http.ResponseWriter, r *htt
 	previewer, err := webServerTask.manager.GetPreviewer(previewerId)
 	if err != nil {
 		serverErrorReq(w, err.Error())
+		return
 	}
 
 	encoder := json.NewEncoder(w)
@@ -135,11 +136,13 @@ func (webServerTask *WebServerTask) stopPreview(w http.ResponseWriter, r *http.R
 	previewer, err := webServerTask.manager.GetPreviewer(previewerId)
 	if err != nil {
 		serverErrorReq(w, err.Error())
+		return
 	}
 
 	err = previewer.Stop()
 	if err != nil {
 		serverErrorReq(w, err.Error())
+		return
 	}
 
 	encoder := json.NewEncoder(w)
@@ -0,0 +1,73 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rcrowley/go-metrics"
+	"github.com/streamsets/datacollector-edge/container/execution/runner"
+	"github.com/streamsets/datacollector-edge/container/util"
+	"net/http"
+)
+
+const (
+	PrometheusNamespace = "sdc_edge"
+	PipelineIdLabel     = "pipeline_id"
+)
+
+// Path - GET /metrics
+// Returns the metrics of the running pipelines and the process metrics in the Prometheus text exposition format.
+func (webServerTask *WebServerTask) prometheusMetricsHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+	ps httprouter.Params,
+) {
+	pipelineInfoList, err := webServerTask.pipelineStoreTask.GetPipelines()
+	if err != nil {
+		w.Header().Set(ContentType, ApplicationJson)
+		serverErrorReq(w, fmt.Sprintf("Failed to get metrics:  %s! ", err))
+		return
+	}
+
+	prometheusMetrics := util.NewPrometheusMetrics(PrometheusNamespace)
+	for _, pipelineInfo := range pipelineInfoList {
+		if metricRegistry, ok := webServerTask.getRunningPipelineMetrics(pipelineInfo.PipelineId); ok {
+			labels := map[string]string{PipelineIdLabel: pipelineInfo.PipelineId}
+			prometheusMetrics.AddRegistry(metricRegistry, "", labels)
+		}
+	}
+	prometheusMetrics.AddRegistry(webServerTask.processManager.GetProcessMetrics(), "process.", nil)
+
+	var metricsBuffer bytes.Buffer
+	if err = prometheusMetrics.Write(&metricsBuffer); err != nil {
+		w.Header().Set(ContentType, ApplicationJson)
+		serverErrorReq(w, fmt.Sprintf("Failed to get metrics:  %s! ", err))
+		return
+	}
+	w.Header().Set(ContentType, util.PrometheusTextFormat)
+	w.Write(metricsBuffer.Bytes())
+}
+
+// getRunningPipelineMetrics returns the metric registry of the pipeline if it is running, the registry of the last
+// run is kept after the pipeline stopped.
+func (webServerTask *WebServerTask) getRunningPipelineMetrics(pipelineId string) (metrics.Registry, bool) {
+	pipelineRunner := webServerTask.manager.GetRunner(pipelineId)
+	pipelineState, err := pipelineRunner.GetStatus()
+	if err != nil || !util.Contains(runner.RestOffsetDisallowedStatuses, pipelineState.Status) {
+		return nil, false
+	}
+	metricRegistry, err := pipelineRunner.GetMetrics()
+	return metricRegistry, err == nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"io"
+	"net/http"
+)
+
+// Path - GET /rest/v1/pipeline/:pipelineId/schedules
+func (webServerTask *WebServerTask) getPipelineSchedules(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set(ContentType, ApplicationJson)
+	pipelineId := ps.ByName("pipelineId")
+	schedules, err := webServerTask.scheduler.GetSchedules(pipelineId)
+	if err == nil {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		encoder.Encode(schedules)
+	} else {
+		serverErrorReq(w, fmt.Sprintf("Failed to get pipeline schedules:  %s! ", err))
+	}
+}
+
+// Path - POST /rest/v1/pipeline/:pipelineId/schedules
+func (webServerTask *WebServerTask) savePipelineSchedule(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set(ContentType, ApplicationJson)
+	pipelineId := ps.ByName("pipelineId")
+
+	decoder := json.NewDecoder(r.Body)
+	var schedule common.PipelineSchedule
+	err := decoder.Decode(&schedule)
+	if err != nil {
+		switch {
+		case err == io.EOF:
+			// empty body
+		case err != nil:
+			// other error
+			serverErrorReq(w, fmt.Sprintf("Failed to save pipeline schedule:  %s! ", err))
+			return
+		}
+	}
+	defer r.Body.Close()
+
+	savedSchedule, err := webServerTask.scheduler.SaveSchedule(pipelineId, schedule)
+	if err == nil {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		encoder.Encode(savedSchedule)
+	} else {
+		serverErrorReq(w, fmt.Sprintf("Failed to save pipeline schedule:  %s! ", err))
+	}
+}
+
+// Path - DELETE /rest/v1/pipeline/:pipelineId/schedules?scheduleId=<scheduleId>
+func (webServerTask *WebServerTask) deletePipelineSchedule(
+	w http.ResponseWriter,
+	r *http.Request,
+	ps httprouter.Params,
+) {
+	w.Header().Set(ContentType, ApplicationJson)
+	pipelineId := ps.ByName("pipelineId")
+	err := webServerTask.scheduler.DeleteSchedule(pipelineId, r.URL.Query().Get("scheduleId"))
+	if err == nil {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		encoder.Encode(true)
+	} else {
+		serverErrorReq(w, fmt.Sprintf("Failed to delete pipeline schedule:  %s! ", err))
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package http
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/pkcs12"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	KeyStoreTypePem    = "PEM"
+	KeyStoreTypePkcs12 = "PKCS12"
+
+	ClientAuthNone    = "none"
+	ClientAuthRequest = "request"
+	ClientAuthRequire = "require"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	ClientAuthNone:    tls.NoClientCert,
+	ClientAuthRequest: tls.VerifyClientCertIfGiven,
+	ClientAuthRequire: tls.RequireAndVerifyClientCert,
+}
+
+var cipherSuites = map[string]uint16{
+	"TLS_RSA_WITH_AES_128_CBC_SHA":                  tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_RSA_WITH_AES_256_CBC_SHA":                  tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_GCM_SHA256":               tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_RSA_WITH_AES_256_GCM_SHA384":               tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":          tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":          tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256":       tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384":       tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":          tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":        tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256":   tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256": tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+}
+
+// certificateReloader holds the server certificate and the client CAs of the TLS configuration and reloads them
+// during handshakes when their files have changed, at most once per reload interval.
+type certificateReloader struct {
+	config        TLSConfig
+	serverConfig  *tls.Config
+	mutex         sync.Mutex
+	certificate   *tls.Certificate
+	clientCAs     *x509.CertPool
+	modTimes      map[string]time.Time
+	lastCheckTime time.Time
+}
+
+func (r *certificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.reloadIfModified()
+	return r.certificate, nil
+}
+
+func (r *certificateReloader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.reloadIfModified()
+	config := r.serverConfig.Clone()
+	config.GetConfigForClient = nil
+	config.ClientCAs = r.clientCAs
+	return config, nil
+}
+
+func (r *certificateReloader) reloadIfModified() {
+	if time.Since(r.lastCheckTime) < time.Duration(r.config.ReloadInterval)*time.Millisecond {
+		return
+	}
+	r.lastCheckTime = time.Now()
+
+	modTimes, err := getModTimes(r.files())
+	if err != nil {
+		log.WithError(err).Warn("Failed to check TLS certificate files for changes")
+		return
+	}
+	modified := false
+	for fileName, modTime := range modTimes {
+		if !modTime.Equal(r.modTimes[fileName]) {
+			modified = true
+		}
+	}
+	if !modified {
+		return
+	}
+
+	if err := r.load(); err != nil {
+		log.WithError(err).Error("Failed to reload TLS certificate, keeping the current certificate")
+		// do not retry broken files until they change again
+		r.modTimes = modTimes
+		return
+	}
+	log.Info("Reloaded TLS certificate")
+}
+
+func (r *certificateReloader) load() error {
+	modTimes, err := getModTimes(r.files())
+	if err != nil {
+		return err
+	}
+
+	var certificate tls.Certificate
+	if strings.EqualFold(r.config.KeyStoreType, KeyStoreTypePkcs12) {
+		certificate, err = loadPkcs12KeyStore(r.config.KeyStoreFile, r.config.KeyStorePassword)
+	} else {
+		certificate, err = tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	}
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if r.config.ClientCaFile != "" {
+		caCerts, err := ioutil.ReadFile(r.config.ClientCaFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caCerts) {
+			return fmt.Errorf("no certificates found in client CA file '%s'", r.config.ClientCaFile)
+		}
+	}
+
+	r.certificate = &certificate
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	return nil
+}
+
+func (r *certificateReloader) files() []string {
+	files := make([]string, 0)
+	if strings.EqualFold(r.config.KeyStoreType, KeyStoreTypePkcs12) {
+		files = append(files, r.config.KeyStoreFile)
+	} else {
+		files = append(files, r.config.CertFile, r.config.KeyFile)
+	}
+	if r.config.ClientCaFile != "" {
+		files = append(files, r.config.ClientCaFile)
+	}
+	return files
+}
+
+func getModTimes(files []string) (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time)
+	for _, fileName := range files {
+		fileInfo, err := os.Stat(fileName)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[fileName] = fileInfo.ModTime()
+	}
+	return modTimes, nil
+}
+
+func loadPkcs12KeyStore(keyStoreFile string, keyStorePassword string) (tls.Certificate, error) {
+	data, err := ioutil.ReadFile(keyStoreFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	pemBlocks, err := pkcs12.ToPEM(data, keyStorePassword)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to decode keystore '%s': %s", keyStoreFile, err)
+	}
+
+	var certPemBlockBuffer, keyPemBlockBuffer bytes.Buffer
+	for _, pemBlock := range pemBlocks {
+		if pemBlock.Type == "CERTIFICATE" {
+			err = pem.Encode(&certPemBlockBuffer, pemBlock)
+		} else {
+			err = pem.Encode(&keyPemBlockBuffer, pemBlock)
+		}
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+	return tls.X509KeyPair(certPemBlockBuffer.Bytes(), keyPemBlockBuffer.Bytes())
+}
+
+// newTLSConfig validates the TLS settings and returns the TLS configuration of the web server, which loads the
+// server certificate and client CAs on every handshake from the reloader.
+func newTLSConfig(config TLSConfig) (*tls.Config, error) {
+	keyStoreType := strings.ToUpper(config.KeyStoreType)
+	if keyStoreType != KeyStoreTypePem && keyStoreType != KeyStoreTypePkcs12 {
+		return nil, fmt.Errorf("invalid TLS keystore type '%s'", config.KeyStoreType)
+	}
+	if keyStoreType == KeyStoreTypePem && (config.CertFile == "" || config.KeyFile == "") {
+		return nil, errors.New("TLS is enabled but the certificate file or key file is not configured")
+	}
+	if keyStoreType == KeyStoreTypePkcs12 && config.KeyStoreFile == "" {
+		return nil, errors.New("TLS is enabled but the keystore file is not configured")
+	}
+
+	minVersion, ok := tlsVersions[config.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("invalid TLS minimum version '%s'", config.MinVersion)
+	}
+
+	clientAuth, ok := clientAuthTypes[strings.ToLower(config.ClientAuth)]
+	if !ok {
+		return nil, fmt.Errorf("invalid TLS client authentication '%s'", config.ClientAuth)
+	}
+	if clientAuth != tls.NoClientCert && config.ClientCaFile == "" {
+		return nil, errors.New("TLS client authentication is enabled but the client CA file is not configured")
+	}
+
+	var suites []uint16
+	for _, cipherSuite := range config.CipherSuites {
+		suite, ok := cipherSuites[strings.ToUpper(cipherSuite)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS cipher suite '%s'", cipherSuite)
+		}
+		suites = append(suites, suite)
+	}
+
+	reloader := &certificateReloader{config: config}
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+	reloader.lastCheckTime = time.Now()
+
+	reloader.serverConfig = &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   suites,
+		ClientAuth:     clientAuth,
+		GetCertificate: reloader.getCertificate,
+	}
+	if clientAuth != tls.NoClientCert {
+		reloader.serverConfig.GetConfigForClient = reloader.getConfigForClient
+	}
+	return reloader.serverConfig, nil
+}
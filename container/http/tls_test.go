@@ -0,0 +1,254 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package http
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	certPem     []byte
+	keyPem      []byte
+}
+
+func (c *testCertificate) tlsCertificate(t *testing.T) tls.Certificate {
+	certificate, err := tls.X509KeyPair(c.certPem, c.keyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate
+}
+
+func newTestCertificate(t *testing.T, commonName string, isCA bool, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: commonName},
+		DNSNames:              []string{commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+
+	parentCertificate, parentKey := template, key
+	if parent != nil {
+		parentCertificate, parentKey = parent.certificate, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCertificate, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{
+		certificate: certificate,
+		key:         key,
+		certPem:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPem:      pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	}
+}
+
+func writeTestCertificate(t *testing.T, certificate *testCertificate, certFile string, keyFile string) {
+	if err := ioutil.WriteFile(certFile, certificate.certPem, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, certificate.keyPem, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestTLSDir(t *testing.T) (string, TLSConfig, *testCertificate) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := newTestCertificate(t, "Test CA", true, nil)
+	if err := ioutil.WriteFile(filepath.Join(dir, "ca.crt"), ca.certPem, 0644); err != nil {
+		t.Fatal(err)
+	}
+	server := newTestCertificate(t, "localhost", false, ca)
+	writeTestCertificate(t, server, filepath.Join(dir, "edge.crt"), filepath.Join(dir, "edge.key"))
+
+	config := NewConfig().TLS
+	config.Enabled = true
+	config.CertFile = filepath.Join(dir, "edge.crt")
+	config.KeyFile = filepath.Join(dir, "edge.key")
+	return dir, config, ca
+}
+
+// handshake returns the error of the server side of a TLS handshake between the given configurations.
+func handshake(t *testing.T, serverConfig *tls.Config, clientConfig *tls.Config) error {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		serverErr <- conn.(*tls.Conn).Handshake()
+	}()
+
+	clientConn, err := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+	if err == nil {
+		defer clientConn.Close()
+	}
+	return <-serverErr
+}
+
+func TestNewTLSConfigInvalid(t *testing.T) {
+	dir, config, _ := newTestTLSDir(t)
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name   string
+		update func(config *TLSConfig)
+	}{
+		{"keystore type", func(config *TLSConfig) { config.KeyStoreType = "JKS" }},
+		{"missing key file", func(config *TLSConfig) { config.KeyFile = "" }},
+		{"missing keystore file", func(config *TLSConfig) { config.KeyStoreType = KeyStoreTypePkcs12 }},
+		{"min version", func(config *TLSConfig) { config.MinVersion = "2.0" }},
+		{"client auth", func(config *TLSConfig) { config.ClientAuth = "always" }},
+		{"client auth without CA", func(config *TLSConfig) { config.ClientAuth = ClientAuthRequire }},
+		{"cipher suite", func(config *TLSConfig) { config.CipherSuites = []string{"TLS_NULL_WITH_NULL_NULL"} }},
+		{"cert file not found", func(config *TLSConfig) { config.CertFile = filepath.Join(dir, "missing.crt") }},
+	}
+
+	for _, testCase := range testCases {
+		invalidConfig := config
+		testCase.update(&invalidConfig)
+		if _, err := newTLSConfig(invalidConfig); err == nil {
+			t.Errorf("%s: expected an error", testCase.name)
+		}
+	}
+}
+
+func TestTLSClientAuth(t *testing.T) {
+	dir, config, ca := newTestTLSDir(t)
+	defer os.RemoveAll(dir)
+
+	config.CipherSuites = []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}
+	config.ClientAuth = ClientAuthRequire
+	config.ClientCaFile = filepath.Join(dir, "ca.crt")
+	serverConfig, err := newTLSConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.certificate)
+	trustedClient := newTestCertificate(t, "fleet", false, ca)
+	untrustedClient := newTestCertificate(t, "fleet", false, newTestCertificate(t, "Other CA", true, nil))
+
+	testCases := []struct {
+		name         string
+		certificates []tls.Certificate
+		success      bool
+	}{
+		{"no client certificate", nil, false},
+		{"trusted client certificate", []tls.Certificate{trustedClient.tlsCertificate(t)}, true},
+		{"untrusted client certificate", []tls.Certificate{untrustedClient.tlsCertificate(t)}, false},
+	}
+
+	for _, testCase := range testCases {
+		clientConfig := &tls.Config{RootCAs: rootCAs, ServerName: "localhost", Certificates: testCase.certificates}
+		err := handshake(t, serverConfig, clientConfig)
+		if testCase.success && err != nil {
+			t.Errorf("%s: unexpected error: %s", testCase.name, err)
+		} else if !testCase.success && err == nil {
+			t.Errorf("%s: expected the handshake to fail", testCase.name)
+		}
+	}
+}
+
+func TestTLSCertificateReload(t *testing.T) {
+	dir, config, ca := newTestTLSDir(t)
+	defer os.RemoveAll(dir)
+
+	config.ReloadInterval = 0
+	serverConfig, err := newTLSConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getLeaf := func() []byte {
+		certificate, err := serverConfig.GetCertificate(&tls.ClientHelloInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return certificate.Certificate[0]
+	}
+	initialLeaf := getLeaf()
+
+	renewed := newTestCertificate(t, "localhost", false, ca)
+	writeTestCertificate(t, renewed, config.CertFile, config.KeyFile)
+	modTime := time.Now().Add(time.Minute)
+	for _, fileName := range []string{config.CertFile, config.KeyFile} {
+		if err := os.Chtimes(fileName, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if leaf := getLeaf(); !bytes.Equal(leaf, renewed.certificate.Raw) || bytes.Equal(leaf, initialLeaf) {
+		t.Fatal("expected the renewed certificate to be loaded")
+	}
+
+	// a broken certificate file keeps the current certificate
+	if err := ioutil.WriteFile(config.CertFile, []byte("invalid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime = modTime.Add(time.Minute)
+	if err := os.Chtimes(config.CertFile, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if leaf := getLeaf(); !bytes.Equal(leaf, renewed.certificate.Raw) {
+		t.Fatal("expected the renewed certificate to be kept")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/streamsets/datacollector-edge/container/common"
 	"github.com/streamsets/datacollector-edge/container/execution/manager"
+	"github.com/streamsets/datacollector-edge/container/execution/scheduler"
 	"github.com/streamsets/datacollector-edge/container/process"
 	"github.com/streamsets/datacollector-edge/container/store"
 	"github.com/streamsets/datacollector-edge/container/util"
@@ -40,6 +41,9 @@ type WebServerTask struct {
 	pipelineStoreTask store.PipelineStoreTask
 	httpServer        *http.Server
 	processManager    *process.Manager
+	scheduler         *scheduler.Scheduler
+	authenticators    []Authenticator
+	shutdownChan      chan struct{}
 }
 
 func (webServerTask *WebServerTask) Init() error {
@@ -49,50 +53,98 @@ func (webServerTask *WebServerTask) Init() error {
 		return nil
 	}
 
-	fmt.Println("Running on URI : http://localhost" + webServerTask.config.BindAddress)
-	log.Info("Running on URI : http://localhost" + webServerTask.config.BindAddress)
+	scheme := "http"
+	if webServerTask.config.TLS.Enabled {
+		scheme = "https"
+	}
+	fmt.Println("Running on URI : " + scheme + "://localhost" + webServerTask.config.BindAddress)
+	log.Info("Running on URI : " + scheme + "://localhost" + webServerTask.config.BindAddress)
+
+	if err := webServerTask.initAuthenticators(); err != nil {
+		return err
+	}
+
+	// Each route is registered with the minimum role a user needs to access it: viewers can read, operators can
+	// also run pipelines, previews and snapshots, admins can also change pipelines and offsets
+	viewer := webServerTask.authorizeRole(RoleViewer)
+	operator := webServerTask.authorizeRole(RoleOperator)
+	admin := webServerTask.authorizeRole(RoleAdmin)
 
 	router := httprouter.New()
-	router.GET("/", webServerTask.homeHandler)
+	router.GET("/", viewer(webServerTask.homeHandler))
 
 	// Manager APIs
-	router.POST("/rest/v1/pipeline/:pipelineId/start", webServerTask.startHandler)
-	router.POST("/rest/v1/pipeline/:pipelineId/stop", webServerTask.stopHandler)
-	router.POST("/rest/v1/pipeline/:pipelineId/resetOffset", webServerTask.resetOffsetHandler)
-	router.POST("/rest/v1/pipeline/:pipelineId/committedOffsets", webServerTask.updateOffsetHandler)
-
-	router.GET("/rest/v1/pipeline/:pipelineId/status", webServerTask.statusHandler)
-	router.GET("/rest/v1/pipeline/:pipelineId/history", webServerTask.historyHandler)
-	router.GET("/rest/v1/pipeline/:pipelineId/metrics", webServerTask.metricsHandler)
-	router.GET("/rest/v1/pipeline/:pipelineId/committedOffsets", webServerTask.getOffsetHandler)
-	router.GET("/rest/v1/pipeline/:pipelineId/errorRecords", webServerTask.getErrorRecords)
-	router.GET("/rest/v1/pipeline/:pipelineId/errorMessages", webServerTask.getErrorMessages)
+	router.POST("/rest/v1/pipeline/:pipelineId/start", operator(webServerTask.startHandler))
+	router.POST("/rest/v1/pipeline/:pipelineId/stop", operator(webServerTask.stopHandler))
+	router.POST("/rest/v1/pipeline/:pipelineId/resetOffset", admin(webServerTask.resetOffsetHandler))
+	router.POST("/rest/v1/pipeline/:pipelineId/committedOffsets", admin(webServerTask.updateOffsetHandler))
+
+	router.GET("/rest/v1/pipeline/:pipelineId/status", viewer(webServerTask.statusHandler))
+	router.GET("/rest/v1/pipeline/:pipelineId/history", viewer(webServerTask.historyHandler))
+	router.GET("/rest/v1/pipeline/:pipelineId/metrics", viewer(webServerTask.metricsHandler))
+	router.GET("/rest/v1/pipeline/:pipelineId/committedOffsets", viewer(webServerTask.getOffsetHandler))
+	router.GET("/rest/v1/pipeline/:pipelineId/errorRecords", viewer(webServerTask.getErrorRecords))
+	router.GET("/rest/v1/pipeline/:pipelineId/errorMessages", viewer(webServerTask.getErrorMessages))
+	router.POST("/rest/v1/pipeline/:pipelineId/errorRecords/replay", operator(webServerTask.replayErrorRecords))
+	router.GET("/rest/v1/pipeline/:pipelineId/alerts", viewer(webServerTask.getAlerts))
+	router.DELETE("/rest/v1/pipeline/:pipelineId/alerts", operator(webServerTask.deleteAlert))
+	router.GET("/rest/v1/pipeline/:pipelineId/webhookDeliveries", viewer(webServerTask.getWebhookDeliveries))
+	router.GET("/rest/v1/pipeline/:pipelineId/snapshots", viewer(webServerTask.getSnapshotsInfo))
+	router.PUT("/rest/v1/pipeline/:pipelineId/snapshot/:snapshotName", operator(webServerTask.captureSnapshot))
+	router.GET("/rest/v1/pipeline/:pipelineId/snapshot/:snapshotName", viewer(webServerTask.getSnapshot))
+	router.GET("/rest/v1/pipeline/:pipelineId/snapshot/:snapshotName/status", viewer(webServerTask.getSnapshotStatus))
+	router.DELETE("/rest/v1/pipeline/:pipelineId/snapshot/:snapshotName", operator(webServerTask.deleteSnapshot))
 
 	// Pipeline Store APIs
-	router.GET("/rest/v1/pipelines", webServerTask.getPipelines)
-	router.GET("/rest/v1/pipeline/:pipelineId", webServerTask.getPipeline)
-	router.PUT("/rest/v1/pipeline/:pipelineTitle", webServerTask.createPipeline)
-	router.POST("/rest/v1/pipeline/:pipelineId", webServerTask.savePipeline)
+	router.GET("/rest/v1/pipelines", viewer(webServerTask.getPipelines))
+	router.GET("/rest/v1/pipeline/:pipelineId", viewer(webServerTask.getPipeline))
+	router.PUT("/rest/v1/pipeline/:pipelineId", admin(webServerTask.createPipeline))
+	router.POST("/rest/v1/pipeline/:pipelineId", admin(webServerTask.savePipeline))
+	router.DELETE("/rest/v1/pipeline/:pipelineId", admin(webServerTask.deletePipeline))
+	router.GET("/rest/v1/pipeline/:pipelineId/export", viewer(webServerTask.exportPipeline))
+	router.POST("/rest/v1/pipelines/import", admin(webServerTask.importPipeline))
+	router.GET("/rest/v1/pipeline/:pipelineId/rules", viewer(webServerTask.getPipelineRules))
+	router.POST("/rest/v1/pipeline/:pipelineId/rules", admin(webServerTask.savePipelineRules))
+	router.GET("/rest/v1/pipeline/:pipelineId/schedules", viewer(webServerTask.getPipelineSchedules))
+	router.POST("/rest/v1/pipeline/:pipelineId/schedules", admin(webServerTask.savePipelineSchedule))
+	router.DELETE("/rest/v1/pipeline/:pipelineId/schedules", admin(webServerTask.deletePipelineSchedule))
 
 	// Pipeline Preview APIs
-	router.GET("/rest/v1/pipeline/:pipelineId/validate", webServerTask.validateConfigs)
-	router.POST("/rest/v1/pipeline/:pipelineId/preview", webServerTask.preview)
-	router.GET("/rest/v1/pipeline/:pipelineId/preview/:previewerId/status", webServerTask.getPreviewStatus)
-	router.GET("/rest/v1/pipeline/:pipelineId/preview/:previewerId", webServerTask.getPreviewData)
+	router.GET("/rest/v1/pipeline/:pipelineId/validate", operator(webServerTask.validateConfigs))
+	router.POST("/rest/v1/pipeline/:pipelineId/preview", operator(webServerTask.preview))
+	router.GET("/rest/v1/pipeline/:pipelineId/preview/:previewerId/status", viewer(webServerTask.getPreviewStatus))
+	router.GET("/rest/v1/pipeline/:pipelineId/preview/:previewerId", viewer(webServerTask.getPreviewData))
+	router.DELETE("/rest/v1/pipeline/:pipelineId/preview/:previewerId", operator(webServerTask.stopPreview))
 
 	// Register pprof handlers
-	router.HandlerFunc("GET", "/debug/pprof/", pprof.Index)
-	router.Handler("GET", "/debug/pprof/heap", pprof.Handler("heap"))
-	router.Handler("GET", "/debug/pprof/goroutine", pprof.Handler("goroutine"))
-	router.Handler("GET", "/debug/pprof/block", pprof.Handler("block"))
-	router.HandlerFunc("GET", "/debug/pprof/cmdline", pprof.Cmdline)
-	router.HandlerFunc("GET", "/debug/pprof/profile", pprof.Profile)
-	router.HandlerFunc("GET", "/debug/pprof/symbol", pprof.Symbol)
-	router.HandlerFunc("GET", "/debug/pprof/trace", pprof.Trace)
-
-	router.GET("/rest/v1/processMetrics", webServerTask.processMetricsHandler)
+	pprofRole := RoleViewer
+	if webServerTask.config.Auth.PprofAdminOnly {
+		pprofRole = RoleAdmin
+	}
+	router.GET("/debug/pprof/", webServerTask.authorizeHandler(pprofRole, http.HandlerFunc(pprof.Index)))
+	router.GET("/debug/pprof/heap", webServerTask.authorizeHandler(pprofRole, pprof.Handler("heap")))
+	router.GET("/debug/pprof/goroutine", webServerTask.authorizeHandler(pprofRole, pprof.Handler("goroutine")))
+	router.GET("/debug/pprof/block", webServerTask.authorizeHandler(pprofRole, pprof.Handler("block")))
+	router.GET("/debug/pprof/cmdline", webServerTask.authorizeHandler(pprofRole, http.HandlerFunc(pprof.Cmdline)))
+	router.GET("/debug/pprof/profile", webServerTask.authorizeHandler(pprofRole, http.HandlerFunc(pprof.Profile)))
+	router.GET("/debug/pprof/symbol", webServerTask.authorizeHandler(pprofRole, http.HandlerFunc(pprof.Symbol)))
+	router.GET("/debug/pprof/trace", webServerTask.authorizeHandler(pprofRole, http.HandlerFunc(pprof.Trace)))
+
+	router.GET("/rest/v1/processMetrics", viewer(webServerTask.processMetricsHandler))
+	router.GET("/metrics", viewer(webServerTask.prometheusMetricsHandler))
+
+	// Event streams
+	router.GET("/rest/v1/events", viewer(webServerTask.streamEvents))
+	router.GET("/rest/v1/pipeline/:pipelineId/events", viewer(webServerTask.streamEvents))
 
 	webServerTask.httpServer = &http.Server{Addr: webServerTask.config.BindAddress, Handler: router}
+	if webServerTask.config.TLS.Enabled {
+		tlsConfig, err := newTLSConfig(webServerTask.config.TLS)
+		if err != nil {
+			return err
+		}
+		webServerTask.httpServer.TLSConfig = tlsConfig
+	}
 	return nil
 }
 
@@ -103,7 +155,10 @@ func (webServerTask *WebServerTask) homeHandler(w http.ResponseWriter, r *http.R
 }
 
 func (webServerTask *WebServerTask) Run() {
-	if webServerTask.config.Enabled {
+	if webServerTask.config.Enabled && webServerTask.config.TLS.Enabled {
+		// certificate and key are provided by the TLS config of the server
+		fmt.Println(webServerTask.httpServer.ListenAndServeTLS("", ""))
+	} else if webServerTask.config.Enabled {
 		fmt.Println(webServerTask.httpServer.ListenAndServe())
 	} else {
 		// Block forever to run Edge process in background
@@ -113,6 +168,8 @@ func (webServerTask *WebServerTask) Run() {
 
 func (webServerTask *WebServerTask) Shutdown() {
 	if webServerTask.config.Enabled {
+		// end the event streams, the server waits for all requests to finish
+		close(webServerTask.shutdownChan)
 		err := webServerTask.httpServer.Shutdown(context.Background())
 		if err != nil {
 			log.WithError(err).Error("Error happened when shutting down web server")
@@ -129,8 +186,12 @@ func (webServerTask *WebServerTask) processMetricsHandler(w http.ResponseWriter,
 }
 
 func serverErrorReq(w http.ResponseWriter, err string) {
+	errorReq(w, http.StatusInternalServerError, err)
+}
+
+func errorReq(w http.ResponseWriter, statusCode int, err string) {
 	w.Header().Set(ContentType, ApplicationJson)
-	w.WriteHeader(http.StatusInternalServerError)
+	w.WriteHeader(statusCode)
 	fmt.Fprintf(w, `{"result":"", "error":%q}`, err)
 }
 
@@ -140,6 +201,7 @@ func NewWebServerTask(
 	manager manager.Manager,
 	pipelineStoreTask store.PipelineStoreTask,
 	processManager *process.Manager,
+	scheduler *scheduler.Scheduler,
 ) (*WebServerTask, error) {
 	webServerTask := WebServerTask{
 		config:            config,
@@ -147,6 +209,8 @@ func NewWebServerTask(
 		manager:           manager,
 		pipelineStoreTask: pipelineStoreTask,
 		processManager:    processManager,
+		scheduler:         scheduler,
+		shutdownChan:      make(chan struct{}),
 	}
 	err := webServerTask.Init()
 	if err != nil {
@@ -0,0 +1,264 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/container/common"
+	pipelineStateStore "github.com/streamsets/datacollector-edge/container/execution/store"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"time"
+	"unicode"
+)
+
+const (
+	OffsetFile               = pipelineStateStore.OFFSET_FILE
+	PipelineStateHistoryFile = pipelineStateStore.PIPELINE_STATE_HISTORY_FILE
+)
+
+// validPipelineId matches the pipeline ids that are safe to use as a directory name in the data directory.
+var validPipelineId = regexp.MustCompile("^[A-Za-z0-9_-]+$")
+
+// PipelineArchive is a self-contained copy of a pipeline to move it between Data Collector Edge instances, the
+// source offset and state history are optional.
+type PipelineArchive struct {
+	Pipeline common.PipelineConfiguration
+	Info     common.PipelineInfo
+	Rules    *common.RuleDefinitions
+	Offset   *common.SourceOffset
+	History  []*common.PipelineState
+}
+
+// Write writes the archive as a zip file with a JSON document per entry.
+func (a *PipelineArchive) Write(w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
+	entries := []struct {
+		name  string
+		value interface{}
+		write bool
+	}{
+		{PipelineFile, a.Pipeline, true},
+		{PipelineInfoFile, a.Info, true},
+		{PipelineRulesFile, a.Rules, a.Rules != nil},
+		{OffsetFile, a.Offset, a.Offset != nil},
+		{PipelineStateHistoryFile, a.History, a.History != nil},
+	}
+	for _, entry := range entries {
+		if !entry.write {
+			continue
+		}
+		data, err := json.MarshalIndent(entry.value, "", "  ")
+		if err != nil {
+			return err
+		}
+		entryWriter, err := zipWriter.Create(entry.name)
+		if err != nil {
+			return err
+		}
+		if _, err = entryWriter.Write(data); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// ReadPipelineArchive reads an archive written by PipelineArchive.Write.
+func ReadPipelineArchive(data []byte) (*PipelineArchive, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pipeline archive: %s", err)
+	}
+
+	archive := &PipelineArchive{}
+	found := make(map[string]bool)
+	for _, file := range zipReader.File {
+		var value interface{}
+		switch file.Name {
+		case PipelineFile:
+			value = &archive.Pipeline
+		case PipelineInfoFile:
+			value = &archive.Info
+		case PipelineRulesFile:
+			archive.Rules = &common.RuleDefinitions{}
+			value = archive.Rules
+		case OffsetFile:
+			archive.Offset = &common.SourceOffset{}
+			value = archive.Offset
+		case PipelineStateHistoryFile:
+			value = &archive.History
+		default:
+			continue
+		}
+		if err = readArchiveEntry(file, value); err != nil {
+			return nil, fmt.Errorf("invalid pipeline archive entry '%s': %s", file.Name, err)
+		}
+		found[file.Name] = true
+	}
+
+	if !found[PipelineFile] || !found[PipelineInfoFile] {
+		return nil, fmt.Errorf("invalid pipeline archive: '%s' and '%s' are required", PipelineFile, PipelineInfoFile)
+	}
+	if archive.Pipeline.PipelineId == "" {
+		return nil, errors.New("invalid pipeline archive: the pipeline has no id")
+	}
+	return archive, nil
+}
+
+func readArchiveEntry(file *zip.File, value interface{}) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+func (store *RuntimePipelineStoreTask) Export(
+	pipelineId string,
+	includeOffset bool,
+	includeHistory bool,
+) (*PipelineArchive, error) {
+	if !store.hasPipeline(pipelineId) {
+		return nil, errors.New("Pipeline '" + pipelineId + " does not exist")
+	}
+
+	archive := &PipelineArchive{}
+	if err := store.loadDocument(pipelineId, PipelineFile, &archive.Pipeline); err != nil {
+		return nil, err
+	}
+	if err := store.loadDocument(pipelineId, PipelineInfoFile, &archive.Info); err != nil {
+		return nil, err
+	}
+
+	rules := &common.RuleDefinitions{}
+	err := store.loadDocument(pipelineId, PipelineRulesFile, rules)
+	if err == nil {
+		archive.Rules = rules
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if includeOffset {
+		sourceOffset, err := store.runtimeStore.GetOffset(pipelineId)
+		if err != nil {
+			return nil, err
+		}
+		archive.Offset = &sourceOffset
+	}
+
+	if includeHistory {
+		if archive.History, err = store.runtimeStore.GetHistory(pipelineId); err != nil {
+			return nil, err
+		}
+	}
+	return archive, nil
+}
+
+func (store *RuntimePipelineStoreTask) Import(
+	archive *PipelineArchive,
+	regenerateIds bool,
+) (common.PipelineInfo, error) {
+	pipelineId := archive.Pipeline.PipelineId
+	if regenerateIds {
+		pipelineId = newPipelineId(archive.Pipeline.Title)
+	} else if !validPipelineId.MatchString(pipelineId) {
+		return common.PipelineInfo{}, fmt.Errorf("invalid pipeline id '%s'", pipelineId)
+	}
+	if store.hasPipeline(pipelineId) {
+		return common.PipelineInfo{}, errors.New("Pipeline '" + pipelineId + " already exists")
+	}
+
+	pipelineUuid := uuid.NewV4().String()
+	pipelineInfo := archive.Info
+	pipelineInfo.PipelineId = pipelineId
+	pipelineInfo.UUID = pipelineUuid
+	pipelineInfo.LastModified = time.Now().Unix()
+	pipelineInfo.Title = archive.Pipeline.Title
+	pipelineInfo.Description = archive.Pipeline.Description
+
+	pipelineConfiguration := archive.Pipeline
+	pipelineConfiguration.PipelineId = pipelineId
+	pipelineConfiguration.UUID = pipelineUuid
+	pipelineConfiguration.Info = pipelineInfo
+
+	err := store.importRuntimeData(pipelineId, pipelineInfo, pipelineConfiguration, archive)
+	if err != nil {
+		// don't leave a partially imported pipeline behind
+		_ = store.runtimeStore.DeletePipeline(pipelineId)
+		return pipelineInfo, err
+	}
+
+	log.WithField("id", pipelineId).Info("Imported pipeline")
+	store.pipelineInfoMap.Store(pipelineId, pipelineInfo)
+	return pipelineInfo, nil
+}
+
+func (store *RuntimePipelineStoreTask) importRuntimeData(
+	pipelineId string,
+	pipelineInfo common.PipelineInfo,
+	pipelineConfiguration common.PipelineConfiguration,
+	archive *PipelineArchive,
+) error {
+	if err := store.saveDocument(pipelineId, PipelineFile, pipelineConfiguration); err != nil {
+		return err
+	}
+	if archive.Rules != nil {
+		rules := *archive.Rules
+		rules.UUID = uuid.NewV4().String()
+		if err := store.saveDocument(pipelineId, PipelineRulesFile, rules); err != nil {
+			return err
+		}
+	}
+	if archive.Offset != nil {
+		if err := store.runtimeStore.SaveOffset(pipelineId, *archive.Offset); err != nil {
+			return err
+		}
+	}
+	for _, historyState := range archive.History {
+		pipelineState := *historyState
+		pipelineState.PipelineId = pipelineId
+		if err := store.runtimeStore.AddHistory(pipelineId, &pipelineState); err != nil {
+			return err
+		}
+	}
+	if err := pipelineStateStore.Edited(pipelineId, false); err != nil {
+		return err
+	}
+	// the info document marks the pipeline as existing, so it is saved last
+	return store.saveDocument(pipelineId, PipelineInfoFile, pipelineInfo)
+}
+
+// newPipelineId returns a new unique pipeline id made of the letters and digits of the title and a UUID.
+func newPipelineId(title string) string {
+	var pipelineId bytes.Buffer
+	for _, r := range title {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			pipelineId.WriteRune(r)
+		}
+	}
+	pipelineId.WriteString(uuid.NewV4().String())
+	return pipelineId.String()
+}
@@ -0,0 +1,162 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"bytes"
+	"github.com/streamsets/datacollector-edge/container/common"
+	pipelineStateStore "github.com/streamsets/datacollector-edge/container/execution/store"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFilePipelineStoreTask_ExportImport(t *testing.T) {
+	pipelineStoreTask := getPipelineStoreTask(t, "TestFilePipelineStoreTask_ExportImport")
+	defer os.RemoveAll(pipelineStateStore.BaseDir)
+
+	if _, err := pipelineStoreTask.Create("exportPipeline", "Export Pipeline", "Sample desc", false); err != nil {
+		t.Fatal(err)
+	}
+	ruleDefinitions := common.RuleDefinitions{EmailIds: []string{"a@b.c"}}
+	if _, err := pipelineStoreTask.SaveRules("exportPipeline", ruleDefinitions); err != nil {
+		t.Fatal(err)
+	}
+	offset := "10"
+	sourceOffset := common.SourceOffset{Version: common.CurrentOffsetVersion, Offset: map[string]*string{"file": &offset}}
+	if err := pipelineStateStore.SaveOffset("exportPipeline", sourceOffset); err != nil {
+		t.Fatal(err)
+	}
+	history, err := pipelineStateStore.GetHistory("exportPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := pipelineStoreTask.Export("exportPipeline", true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	if err = archive.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	archiveData := buffer.Bytes()
+
+	if _, err = pipelineStoreTask.Import(archive, false); err == nil {
+		t.Error("Excepted error when importing an existing pipeline")
+	}
+	if err = pipelineStoreTask.Delete("exportPipeline"); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err = ReadPipelineArchive(archiveData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipelineInfo, err := pipelineStoreTask.Import(archive, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pipelineInfo.PipelineId != "exportPipeline" || pipelineInfo.Title != "Export Pipeline" {
+		t.Errorf("Unexpected pipeline info: %+v", pipelineInfo)
+	}
+
+	pipelineConfig, err := pipelineStoreTask.LoadPipelineConfig("exportPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pipelineConfig.Description != "Sample desc" {
+		t.Errorf("Excepted description 'Sample desc', but got: %s", pipelineConfig.Description)
+	}
+	rules, err := pipelineStoreTask.RetrieveRules("exportPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules.EmailIds) != 1 || rules.EmailIds[0] != "a@b.c" {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+	importedOffset, err := pipelineStateStore.GetOffset("exportPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *importedOffset.Offset["file"] != "10" {
+		t.Errorf("Excepted offset '10', but got: %s", *importedOffset.Offset["file"])
+	}
+	// the imported history is followed by the states of the imported pipeline
+	importedHistory, err := pipelineStateStore.GetHistory("exportPipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(importedHistory) <= len(history) {
+		t.Fatalf("Excepted more than %d history entries, but got: %d", len(history), len(importedHistory))
+	}
+	for i, pipelineState := range history {
+		if importedHistory[i].TimeStamp != pipelineState.TimeStamp {
+			t.Errorf("Excepted history entry %d to be imported", i)
+		}
+	}
+
+	pipelineInfo, err = pipelineStoreTask.Import(archive, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pipelineInfo.PipelineId == "exportPipeline" || !strings.HasPrefix(pipelineInfo.PipelineId, "ExportPipeline") {
+		t.Errorf("Unexpected regenerated pipeline id: %s", pipelineInfo.PipelineId)
+	}
+	pipelineInfoList, err := pipelineStoreTask.GetPipelines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pipelineInfoList) != 2 {
+		t.Errorf("Excepted 2 pipelines, but got: %d", len(pipelineInfoList))
+	}
+}
+
+func TestReadPipelineArchive_Invalid(t *testing.T) {
+	if _, err := ReadPipelineArchive([]byte("not a zip file")); err == nil {
+		t.Error("Excepted error for invalid archive")
+	}
+
+	var buffer bytes.Buffer
+	archive := &PipelineArchive{Info: common.PipelineInfo{PipelineId: "test"}}
+	if err := archive.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPipelineArchive(buffer.Bytes()); err == nil {
+		t.Error("Excepted error for archive without pipeline id")
+	}
+}
+
+func TestFilePipelineStoreTask_ImportInvalidPipelineId(t *testing.T) {
+	pipelineStoreTask := getPipelineStoreTask(t, "TestFilePipelineStoreTask_ImportInvalidPipelineId")
+	defer os.RemoveAll(pipelineStateStore.BaseDir)
+
+	for _, pipelineId := range []string{"../..", "..", "a/b", "a b", ""} {
+		archive := &PipelineArchive{Pipeline: common.PipelineConfiguration{PipelineId: pipelineId, Title: "Invalid"}}
+		if _, err := pipelineStoreTask.Import(archive, false); err == nil {
+			t.Errorf("Excepted error when importing pipeline id '%s'", pipelineId)
+		}
+	}
+	if _, err := os.Stat(pipelineStateStore.BaseDir); err != nil {
+		t.Errorf("Excepted data directory to be kept: %s", err)
+	}
+
+	archive := &PipelineArchive{Pipeline: common.PipelineConfiguration{PipelineId: "../..", Title: "Valid Title"}}
+	pipelineInfo, err := pipelineStoreTask.Import(archive, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(pipelineInfo.PipelineId, "ValidTitle") {
+		t.Errorf("Unexpected regenerated pipeline id: %s", pipelineInfo.PipelineId)
+	}
+}
@@ -26,4 +26,12 @@ type PipelineStoreTask interface {
 	Save(pipelineId string, pipelineConfiguration common.PipelineConfiguration) (common.PipelineConfiguration, error)
 	LoadPipelineConfig(pipelineId string) (common.PipelineConfiguration, error)
 	Delete(pipelineId string) error
+	SaveRules(pipelineId string, ruleDefinitions common.RuleDefinitions) (common.RuleDefinitions, error)
+	RetrieveRules(pipelineId string) (common.RuleDefinitions, error)
+	SaveSchedules(pipelineId string, schedules []*common.PipelineSchedule) error
+	RetrieveSchedules(pipelineId string) ([]*common.PipelineSchedule, error)
+	// Export returns an archive of the pipeline, optionally with its source offset and state history.
+	Export(pipelineId string, includeOffset bool, includeHistory bool) (*PipelineArchive, error)
+	// Import creates the pipeline of the archive, under a new id if regenerateIds is set.
+	Import(archive *PipelineArchive, regenerateIds bool) (common.PipelineInfo, error)
 }
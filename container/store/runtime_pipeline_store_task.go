@@ -0,0 +1,320 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/streamsets/datacollector-edge/container/common"
+	"github.com/streamsets/datacollector-edge/container/creation"
+	pipelineStateStore "github.com/streamsets/datacollector-edge/container/execution/store"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	PipelineFile           = pipelineStateStore.PIPELINE_FILE
+	PipelineInfoFile       = pipelineStateStore.PIPELINE_INFO_FILE
+	PipelineRulesFile      = pipelineStateStore.PIPELINE_RULES_FILE
+	PipelineSchedulesFile  = pipelineStateStore.PIPELINE_SCHEDULES_FILE
+	PipelinesFolder        = pipelineStateStore.PIPELINES_FOLDER
+	PipelinesRunInfoFolder = pipelineStateStore.PIPELINES_RUN_INFO_FOLDER
+)
+
+// RuntimePipelineStoreTask keeps the pipeline definitions in a runtime store.
+type RuntimePipelineStoreTask struct {
+	runtimeInfo     common.RuntimeInfo
+	runtimeStore    pipelineStateStore.RuntimeStore
+	pipelineInfoMap sync.Map
+}
+
+func (store *RuntimePipelineStoreTask) init() {
+	pipelineIds, err := store.runtimeStore.GetPipelineIds()
+	if err != nil {
+		log.WithError(err).Error("Failed to read pipelines")
+		return
+	}
+
+	for _, pipelineId := range pipelineIds {
+		pipelineInfo := common.PipelineInfo{}
+		if err = store.loadDocument(pipelineId, PipelineInfoFile, &pipelineInfo); err == nil {
+			store.pipelineInfoMap.Store(pipelineInfo.PipelineId, pipelineInfo)
+		} else {
+			log.WithError(err).Error("failed to parse pipeline info file")
+		}
+	}
+}
+
+func (store *RuntimePipelineStoreTask) GetPipelines() ([]common.PipelineInfo, error) {
+	pipelineInfoList := make([]common.PipelineInfo, 0)
+	store.pipelineInfoMap.Range(func(key, value interface{}) bool {
+		pipelineInfoList = append(pipelineInfoList, value.(common.PipelineInfo))
+		return true
+	})
+	return pipelineInfoList, nil
+}
+
+func (store *RuntimePipelineStoreTask) GetInfo(pipelineId string) (common.PipelineInfo, error) {
+	if !store.hasPipeline(pipelineId) {
+		return common.PipelineInfo{}, errors.New("Pipeline '" + pipelineId + " does not exist")
+	}
+
+	pipelineInfo := common.PipelineInfo{}
+	err := store.loadDocument(pipelineId, PipelineInfoFile, &pipelineInfo)
+	if err != nil {
+		return pipelineInfo, err
+	}
+
+	if pipelineInfo.PipelineId == "" {
+		err = errors.New("InValid pipeline configuration")
+	}
+
+	return pipelineInfo, err
+}
+
+func (store *RuntimePipelineStoreTask) Create(
+	pipelineId string,
+	pipelineTitle string,
+	description string,
+	isRemote bool,
+) (common.PipelineConfiguration, error) {
+
+	if store.hasPipeline(pipelineId) {
+		return common.PipelineConfiguration{}, errors.New("Pipeline '" + pipelineId + " already exists")
+	}
+
+	currentTime := time.Now().Unix()
+	metadata := map[string]interface{}{
+		"labels": []string{},
+	}
+	pipelineUuid := uuid.NewV4().String()
+
+	pipelineInfo := common.PipelineInfo{
+		PipelineId:   pipelineId,
+		Title:        pipelineTitle,
+		Description:  description,
+		Created:      currentTime,
+		LastModified: currentTime,
+		Creator:      "admin",
+		LastModifier: "admin",
+		LastRev:      "0",
+		UUID:         pipelineUuid,
+		Valid:        true,
+		Metadata:     metadata,
+	}
+
+	pipelineConfiguration := common.PipelineConfiguration{
+		SchemaVersion:        common.PipelineConfigSchemaVersion,
+		Version:              common.PipelineConfigVersion,
+		PipelineId:           pipelineId,
+		Title:                pipelineTitle,
+		Description:          description,
+		UUID:                 pipelineUuid,
+		Configuration:        creation.GetDefaultPipelineConfigs(),
+		UiInfo:               map[string]interface{}{},
+		Stages:               []*common.StageConfiguration{},
+		ErrorStage:           creation.GetTrashErrorStageInstance(),
+		StatsAggregatorStage: creation.GetDefaultStatsAggregatorStageInstance(),
+		Previewable:          true,
+		Info:                 pipelineInfo,
+		Metadata:             metadata,
+	}
+
+	err := store.saveDocument(pipelineId, PipelineInfoFile, pipelineInfo)
+	if err != nil {
+		return pipelineConfiguration, err
+	}
+
+	err = store.saveDocument(pipelineId, PipelineFile, pipelineConfiguration)
+	if err != nil {
+		return pipelineConfiguration, err
+	}
+
+	err = pipelineStateStore.Edited(pipelineId, isRemote)
+
+	log.WithField("id", pipelineInfo.PipelineId).Info("Created pipeline")
+
+	store.pipelineInfoMap.Store(pipelineInfo.PipelineId, pipelineInfo)
+
+	return pipelineConfiguration, err
+}
+
+func (store *RuntimePipelineStoreTask) Save(
+	pipelineId string,
+	pipelineConfiguration common.PipelineConfiguration,
+) (common.PipelineConfiguration, error) {
+	if !store.hasPipeline(pipelineId) {
+		return common.PipelineConfiguration{}, errors.New("Pipeline '" + pipelineId + " does not exist")
+	}
+
+	currentTime := time.Now().Unix()
+	pipelineUuid := uuid.NewV4().String()
+	pipelineInfo := pipelineConfiguration.Info
+
+	pipelineInfo.UUID = pipelineUuid
+	pipelineInfo.PipelineId = pipelineConfiguration.PipelineId
+	pipelineInfo.LastModified = currentTime
+	pipelineInfo.Title = pipelineConfiguration.Title
+	pipelineInfo.Description = pipelineConfiguration.Description
+
+	pipelineConfiguration.Info = pipelineInfo
+	pipelineConfiguration.UUID = pipelineUuid
+
+	err := store.saveDocument(pipelineId, PipelineInfoFile, pipelineInfo)
+	if err != nil {
+		return pipelineConfiguration, err
+	}
+
+	err = store.saveDocument(pipelineId, PipelineFile, pipelineConfiguration)
+	if err != nil {
+		return pipelineConfiguration, err
+	}
+
+	log.WithField("id", pipelineInfo.PipelineId).Info("Updated pipeline")
+
+	return pipelineConfiguration, nil
+}
+
+func (store *RuntimePipelineStoreTask) LoadPipelineConfig(pipelineId string) (common.PipelineConfiguration, error) {
+	pipelineConfiguration := common.PipelineConfiguration{}
+	err := store.loadDocument(pipelineId, PipelineFile, &pipelineConfiguration)
+	if err != nil {
+		return pipelineConfiguration, err
+	}
+
+	if pipelineConfiguration.PipelineId == "" {
+		return pipelineConfiguration, errors.New("InValid pipeline configuration")
+	}
+
+	// Process fragment stages
+	err = pipelineConfiguration.ProcessFragmentStages()
+
+	return pipelineConfiguration, err
+}
+
+func (store *RuntimePipelineStoreTask) Delete(pipelineId string) error {
+	if !store.hasPipeline(pipelineId) {
+		return errors.New("Pipeline '" + pipelineId + " does not exist")
+	}
+	err := store.runtimeStore.DeletePipeline(pipelineId)
+	log.WithField("id", pipelineId).Info("Deleted pipeline")
+	store.pipelineInfoMap.Delete(pipelineId)
+	return err
+}
+
+func (store *RuntimePipelineStoreTask) SaveRules(
+	pipelineId string,
+	ruleDefinitions common.RuleDefinitions,
+) (common.RuleDefinitions, error) {
+	if !store.hasPipeline(pipelineId) {
+		return ruleDefinitions, errors.New("Pipeline '" + pipelineId + " does not exist")
+	}
+
+	ruleDefinitions.SchemaVersion = common.RuleDefinitionsSchemaVersion
+	ruleDefinitions.Version = common.RuleDefinitionsVersion
+	ruleDefinitions.UUID = uuid.NewV4().String()
+
+	err := store.saveDocument(pipelineId, PipelineRulesFile, ruleDefinitions)
+	if err != nil {
+		return ruleDefinitions, err
+	}
+
+	log.WithField("id", pipelineId).Info("Updated pipeline rules")
+
+	return ruleDefinitions, nil
+}
+
+func (store *RuntimePipelineStoreTask) RetrieveRules(pipelineId string) (common.RuleDefinitions, error) {
+	ruleDefinitions := common.RuleDefinitions{
+		SchemaVersion:          common.RuleDefinitionsSchemaVersion,
+		Version:                common.RuleDefinitionsVersion,
+		MetricsRuleDefinitions: []*common.MetricsRuleDefinition{},
+		DataRuleDefinitions:    []*common.DataRuleDefinition{},
+		DriftRuleDefinitions:   []*common.DataRuleDefinition{},
+		EmailIds:               []string{},
+		Configuration:          []common.Config{},
+	}
+	if !store.hasPipeline(pipelineId) {
+		return ruleDefinitions, errors.New("Pipeline '" + pipelineId + " does not exist")
+	}
+
+	err := store.loadDocument(pipelineId, PipelineRulesFile, &ruleDefinitions)
+	if os.IsNotExist(err) {
+		ruleDefinitions.UUID = uuid.NewV4().String()
+		return ruleDefinitions, nil
+	}
+	return ruleDefinitions, err
+}
+
+func (store *RuntimePipelineStoreTask) SaveSchedules(pipelineId string, schedules []*common.PipelineSchedule) error {
+	if !store.hasPipeline(pipelineId) {
+		return errors.New("Pipeline '" + pipelineId + " does not exist")
+	}
+	return store.saveDocument(pipelineId, PipelineSchedulesFile, schedules)
+}
+
+func (store *RuntimePipelineStoreTask) RetrieveSchedules(pipelineId string) ([]*common.PipelineSchedule, error) {
+	schedules := make([]*common.PipelineSchedule, 0)
+	if !store.hasPipeline(pipelineId) {
+		return schedules, errors.New("Pipeline '" + pipelineId + " does not exist")
+	}
+
+	err := store.loadDocument(pipelineId, PipelineSchedulesFile, &schedules)
+	if os.IsNotExist(err) {
+		return schedules, nil
+	}
+	return schedules, err
+}
+
+func (store *RuntimePipelineStoreTask) hasPipeline(pipelineId string) bool {
+	_, err := store.runtimeStore.GetPipelineDocument(pipelineId, PipelineInfoFile)
+	return !os.IsNotExist(err)
+}
+
+func (store *RuntimePipelineStoreTask) loadDocument(pipelineId string, name string, v interface{}) error {
+	data, err := store.runtimeStore.GetPipelineDocument(pipelineId, name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (store *RuntimePipelineStoreTask) saveDocument(pipelineId string, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.runtimeStore.SavePipelineDocument(pipelineId, name, data)
+}
+
+// NewFilePipelineStoreTask returns a pipeline store task that keeps the pipelines in the file layout under the
+// base directory.
+func NewFilePipelineStoreTask(runtimeInfo common.RuntimeInfo) PipelineStoreTask {
+	pipelineStateStore.BaseDir = runtimeInfo.BaseDir
+	return NewPipelineStoreTask(runtimeInfo, pipelineStateStore.NewFileRuntimeStore())
+}
+
+func NewPipelineStoreTask(
+	runtimeInfo common.RuntimeInfo,
+	runtimeStore pipelineStateStore.RuntimeStore,
+) PipelineStoreTask {
+	storeTask := &RuntimePipelineStoreTask{
+		runtimeInfo:  runtimeInfo,
+		runtimeStore: runtimeStore,
+	}
+	storeTask.init()
+	return storeTask
+}
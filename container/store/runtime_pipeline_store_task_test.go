@@ -193,3 +193,54 @@ func TestFilePipelineStoreTask_Delete(t *testing.T) {
 		t.Error("Excepted error from delete API")
 	}
 }
+
+func TestFilePipelineStoreTask_SaveRules(t *testing.T) {
+	pipelineStoreTask := getPipelineStoreTask(t, "TestFilePipelineStoreTask_SaveRules")
+
+	_, err := pipelineStoreTask.Create("testPipeline", "testPipeline", "Sample desc", false)
+	if err != nil {
+		t.Error("Error from Create: ", err)
+		return
+	}
+
+	ruleDefinitions, err := pipelineStoreTask.RetrieveRules("testPipeline")
+	if err != nil {
+		t.Error("Error from RetrieveRules: ", err)
+		return
+	}
+
+	if len(ruleDefinitions.MetricsRuleDefinitions) != 0 || len(ruleDefinitions.DataRuleDefinitions) != 0 {
+		t.Error("Excepted no rules for new pipeline")
+	}
+
+	ruleDefinitions.MetricsRuleDefinitions = append(ruleDefinitions.MetricsRuleDefinitions, &common.MetricsRuleDefinition{
+		Id:            "batchCountRule",
+		MetricId:      "pipeline.batchCount.counter",
+		MetricType:    common.MetricTypeCounter,
+		MetricElement: "COUNTER_COUNT",
+		Condition:     "${value() > 100}",
+		Enabled:       true,
+	})
+	_, err = pipelineStoreTask.SaveRules("testPipeline", ruleDefinitions)
+	if err != nil {
+		t.Error("Error from SaveRules: ", err)
+		return
+	}
+
+	ruleDefinitions, err = pipelineStoreTask.RetrieveRules("testPipeline")
+	if err != nil {
+		t.Error("Error from RetrieveRules: ", err)
+		return
+	}
+
+	if len(ruleDefinitions.MetricsRuleDefinitions) != 1 ||
+		ruleDefinitions.MetricsRuleDefinitions[0].Id != "batchCountRule" {
+		t.Error("Excepted metric rule 'batchCountRule' but got : ", ruleDefinitions.MetricsRuleDefinitions)
+	}
+
+	// Save rules for invalid pipelineId
+	_, err = pipelineStoreTask.SaveRules("invalidPipeline", ruleDefinitions)
+	if err == nil {
+		t.Error("Error excepted for invalid pipelineId")
+	}
+}
@@ -25,28 +25,28 @@ const (
 	GAUGE_SUFFIX        = ".gauge"
 )
 
+// CreateCounter and the other Create functions use GetOrRegister so that stages instantiated once per
+// pipeline runner share, and roll up into, the same pipeline level metric.
 func CreateCounter(registry metrics.Registry, name string) metrics.Counter {
-	counter := metrics.NewCounter()
-	registry.Register(metricName(name, COUNTER_SUFFIX), counter)
-	return counter
+	return registry.GetOrRegister(metricName(name, COUNTER_SUFFIX), metrics.NewCounter).(metrics.Counter)
 }
 
 func CreateMeter(registry metrics.Registry, name string) metrics.Meter {
-	meter := metrics.NewMeter()
-	registry.Register(metricName(name, METER_SUFFIX), meter)
-	return meter
+	return registry.GetOrRegister(metricName(name, METER_SUFFIX), metrics.NewMeter).(metrics.Meter)
 }
 
 func CreateHistogram5Min(registry metrics.Registry, name string) metrics.Histogram {
-	histogram := metrics.NewHistogram(metrics.NewExpDecaySample(1028, 0.015))
-	registry.Register(metricName(name, HISTOGRAM_M5_SUFFIX), histogram)
-	return histogram
+	return registry.GetOrRegister(metricName(name, HISTOGRAM_M5_SUFFIX), func() metrics.Histogram {
+		return metrics.NewHistogram(metrics.NewExpDecaySample(1028, 0.015))
+	}).(metrics.Histogram)
 }
 
 func CreateTimer(registry metrics.Registry, name string) metrics.Timer {
-	timer := metrics.NewTimer()
-	registry.Register(metricName(name, TIMER_SUFFIX), timer)
-	return timer
+	return registry.GetOrRegister(metricName(name, TIMER_SUFFIX), metrics.NewTimer).(metrics.Timer)
+}
+
+func CreateFunctionalGauge(registry metrics.Registry, name string, f func() int64) metrics.Gauge {
+	return registry.GetOrRegister(metricName(name, GAUGE_SUFFIX), metrics.NewFunctionalGauge(f)).(metrics.Gauge)
 }
 
 func metricName(name string, suffix string) string {
@@ -0,0 +1,262 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package util
+
+import (
+	"bufio"
+	"github.com/rcrowley/go-metrics"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	PrometheusTextFormat = "text/plain; version=0.0.4; charset=utf-8"
+
+	prometheusCounter = "counter"
+	prometheusGauge   = "gauge"
+	prometheusSummary = "summary"
+
+	stageMetricPrefix  = "stage."
+	runnerMetricPrefix = "pipeline.runner."
+)
+
+var (
+	prometheusQuantiles         = []float64{0.5, 0.75, 0.95, 0.98, 0.99, 0.999}
+	prometheusLabelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+type prometheusLabel struct {
+	name  string
+	value string
+}
+
+type prometheusSample struct {
+	suffix string
+	labels []prometheusLabel
+	value  float64
+}
+
+type prometheusFamily struct {
+	metricType string
+	samples    []prometheusSample
+	sampleKeys map[string]bool
+}
+
+// PrometheusMetrics converts the metrics of go-metrics registries to metric families of the Prometheus text
+// exposition format. Stage metrics get stage and lane labels and pipeline runner metrics a runner label, counters
+// and meters of the same metric are exported once as counter, histograms and timers as summaries.
+type PrometheusMetrics struct {
+	namespace string
+	families  map[string]*prometheusFamily
+}
+
+func NewPrometheusMetrics(namespace string) *PrometheusMetrics {
+	return &PrometheusMetrics{
+		namespace: namespace,
+		families:  make(map[string]*prometheusFamily),
+	}
+}
+
+// AddRegistry adds the metrics of the registry, with the prefix prepended to their names and the given labels.
+func (p *PrometheusMetrics) AddRegistry(registry metrics.Registry, prefix string, labels map[string]string) {
+	baseLabels := make([]prometheusLabel, 0, len(labels))
+	for name, value := range labels {
+		baseLabels = append(baseLabels, prometheusLabel{name: name, value: value})
+	}
+	sort.Slice(baseLabels, func(i, j int) bool { return baseLabels[i].name < baseLabels[j].name })
+
+	metricsByName := make(map[string]interface{})
+	names := make([]string, 0)
+	registry.Each(func(name string, metric interface{}) {
+		metricsByName[name] = metric
+		names = append(names, name)
+	})
+	sort.Strings(names)
+
+	for _, name := range names {
+		p.addMetric(prefix+name, baseLabels, metricsByName[name])
+	}
+}
+
+func (p *PrometheusMetrics) addMetric(name string, baseLabels []prometheusLabel, metric interface{}) {
+	switch m := metric.(type) {
+	case metrics.Counter:
+		familyName, labels := p.familyName(strings.TrimSuffix(name, COUNTER_SUFFIX), baseLabels)
+		p.addSample(familyName+"_total", prometheusCounter, "", labels, float64(m.Count()))
+	case metrics.Meter:
+		familyName, labels := p.familyName(strings.TrimSuffix(name, METER_SUFFIX), baseLabels)
+		p.addSample(familyName+"_total", prometheusCounter, "", labels, float64(m.Count()))
+	case metrics.Gauge:
+		familyName, labels := p.familyName(strings.TrimSuffix(name, GAUGE_SUFFIX), baseLabels)
+		p.addSample(familyName, prometheusGauge, "", labels, float64(m.Value()))
+	case metrics.GaugeFloat64:
+		familyName, labels := p.familyName(strings.TrimSuffix(name, GAUGE_SUFFIX), baseLabels)
+		p.addSample(familyName, prometheusGauge, "", labels, m.Value())
+	case metrics.Histogram:
+		name = strings.TrimSuffix(name, HISTOGRAM_M5_SUFFIX)
+		familyName, labels := p.familyName(name, baseLabels)
+		if strings.HasPrefix(name, stageMetricPrefix) {
+			// stage histograms are the records per batch, like the pipeline histograms are named
+			familyName += "_per_batch"
+		}
+		h := m.Snapshot()
+		p.addSummary(familyName, labels, h.Percentiles(prometheusQuantiles), float64(h.Sum()), h.Count())
+	case metrics.Timer:
+		familyName, labels := p.familyName(strings.TrimSuffix(name, TIMER_SUFFIX), baseLabels)
+		t := m.Snapshot()
+		quantiles := t.Percentiles(prometheusQuantiles)
+		for i := range quantiles {
+			quantiles[i] = quantiles[i] / 1e9
+		}
+		p.addSummary(familyName+"_seconds", labels, quantiles, float64(t.Sum())/1e9, t.Count())
+	}
+}
+
+// familyName returns the metric family name of a metric name, with the stage, lane and runner embedded in the
+// name moved to labels, e.g. stage.<stage>:<lane>.outputRecords becomes stage_output_records{stage, lane}.
+func (p *PrometheusMetrics) familyName(name string, baseLabels []prometheusLabel) (string, []prometheusLabel) {
+	labels := append([]prometheusLabel{}, baseLabels...)
+	if strings.HasPrefix(name, stageMetricPrefix) && strings.LastIndex(name, ".") > len(stageMetricPrefix) {
+		index := strings.LastIndex(name, ".")
+		stage := name[len(stageMetricPrefix):index]
+		lane := ""
+		if laneIndex := strings.Index(stage, ":"); laneIndex >= 0 {
+			stage, lane = stage[:laneIndex], stage[laneIndex+1:]
+		}
+		labels = append(labels, prometheusLabel{name: "stage", value: stage})
+		if lane != "" {
+			labels = append(labels, prometheusLabel{name: "lane", value: lane})
+		}
+		name = "stage" + name[index:]
+	} else if strings.HasPrefix(name, runnerMetricPrefix) {
+		runnerId := strings.TrimPrefix(name, runnerMetricPrefix)
+		if index := strings.Index(runnerId, "."); index > 0 {
+			labels = append(labels, prometheusLabel{name: "runner", value: runnerId[:index]})
+			name = "pipeline.runner" + runnerId[index:]
+		}
+	}
+	return p.namespace + "_" + toPrometheusName(name), labels
+}
+
+func (p *PrometheusMetrics) addSummary(
+	familyName string,
+	labels []prometheusLabel,
+	quantiles []float64,
+	sum float64,
+	count int64,
+) {
+	for i, quantile := range prometheusQuantiles {
+		quantileLabels := append(append([]prometheusLabel{}, labels...), prometheusLabel{
+			name:  "quantile",
+			value: strconv.FormatFloat(quantile, 'g', -1, 64),
+		})
+		p.addSample(familyName, prometheusSummary, "", quantileLabels, quantiles[i])
+	}
+	p.addSample(familyName, prometheusSummary, "_sum", labels, sum)
+	p.addSample(familyName, prometheusSummary, "_count", labels, float64(count))
+}
+
+// addSample adds a sample to the metric family, samples of a family with a different type and duplicates, like
+// the meter of a counter, are dropped.
+func (p *PrometheusMetrics) addSample(
+	familyName string,
+	metricType string,
+	suffix string,
+	labels []prometheusLabel,
+	value float64,
+) {
+	family := p.families[familyName]
+	if family == nil {
+		family = &prometheusFamily{metricType: metricType, sampleKeys: make(map[string]bool)}
+		p.families[familyName] = family
+	} else if family.metricType != metricType {
+		return
+	}
+
+	sampleKey := suffix + formatPrometheusLabels(labels)
+	if family.sampleKeys[sampleKey] {
+		return
+	}
+	family.sampleKeys[sampleKey] = true
+	family.samples = append(family.samples, prometheusSample{suffix: suffix, labels: labels, value: value})
+}
+
+// Write writes the metric families sorted by name in the Prometheus text exposition format.
+func (p *PrometheusMetrics) Write(w io.Writer) error {
+	familyNames := make([]string, 0, len(p.families))
+	for familyName := range p.families {
+		familyNames = append(familyNames, familyName)
+	}
+	sort.Strings(familyNames)
+
+	writer := bufio.NewWriter(w)
+	for _, familyName := range familyNames {
+		family := p.families[familyName]
+		writer.WriteString("# TYPE " + familyName + " " + family.metricType + "\n")
+		for _, sample := range family.samples {
+			writer.WriteString(familyName + sample.suffix + formatPrometheusLabels(sample.labels) + " ")
+			writer.WriteString(formatPrometheusValue(sample.value) + "\n")
+		}
+	}
+	return writer.Flush()
+}
+
+func formatPrometheusLabels(labels []prometheusLabel) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	formattedLabels := make([]string, len(labels))
+	for i, label := range labels {
+		formattedLabels[i] = label.name + "=\"" + prometheusLabelValueEscaper.Replace(label.value) + "\""
+	}
+	return "{" + strings.Join(formattedLabels, ",") + "}"
+}
+
+func formatPrometheusValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// toPrometheusName converts a dotted camel case metric name like pipeline.batchInputRecords to snake case like
+// pipeline_batch_input_records, characters not allowed in metric names are replaced by underscores.
+func toPrometheusName(name string) string {
+	runes := []rune(name)
+	var prometheusName strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				prometheusName.WriteRune('_')
+			}
+		}
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			prometheusName.WriteRune(unicode.ToLower(r))
+		} else {
+			prometheusName.WriteRune('_')
+		}
+	}
+	return prometheusName.String()
+}
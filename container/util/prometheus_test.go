@@ -0,0 +1,83 @@
+// Copyright 2018 StreamSets Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package util
+
+import (
+	"bytes"
+	"github.com/rcrowley/go-metrics"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToPrometheusName(t *testing.T) {
+	testCases := map[string]string{
+		"pipeline.batchInputRecords": "pipeline_batch_input_records",
+		"runtime.MemStats.NumGC":     "runtime_mem_stats_num_gc",
+		"debug.GCStats.LastGC":       "debug_gc_stats_last_gc",
+		"user.rule-1":                "user_rule_1",
+	}
+	for name, expected := range testCases {
+		if actual := toPrometheusName(name); actual != expected {
+			t.Errorf("Expected '%s' for '%s', got '%s'", expected, name, actual)
+		}
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	CreateCounter(registry, "pipeline.batchCount").Inc(3)
+	CreateMeter(registry, "pipeline.batchCount").Mark(3)
+	CreateCounter(registry, "stage.dev_01.inputRecords").Inc(10)
+	CreateCounter(registry, "stage.dev_01:lane_\"1\".outputRecords").Inc(7)
+	CreateHistogram5Min(registry, "stage.dev_01.inputRecords").Update(10)
+	CreateTimer(registry, "pipeline.runner.0.batchProcessing").Update(2 * time.Second)
+	CreateFunctionalGauge(registry, "pipeline.memoryLimit", func() int64 { return 1024 })
+
+	processRegistry := metrics.NewRegistry()
+	processRegistry.Register("runtime.NumGoroutine", metrics.NewGauge())
+
+	prometheusMetrics := NewPrometheusMetrics("sdc_edge")
+	prometheusMetrics.AddRegistry(registry, "", map[string]string{"pipeline_id": "test"})
+	prometheusMetrics.AddRegistry(processRegistry, "process.", nil)
+	var buffer bytes.Buffer
+	if err := prometheusMetrics.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	output := buffer.String()
+
+	expectedLines := []string{
+		"# TYPE sdc_edge_pipeline_batch_count_total counter",
+		`sdc_edge_pipeline_batch_count_total{pipeline_id="test"} 3`,
+		"# TYPE sdc_edge_pipeline_memory_limit gauge",
+		`sdc_edge_pipeline_memory_limit{pipeline_id="test"} 1024`,
+		"# TYPE sdc_edge_pipeline_runner_batch_processing_seconds summary",
+		`sdc_edge_pipeline_runner_batch_processing_seconds{pipeline_id="test",runner="0",quantile="0.5"} 2`,
+		`sdc_edge_pipeline_runner_batch_processing_seconds_sum{pipeline_id="test",runner="0"} 2`,
+		`sdc_edge_pipeline_runner_batch_processing_seconds_count{pipeline_id="test",runner="0"} 1`,
+		`sdc_edge_stage_input_records_total{pipeline_id="test",stage="dev_01"} 10`,
+		"# TYPE sdc_edge_stage_input_records_per_batch summary",
+		`sdc_edge_stage_input_records_per_batch_count{pipeline_id="test",stage="dev_01"} 1`,
+		`sdc_edge_stage_output_records_total{pipeline_id="test",stage="dev_01",lane="lane_\"1\""} 7`,
+		"# TYPE sdc_edge_process_runtime_num_goroutine gauge",
+		"sdc_edge_process_runtime_num_goroutine 0",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(output, expectedLine+"\n") {
+			t.Errorf("Expected line '%s' in output:\n%s", expectedLine, output)
+		}
+	}
+	if strings.Count(output, "sdc_edge_pipeline_batch_count_total{") != 1 {
+		t.Errorf("Expected the counter and meter of the batch count to be exported once:\n%s", output)
+	}
+}
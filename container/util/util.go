@@ -125,3 +125,15 @@ func TruncateString(str string, num int) string {
 	}
 	return s
 }
+
+// SyncDir syncs the directory so that files created or renamed in it survive a crash.
+func SyncDir(dir string) error {
+	dirFile, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer dirFile.Close()
+	// not all platforms support syncing directories
+	_ = dirFile.Sync()
+	return nil
+}
@@ -32,6 +32,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 )
 
@@ -227,18 +228,30 @@ func shutdownHook(dataCollectorEdge *edge.DataCollectorEdgeMain) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
 	log.Infof("Program got a system signal %v", <-c)
+	if dataCollectorEdge.Scheduler != nil {
+		dataCollectorEdge.Scheduler.Stop()
+	}
+	// stop the pipelines in parallel, each one drains its batch in flight before it is stopped
+	var waitGroup sync.WaitGroup
 	if pipelineInfos, er := dataCollectorEdge.PipelineStoreTask.GetPipelines(); er == nil {
 		for _, pipelineInfo := range pipelineInfos {
-			runner := dataCollectorEdge.Manager.GetRunner(pipelineInfo.PipelineId)
+			pipelineId := pipelineInfo.PipelineId
+			runner := dataCollectorEdge.Manager.GetRunner(pipelineId)
 			if pipelineState, er := runner.GetStatus(); er == nil &&
-				(pipelineState.Status == common.RUNNING || pipelineState.Status == common.STARTING) {
-				log.WithField("id", pipelineInfo.PipelineId).Info("Stopping pipeline")
-				if _, err := runner.StopPipeline(); err != nil {
-					log.WithField("id", pipelineInfo.PipelineId).Error("Error stopping pipeline")
-				}
+				(pipelineState.Status == common.RUNNING || pipelineState.Status == common.STARTING ||
+					pipelineState.Status == common.RETRY) {
+				log.WithField("id", pipelineId).Info("Stopping pipeline")
+				waitGroup.Add(1)
+				go func() {
+					defer waitGroup.Done()
+					if _, err := runner.StopPipeline(); err != nil {
+						log.WithError(err).WithField("id", pipelineId).Error("Error stopping pipeline")
+					}
+				}()
 			}
 		}
 	}
+	waitGroup.Wait()
 	dataCollectorEdge.WebServerTask.Shutdown()
 	if dataCollectorEdge.RuntimeInfo.DPMEnabled {
 		dataCollectorEdge.DPMMessageEventHandler.Shutdown()
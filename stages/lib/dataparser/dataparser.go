@@ -31,8 +31,8 @@ const (
 )
 
 type DataParserFormatConfig struct {
-	Compression         string `ConfigDef:"type=STRING,required=true"`
-	FlePatternInArchive string `ConfigDef:"type=STRING,required=true"`
+	Compression          string `ConfigDef:"type=STRING,required=true"`
+	FilePatternInArchive string `ConfigDef:"type=STRING,required=true"`
 
 	/* Charset Related -- Shown last */
 	Charset         string `ConfigDef:"type=STRING,required=true"`
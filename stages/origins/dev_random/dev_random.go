@@ -35,8 +35,8 @@ var randomOffset = "random"
 type DevRandom struct {
 	*common.BaseStage
 	Fields               string  `ConfigDef:"type=STRING,required=true"`
-	Delay                float64 `ConfigDef:"type=NUMBER,required=true"`
-	MaxRecordsToGenerate float64 `ConfigDef:"type=NUMBER,required=true"`
+	Delay                float64 `ConfigDef:"type=NUMBER,required=true,min=0"`
+	MaxRecordsToGenerate float64 `ConfigDef:"type=NUMBER,required=true,min=0"`
 	fieldsList           []string
 	recordsProduced      float64
 }
@@ -31,6 +31,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"sync"
 )
 
 const (
@@ -50,6 +51,8 @@ type Origin struct {
 	DataFormatConfig dataparser.DataParserFormatConfig `ConfigDefBean:"dataFormatConfig"`
 	httpServer       *http.Server
 	incomingRecords  chan []api.Record
+	done             chan struct{}
+	doneOnce         sync.Once
 }
 
 type RawHttpConfigs struct {
@@ -91,14 +94,15 @@ func (h *Origin) Init(stageContext api.StageContext) []validation.Issue {
 	if len(issues) == 0 {
 		h.httpServer = h.startHttpServer()
 		h.incomingRecords = make(chan []api.Record)
+		h.done = make(chan struct{})
 	}
 
 	return issues
 }
 
 func (h *Origin) Destroy() error {
-	if h.incomingRecords != nil {
-		close(h.incomingRecords)
+	if h.done != nil {
+		h.doneOnce.Do(func() { close(h.done) })
 	}
 	if h.httpServer != nil {
 		if err := h.httpServer.Shutdown(context.Background()); err != nil {
@@ -115,14 +119,23 @@ func (h *Origin) Produce(
 	batchMaker api.BatchMaker,
 ) (*string, error) {
 	log.Debug("HTTP Server - Produce method")
-	records := <-h.incomingRecords
-	for _, record := range records {
-		batchMaker.AddRecord(record)
+	select {
+	case records := <-h.incomingRecords:
+		for _, record := range records {
+			batchMaker.AddRecord(record)
+		}
+	case <-h.GetStageContext().Done():
+	case <-h.done:
 	}
 	return &stringOffset, nil
 }
 
 func (h *Origin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.GetStageContext().IsStopped() {
+		h.rejectStopped(w)
+		return
+	}
+
 	if h.validateAppId(w, r) {
 		recordReaderFactory := h.DataFormatConfig.RecordReaderFactory
 		recordReader, err := recordReaderFactory.CreateReader(h.GetStageContext(), r.Body, "http-server")
@@ -148,11 +161,22 @@ func (h *Origin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if len(records) > 0 {
-			h.incomingRecords <- records
+			select {
+			case h.incomingRecords <- records:
+			case <-h.GetStageContext().Done():
+				h.rejectStopped(w)
+			case <-h.done:
+				h.rejectStopped(w)
+			}
 		}
 	}
 }
 
+func (h *Origin) rejectStopped(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_, _ = fmt.Fprintf(w, "Pipeline is stopping")
+}
+
 func (h *Origin) validateAppId(w http.ResponseWriter, r *http.Request) bool {
 	valid := false
 	reqAppId := r.Header.Get(X_SDC_APPLICATION_ID_HEADER)
@@ -282,6 +282,65 @@ func TestOrigin_Produce_InvalidAppId(t *testing.T) {
 	_ = stageInstance.Destroy()
 }
 
+func TestOrigin_Produce_Stop(t *testing.T) {
+	freePort, _ := lib.GetFreePort()
+	configs := []common.Config{
+		{
+			Name:  "httpConfigs.port",
+			Value: float64(freePort),
+		},
+		{
+			Name:  "httpConfigs.appId",
+			Value: "edge",
+		},
+		{
+			Name:  "dataFormat",
+			Value: "TEXT",
+		},
+	}
+
+	stageContext, _ := getStageContext(configs, nil)
+	stageBean, err := creation.NewStageBean(stageContext.StageConfig, stageContext.Parameters, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	stageInstance := stageBean.Stage
+
+	issues := stageInstance.Init(stageContext)
+	if len(issues) > 0 {
+		t.Fatal(issues[0].Message)
+	}
+	defer stageInstance.Destroy()
+
+	produced := make(chan error)
+	batchMaker := runner.NewBatchMakerImpl(runner.StagePipe{}, false)
+	go func() {
+		_, err := stageInstance.(api.Origin).Produce(&stringOffset, 1, batchMaker)
+		produced <- err
+	}()
+
+	select {
+	case <-produced:
+		t.Fatal("Expected Produce to wait for records until the stage is stopped")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	stageContext.SetStop()
+	select {
+	case err := <-produced:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Produce to return once the stage is stopped")
+	}
+
+	if records := batchMaker.GetStageOutput(); len(records) != 0 {
+		t.Errorf("Expected no records after the stage is stopped, but got %d", len(records))
+	}
+}
+
 func TestOrigin_Produce_HTTPS(t *testing.T) {
 	keyStoreFilePath, _ := filepath.Abs("test/myp12.p12")
 	freePort, _ := lib.GetFreePort()
@@ -44,7 +44,7 @@ type Origin struct {
 }
 
 type OriginClientConfig struct {
-	Delay             float64           `ConfigDef:"type=NUMBER,required=true"`
+	Delay             float64           `ConfigDef:"type=NUMBER,required=true,min=0"`
 	FetchHostInfo     bool              `ConfigDef:"type=BOOLEAN,required=true"`
 	FetchCpuStats     bool              `ConfigDef:"type=BOOLEAN,required=true"`
 	FetchMemStats     bool              `ConfigDef:"type=BOOLEAN,required=true"`
@@ -28,7 +28,7 @@ const (
 
 type DelayProcessor struct {
 	*common.BaseStage
-	Delay float64 `ConfigDef:"type=NUMBER,required=true"`
+	Delay float64 `ConfigDef:"type=NUMBER,required=true,min=0"`
 }
 
 func init() {
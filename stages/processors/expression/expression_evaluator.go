@@ -44,8 +44,8 @@ type FieldValueConfig struct {
 }
 
 type HeaderAttributeConfig struct {
-	AttributeToSet string `ConfigDef:"type=STRING,required=true"`
-	Expression     string `ConfigDef:"type=STRING,evaluation=EXPLICIT,required=true"`
+	AttributeToSet            string `ConfigDef:"type=STRING,required=true"`
+	HeaderAttributeExpression string `ConfigDef:"type=STRING,evaluation=EXPLICIT,required=true"`
 }
 
 type FieldAttributeConfig struct {
@@ -66,47 +66,62 @@ func (f *ExpressionProcessor) Init(stageContext api.StageContext) []validation.I
 
 func (f *ExpressionProcessor) Process(batch api.Batch, batchMaker api.BatchMaker) error {
 	for _, record := range batch.GetRecords() {
-		recordContext := context.WithValue(context.Background(), el.RecordContextVar, record)
-		var err error
-		var evaluatedRes interface{}
-		for _, exprProcessorConfig := range f.ExpressionProcessorConfigs {
-			evaluatedRes, err = f.GetStageContext().Evaluate(exprProcessorConfig.Expression, EXPRESSION, recordContext)
-			if err == nil {
-				var evalField *api.Field
-				if evalField, err = api.CreateFieldFromSDCField(evaluatedRes); err == nil {
-					record.SetField(exprProcessorConfig.FieldToSet, evalField)
-				}
-			}
-			if err != nil {
-				err = errors.New(
-					fmt.Sprintf(
-						"Error when setting field '%s' with expression : '%s'. Reason : '%s'",
-						exprProcessorConfig.FieldToSet, exprProcessorConfig.Expression, err.Error()))
-				break
-			}
+		if err := f.ProcessRecord(record, batchMaker); err != nil {
+			f.GetStageContext().ToError(err, record)
 		}
+	}
+	return nil
+}
 
+func (f *ExpressionProcessor) ProcessRecord(record api.Record, batchMaker api.BatchMaker) error {
+	recordContext := context.WithValue(context.Background(), el.RecordContextVar, record)
+	var err error
+	var evaluatedRes interface{}
+	for _, exprProcessorConfig := range f.ExpressionProcessorConfigs {
+		evaluatedRes, err = f.GetStageContext().Evaluate(exprProcessorConfig.Expression, EXPRESSION, recordContext)
 		if err == nil {
-			for _, headerAttrConfig := range f.HeaderAttributeConfigs {
-				evaluatedRes, err = f.GetStageContext().Evaluate(headerAttrConfig.Expression, EXPRESSION, recordContext)
-				if err == nil {
-					record.GetHeader().SetAttribute(headerAttrConfig.AttributeToSet, evaluatedRes.(string))
-				} else {
-					err = errors.New(
-						fmt.Sprintf(
-							"Error when setting attribute '%s' with expression : '%s'. Reason : '%s'",
-							headerAttrConfig.AttributeToSet, headerAttrConfig.Expression, err.Error()))
-					break
-				}
+			var evalField *api.Field
+			if evalField, err = api.CreateFieldFromSDCField(evaluatedRes); err == nil {
+				record.SetField(exprProcessorConfig.FieldToSet, evalField)
 			}
 		}
-
 		if err != nil {
-			log.WithError(err).Error("Error evaluating record")
-			f.GetStageContext().ToError(err, record)
-		} else {
-			batchMaker.AddRecord(record)
+			err = errors.New(
+				fmt.Sprintf(
+					"Error when setting field '%s' with expression : '%s'. Reason : '%s'",
+					exprProcessorConfig.FieldToSet, exprProcessorConfig.Expression, err.Error()))
+			break
 		}
 	}
+
+	if err == nil {
+		for _, headerAttrConfig := range f.HeaderAttributeConfigs {
+			evaluatedRes, err = f.GetStageContext().Evaluate(
+				headerAttrConfig.HeaderAttributeExpression,
+				EXPRESSION,
+				recordContext,
+			)
+			if err == nil {
+				record.GetHeader().SetAttribute(headerAttrConfig.AttributeToSet, evaluatedRes.(string))
+			} else {
+				err = errors.New(
+					fmt.Sprintf(
+						"Error when setting attribute '%s' with expression : '%s'. Reason : '%s'",
+						headerAttrConfig.AttributeToSet, headerAttrConfig.HeaderAttributeExpression, err.Error()))
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		log.WithError(err).Error("Error evaluating record")
+		return err
+	}
+	batchMaker.AddRecord(record)
 	return nil
 }
+
+// IsRecordParallel returns true, expressions are evaluated against a single record.
+func (f *ExpressionProcessor) IsRecordParallel() bool {
+	return true
+}
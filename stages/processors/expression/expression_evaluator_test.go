@@ -27,6 +27,7 @@ const (
 	HEADER_ATTRIBUTE_CONFIGS     = "headerAttributeConfigs"
 	FIELD_TO_SET                 = "fieldToSet"
 	ATTRIBUTE_TO_SET             = "attributeToSet"
+	HEADER_ATTRIBUTE_EXPRESSION  = "headerAttributeExpression"
 )
 
 func getStageContext() (*common.StageContextImpl, *common.ErrorSink) {
@@ -49,8 +50,8 @@ func getStageContext() (*common.StageContextImpl, *common.ErrorSink) {
 
 	headerAttributeConfigs := []interface{}{}
 	headerAttributeConfigs = append(headerAttributeConfigs, map[string]interface{}{
-		ATTRIBUTE_TO_SET: "eval",
-		EXPRESSION:       "${str:toUpper(record:value('/c'))}",
+		ATTRIBUTE_TO_SET:            "eval",
+		HEADER_ATTRIBUTE_EXPRESSION: "${str:toUpper(record:value('/c'))}",
 	})
 
 	stageConfig.Configuration[0] = common.Config{
@@ -139,14 +140,54 @@ func TestExpressionProcessor_Success(t *testing.T) {
 	}
 }
 
+func TestExpressionProcessor_HeaderAttribute(t *testing.T) {
+	stageContext, errSink := getStageContext()
+	stageContext.StageConfig.Configuration[0].Value = []interface{}{}
+
+	stageBean, err := creation.NewStageBean(stageContext.StageConfig, stageContext.Parameters, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stageInstance := stageBean.Stage.(*ExpressionProcessor)
+	if len(stageInstance.HeaderAttributeConfigs) != 1 ||
+		stageInstance.HeaderAttributeConfigs[0].HeaderAttributeExpression != "${str:toUpper(record:value('/c'))}" {
+		t.Fatalf("Expected the header attribute expression to be set, but got %v", stageInstance.HeaderAttributeConfigs)
+	}
+	issues := stageInstance.Init(stageContext)
+	if len(issues) != 0 {
+		t.Error(issues[0].Message)
+	}
+	defer stageInstance.Destroy()
+
+	records := make([]api.Record, 1)
+	records[0], _ = stageContext.CreateRecord("abc", map[string]interface{}{"c": "random"})
+	batch := runner.NewBatchImpl("random", records, nil)
+	batchMaker := runner.NewBatchMakerImpl(runner.StagePipe{}, false)
+	if err = stageInstance.Process(batch, batchMaker); err != nil {
+		t.Fatal("Error when processing batch " + err.Error())
+	}
+
+	records = batchMaker.GetStageOutput()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, but got %d", len(records))
+	}
+	if header := records[0].GetHeader().GetAttributes()["eval"]; header != "RANDOM" {
+		t.Errorf("Expected header attribute eval to be RANDOM, but got '%s'", header)
+	}
+	if errSink.GetTotalErrorRecords() != 0 {
+		t.Fatal("There should be no error records in error sink")
+	}
+}
+
 func TestExpressionProcessor_Error(t *testing.T) {
 	stageContext, errSink := getStageContext()
 
 	stageContext.StageConfig.Configuration[1] = common.Config{
 		Name: HEADER_ATTRIBUTE_CONFIGS,
 		Value: []interface{}{map[string]interface{}{
-			ATTRIBUTE_TO_SET: "eval",
-			EXPRESSION:       "${unsupport:unsupported()}",
+			ATTRIBUTE_TO_SET:            "eval",
+			HEADER_ATTRIBUTE_EXPRESSION: "${unsupport:unsupported()}",
 		}},
 	}
 	stageBean, err := creation.NewStageBean(stageContext.StageConfig, stageContext.Parameters, nil)
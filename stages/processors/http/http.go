@@ -83,16 +83,26 @@ func (h *Processor) Init(stageContext api.StageContext) []validation.Issue {
 
 func (h *Processor) Process(batch api.Batch, batchMaker api.BatchMaker) error {
 	for _, record := range batch.GetRecords() {
-		err := h.processRecord(record)
-		if err != nil {
+		if err := h.ProcessRecord(record, batchMaker); err != nil {
 			h.GetStageContext().ToError(err, record)
-		} else {
-			batchMaker.AddRecord(record)
 		}
 	}
 	return nil
 }
 
+func (h *Processor) ProcessRecord(record api.Record, batchMaker api.BatchMaker) error {
+	if err := h.processRecord(record); err != nil {
+		return err
+	}
+	batchMaker.AddRecord(record)
+	return nil
+}
+
+// IsRecordParallel returns true, requests for different records are independent of each other.
+func (h *Processor) IsRecordParallel() bool {
+	return true
+}
+
 func (h *Processor) processRecord(record api.Record) (err error) {
 	recordContext := context.WithValue(context.Background(), el.RecordContextVar, record)
 
@@ -1,3 +1,4 @@
+//go:build javascript
 // +build javascript
 
 // Copyright 2018 StreamSets Inc.
@@ -27,6 +28,7 @@ import (
 	"github.com/streamsets/datacollector-edge/stages/lib/scripting"
 	"github.com/streamsets/datacollector-edge/stages/stagelibrary"
 	"strings"
+	"sync"
 )
 
 const (
@@ -48,6 +50,8 @@ type JavaScriptProcessor struct {
 	Script         string `ConfigDef:"type=STRING,required=true"`
 	DestroyScript  string `ConfigDef:"type=STRING,required=true"`
 	state          map[string]interface{}
+	usesState      bool
+	stateMutex     sync.Mutex
 }
 
 func init() {
@@ -72,6 +76,7 @@ func (j *JavaScriptProcessor) Init(stageContext api.StageContext) []validation.I
 	}
 
 	j.Script = j.preProcessScript(j.Script)
+	j.usesState = strings.Contains(j.Script, State)
 
 	return issues
 }
@@ -88,23 +93,29 @@ func (j *JavaScriptProcessor) Process(batch api.Batch, batchMaker api.BatchMaker
 }
 
 func (j *JavaScriptProcessor) runRecordProcessingMode(batch api.Batch, batchMaker api.BatchMaker) error {
-	scriptObjectFactory := scripting.ScriptObjectFactory{Context: j.GetStageContext()}
-
 	for _, record := range batch.GetRecords() {
-		scriptRecords := make([]map[string]interface{}, 0)
-		scriptRecord, err := scriptObjectFactory.CreateScriptRecord(record)
-		if err != nil {
-			log.WithError(err).Error("Failed to create script record")
+		if err := j.ProcessRecord(record, batchMaker); err != nil {
 			j.GetStageContext().ToError(err, record)
-			continue
 		}
-		scriptRecords = append(scriptRecords, scriptRecord)
-		j.runScript(scriptRecords, j.GetStageContext(), batchMaker, scriptObjectFactory)
 	}
-
 	return nil
 }
 
+func (j *JavaScriptProcessor) ProcessRecord(record api.Record, batchMaker api.BatchMaker) error {
+	scriptObjectFactory := scripting.ScriptObjectFactory{Context: j.GetStageContext()}
+	scriptRecord, err := scriptObjectFactory.CreateScriptRecord(record)
+	if err != nil {
+		log.WithError(err).Error("Failed to create script record")
+		return err
+	}
+	return j.runScript([]map[string]interface{}{scriptRecord}, j.GetStageContext(), batchMaker, scriptObjectFactory)
+}
+
+// IsRecordParallel returns true in record processing mode, where the script is run separately for each record.
+func (j *JavaScriptProcessor) IsRecordParallel() bool {
+	return j.ProcessingMode == RecordProcessingMode
+}
+
 func (j *JavaScriptProcessor) runBatchProcessingMode(batch api.Batch, batchMaker api.BatchMaker) error {
 	scriptObjectFactory := scripting.ScriptObjectFactory{Context: j.GetStageContext()}
 	scriptRecords := make([]map[string]interface{}, 0)
@@ -156,6 +167,11 @@ func (j *JavaScriptProcessor) runScript(
 	vm.Set("NULL_LIST", scripting.NULL_LIST)
 	vm.Set("NULL_MAP", scripting.NULL_MAP)
 
+	if j.usesState {
+		// the state object is shared by all script runs
+		j.stateMutex.Lock()
+		defer j.stateMutex.Unlock()
+	}
 	_, err := vm.Run(j.Script)
 	if err != nil {
 		log.Error(fmt.Sprintf("Failed to execute JavaScript code due to error: %s", err.Error()))
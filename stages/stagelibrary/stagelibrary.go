@@ -69,6 +69,13 @@ func CreateStageInstance(library string, stageName string) (api.Stage, *common.S
 	}
 }
 
+// GetStageDefinition returns the definition of the given stage. The stage instance created to extract it is not
+// initialized, so no resources are acquired.
+func GetStageDefinition(library string, stageName string) (*common.StageDefinition, error) {
+	_, stageDefinition, err := CreateStageInstance(library, stageName)
+	return stageDefinition, err
+}
+
 func extractStageDefinition(library string, stageName string, stageInstance interface{}) *common.StageDefinition {
 	stageDefinition := &common.StageDefinition{
 		Name:                 stageName,
@@ -117,6 +124,18 @@ func extractConfigDefinition(
 			_, _ = fmt.Sscanf(tagValue, "required=%t", &configDef.Required)
 		case "evaluation":
 			_, _ = fmt.Sscanf(tagValue, "evaluation=%s", &configDef.Evaluation)
+		case "group":
+			_, _ = fmt.Sscanf(tagValue, "group=%s", &configDef.Group)
+		case "min":
+			var min float64
+			if _, err := fmt.Sscanf(tagValue, "min=%g", &min); err == nil {
+				configDef.Min = &min
+			}
+		case "max":
+			var max float64
+			if _, err := fmt.Sscanf(tagValue, "max=%g", &max); err == nil {
+				configDef.Max = &max
+			}
 		}
 	}
 	configDef.Name = configPrefix + util.LcFirst(field.Name)
@@ -159,6 +178,12 @@ func CreateServiceInstance(serviceName string) (api.Service, *common.ServiceDefi
 	}
 }
 
+// GetServiceDefinition returns the definition of the given service without initializing a service instance.
+func GetServiceDefinition(serviceName string) (*common.ServiceDefinition, error) {
+	_, serviceDefinition, err := CreateServiceInstance(serviceName)
+	return serviceDefinition, err
+}
+
 func extractServiceDefinition(
 	serviceName string,
 	serviceInstance interface{},